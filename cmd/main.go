@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	
@@ -10,6 +11,7 @@ import (
 	"BackendFramework/internal/database"
 	"BackendFramework/internal/middleware"
 	"BackendFramework/internal/route"
+	"BackendFramework/internal/service"
 )
 
 func init() {
@@ -22,7 +24,6 @@ func init() {
 	log.Println("=== Environment Variables ===")
 	log.Println("ENVIRONMENT:", os.Getenv("ENVIRONMENT"))
 	log.Println("INFOBIP_SENDER:", os.Getenv("INFOBIP_SENDER"))
-	log.Println("INFOBIP_API_KEY:", os.Getenv("INFOBIP_API_KEY")[:20]+"...")
 	log.Println("=============================")
 
 	config.InitEnvronment()
@@ -31,6 +32,8 @@ func init() {
 	config.InitEncryptionVars()
 	config.InitBucketVars()
 	config.InitEmailVars()
+	config.InitAppVars()
+	config.InitOTPVars()
 
 	middleware.InitLogger()
 	middleware.InitValidator()
@@ -40,6 +43,19 @@ func init() {
 }
 
 func main() {
+	// Data lama /employees, /master/parfums, /master/discounts dipindah ke Karyawan, Parfum, Diskon
+	if err := service.MigrateLegacyMasterData(database.DbCore); err != nil {
+		log.Fatalf("Gagal migrasi data master lama: %v", err)
+	}
+
+	// Nomor HP pelanggan lama dinormalisasi sebelum unique index (outlet_id, phone) dipasang
+	if err := service.NewCustomerService(database.DbCore).EnsurePhoneIndex(); err != nil {
+		log.Printf("Gagal memasang unique index nomor HP pelanggan: %v", err)
+	}
+
+	// Pengingat WhatsApp untuk cucian Siap Ambil yang belum diambil
+	service.NewWhatsappNotificationService(database.DbCore).StartPickupReminderJob(time.Hour)
+
 	router := route.SetupRouter()
 	err := router.Run(":8080")
 	if err != nil {
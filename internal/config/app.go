@@ -0,0 +1,25 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+var (
+	APP_BASE_URL string
+	APP_NAME     string
+)
+
+// InitAppVars memuat URL publik backend, dipakai untuk link yang dibagikan ke pelanggan (nota, tracking)
+func InitAppVars() {
+	APP_BASE_URL = strings.TrimRight(os.Getenv("APP_BASE_URL"+Prefix), "/")
+	if APP_BASE_URL == "" {
+		APP_BASE_URL = "http://localhost:8080"
+	}
+
+	// Nama aplikasi yang tampil di aplikasi authenticator (issuer TOTP)
+	APP_NAME = os.Getenv("APP_NAME" + Prefix)
+	if APP_NAME == "" {
+		APP_NAME = "Laundry"
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"log"
 	"os"
 	"time"
 )
@@ -11,11 +12,21 @@ var(
 )
 
 const (
-	AccessTokenExpiry  = time.Hour * 24
-	RefreshTokenExpiry = time.Hour * 24 * 7
+	AccessTokenExpiry = time.Hour * 24
+	// RefreshTokenExpiry berlaku sejak login dan tidak diperpanjang saat rotasi (perangkat bersama di kasir)
+	RefreshTokenExpiry = time.Hour * 24
+	// RememberMeRefreshTokenExpiry diperpanjang setiap rotasi selama sesi masih dipakai
+	RememberMeRefreshTokenExpiry = time.Hour * 24 * 30
 )
 
 func InitEncryptionVars() {
 	JWT_SIGNATURE_KEY = os.Getenv("JWT_SIGNATURE_KEY"+Prefix)
 	ENCRYPTION_KEY = os.Getenv("ENCRYPTION_KEY"+Prefix)
+
+	// Dipakai langsung sebagai kunci AES (refresh token, secret 2FA): harus 16, 24 atau 32 byte
+	switch len(ENCRYPTION_KEY) {
+	case 16, 24, 32:
+	default:
+		log.Fatalf("ENCRYPTION_KEY%s harus 16, 24 atau 32 byte untuk AES, panjang sekarang %d", Prefix, len(ENCRYPTION_KEY))
+	}
 }
\ No newline at end of file
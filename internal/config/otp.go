@@ -0,0 +1,24 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+var (
+	OTP_CHANNEL_ORDER []string
+)
+
+// InitOTPVars memuat urutan channel pengiriman OTP, misal "whatsapp,sms,email".
+// Channel "fake" hanya mencetak kode ke log, untuk development tanpa kredit Infobip.
+func InitOTPVars() {
+	OTP_CHANNEL_ORDER = nil
+	for _, channel := range strings.Split(os.Getenv("OTP_CHANNEL_ORDER"+Prefix), ",") {
+		if channel = strings.ToLower(strings.TrimSpace(channel)); channel != "" {
+			OTP_CHANNEL_ORDER = append(OTP_CHANNEL_ORDER, channel)
+		}
+	}
+	if len(OTP_CHANNEL_ORDER) == 0 {
+		OTP_CHANNEL_ORDER = []string{"whatsapp", "sms", "email"}
+	}
+}
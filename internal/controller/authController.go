@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/api/idtoken"
+	"gorm.io/gorm"
 
 	"BackendFramework/internal/config"
 	"BackendFramework/internal/database"
@@ -24,6 +26,7 @@ type loginBody struct {
 	Email      string `json:"email" binding:"required,email"`
 	Password   string `json:"password" binding:"required"`
 	RememberMe string `json:"remember_me"`
+	DeviceID   string `json:"device_id"`
 }
 
 type refreshBody struct {
@@ -31,10 +34,6 @@ type refreshBody struct {
 	UserID       string `json:"user_id" binding:"required"`
 }
 
-type forgotPasswordInput struct {
-	NomorHP string `json:"nomor_hp" binding:"required"`
-}
-
 type verifyOTPInput struct {
 	NomorHP string `json:"nomor_hp" binding:"required"`
 	Code    string `json:"code" binding:"required,len=6"`
@@ -88,6 +87,14 @@ func generateTokens(userID string, outletID uint) (string, string, error) {
 	return accessToken, refreshToken, nil
 }
 
+// deviceIDFromRequest mengambil ID perangkat dari header X-Device-ID atau body login
+func deviceIDFromRequest(c *gin.Context, bodyDeviceID string) string {
+	if deviceID := strings.TrimSpace(c.GetHeader("X-Device-ID")); deviceID != "" {
+		return deviceID
+	}
+	return strings.TrimSpace(bodyDeviceID)
+}
+
 // saveTokenData saves token information to MongoDB
 func saveTokenData(userID string, user *model.User, accessToken, refreshToken, loginMethod string, c *gin.Context) error {
 	tokenData := bson.M{
@@ -98,16 +105,15 @@ func saveTokenData(userID string, user *model.User, accessToken, refreshToken, l
 		"last_user_agent":       c.GetHeader("User-Agent"),
 		"access_token":          accessToken,
 		"refresh_token":         refreshToken,
-		"refresh_token_expired": time.Now().Add(config.RefreshTokenExpiry),
 		"last_login":            time.Now(),
 		"is_valid_token":        "y",
 		"is_remember_me":        "n",
 		"login_method":          loginMethod,
-		"created_at":            time.Now(),
 		"updated_at":            time.Now(),
 	}
 
-	return service.UpsertTokenData(userID, tokenData)
+	_, err := service.SaveSession(userID, deviceIDFromRequest(c, ""), tokenData)
+	return err
 }
 
 
@@ -124,6 +130,7 @@ type updateProfileBody struct {
 
 type ResetPasswordInput struct {
     NomorHP     string `json:"nomor_hp" binding:"required"`
+    ResetToken  string `json:"reset_token" binding:"required"` // Dari respons VerifyOTP
     NewPassword string `json:"new_password" binding:"required,min=6"`
 }
 
@@ -170,6 +177,10 @@ func GoogleLogin(c *gin.Context) {
         return
     }
 
+    if requireTwoFactor(c, user, "google", "n", deviceIDFromRequest(c, "")) {
+        return
+    }
+
     // Logic Cek Outlet
     var outletCount int64
     database.DbCore.Model(&model.Outlet{}).Where("user_id = ?", user.ID).Count(&outletCount)
@@ -193,9 +204,11 @@ func GoogleLogin(c *gin.Context) {
     tokenData := bson.M{
         "user_id": userID, "email": user.Email, "username": user.NamaLengkap,
         "access_token": accessToken, "refresh_token": refreshToken,
-        "login_method": "google", "updated_at": time.Now(),
+        "last_ip_address": c.ClientIP(), "last_user_agent": c.GetHeader("User-Agent"),
+        "last_login": time.Now(), "is_valid_token": "y",
+        "login_method": "google", "outlet_id": outletID, "updated_at": time.Now(),
     }
-    service.UpsertTokenData(userID, tokenData)
+    service.SaveSession(userID, deviceIDFromRequest(c, ""), tokenData)
 
     // RESPONSE UTAMA
     c.JSON(http.StatusOK, gin.H{
@@ -307,23 +320,25 @@ func ResetPassword(c *gin.Context) {
         return
     }
 
-    // 2. Cari user berdasarkan nomor HP
-    var user model.User
-    if err := database.DbCore.Where("nomor_hp = ?", input.NomorHP).First(&user).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Nomor WhatsApp tidak terdaftar"})
-        return
-    }
-
-    // 3. Hash password baru
+    // 2. Hash password baru
     hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses password"})
         return
     }
 
-    // 4. Update password di database
-    if err := database.DbCore.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui password"})
+    // 3. Pakai reset token dari VerifyOTP dan update password di database
+    otpService := service.NewOTPService(database.DbCore)
+    if err := otpService.ResetPassword(input.NomorHP, input.ResetToken, string(hashedPassword)); err != nil {
+        switch {
+        case errors.Is(err, service.ErrResetTokenInvalid):
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Sesi reset password tidak valid atau sudah kadaluarsa, silakan verifikasi OTP lagi"})
+        case errors.Is(err, service.ErrOTPPhoneNotRegistered):
+            c.JSON(http.StatusNotFound, gin.H{"error": "Nomor WhatsApp tidak terdaftar"})
+        default:
+            middleware.LogError(err, "Gagal reset password")
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui password"})
+        }
         return
     }
 
@@ -436,7 +451,18 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	limiter.RecordLoginAttempt(body.Email, clientIP, true)
+	// Owner dengan 2FA aktif harus melewati POST /auth/login/2fa sebelum token diterbitkan
+	deviceID := deviceIDFromRequest(c, body.DeviceID)
+	if requireTwoFactor(c, user, "email", body.RememberMe, deviceID) {
+		return
+	}
+
+	completeOwnerLogin(c, user, limiter, body.Email, clientIP, "email", body.RememberMe, deviceID)
+}
+
+// completeOwnerLogin menerbitkan token owner setelah semua langkah verifikasi (password, 2FA) lolos
+func completeOwnerLogin(c *gin.Context, user *model.User, limiter *service.LoginLimiterService, email, clientIP, loginMethod, rememberMe, deviceID string) {
+	limiter.RecordLoginAttempt(email, clientIP, true)
 
 	// Track referral login
 	if err := service.TrackReferralLogin(user.ID); err != nil {
@@ -477,15 +503,17 @@ func Login(c *gin.Context) {
 		"last_user_agent":       c.GetHeader("User-Agent"),
 		"access_token":          accessToken,
 		"refresh_token":         refreshToken,
-		"refresh_token_expired": time.Now().Add(config.RefreshTokenExpiry),
 		"last_login":            time.Now(),
 		"is_valid_token":        "y",
-		"is_remember_me":        body.RememberMe,
-		"login_method":          "email",
+		"is_remember_me":        rememberMe,
+		"login_method":          loginMethod,
+		"account_type":          model.AccountTypeOwner,
+		"outlet_id":             outletID,
 		"updated_at":            time.Now(),
 	}
 
-	if err := service.UpsertTokenData(userID, tokenData); err != nil {
+	sessionID, err := service.SaveSession(userID, deviceID, tokenData)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":  http.StatusInternalServerError,
 			"error": "Gagal menyimpan data token",
@@ -506,6 +534,7 @@ func Login(c *gin.Context) {
 		"outlet_count":  outletCount,
 		"outlet_id":     outletID,
 		"referral_code": user.ReferralCode,
+		"session_id":    sessionID,
 	}
 
 	if user.ReferredBy != nil && *user.ReferredBy != "" {
@@ -526,6 +555,165 @@ func Login(c *gin.Context) {
 	})
 }
 
+// respondLoginFailed mencatat percobaan gagal dan mengembalikan sisa percobaan atau status terkunci
+func respondLoginFailed(c *gin.Context, limiter *service.LoginLimiterService, email, clientIP string) {
+	respondAttemptFailed(c, limiter, email, clientIP, "Email atau password salah")
+}
+
+// respondAttemptFailed sama seperti respondLoginFailed dengan pesan error sendiri (mis. kode 2FA salah);
+// semua kegagalan dihitung ke lockout email yang sama
+func respondAttemptFailed(c *gin.Context, limiter *service.LoginLimiterService, email, clientIP, errorMessage string) {
+	limiter.RecordLoginAttempt(email, clientIP, false)
+	remaining := limiter.GetRemainingAttempts(email)
+
+	if remaining <= 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"code":              http.StatusTooManyRequests,
+			"error":             "Akun terkunci",
+			"message":           fmt.Sprintf("Terlalu banyak percobaan gagal. Coba lagi dalam %d menit", int(service.LockoutDuration.Minutes())),
+			"locked_until":      time.Now().Add(service.LockoutDuration).Unix(),
+			"remaining_seconds": int(service.LockoutDuration.Seconds()),
+		})
+		return
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"code":               http.StatusUnauthorized,
+		"error":              errorMessage,
+		"remaining_attempts": remaining,
+		"message":            fmt.Sprintf("Login gagal. Sisa percobaan: %d", remaining),
+	})
+}
+
+// KaryawanLogin mengautentikasi staf (model.Karyawan) dan menerbitkan token berisi karyawan ID, outlet, role dan permissions
+func KaryawanLogin(c *gin.Context) {
+	var body loginBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":  http.StatusBadRequest,
+			"error": "Data request tidak valid",
+		})
+		return
+	}
+
+	body.Email = strings.ToLower(strings.TrimSpace(body.Email))
+	clientIP := c.ClientIP()
+
+	limiter := service.NewLoginLimiterService()
+
+	canAttempt, remainingLock, _, err := limiter.CanAttemptLogin(body.Email)
+	if err != nil {
+		middleware.LogError(err, "Gagal cek rate limiting")
+	}
+
+	if !canAttempt {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"code":              http.StatusTooManyRequests,
+			"error":             "Terlalu banyak percobaan login",
+			"message":           service.FormatLockMessage(remainingLock),
+			"locked_until":      time.Now().Add(remainingLock).Unix(),
+			"remaining_seconds": int(remainingLock.Seconds()),
+		})
+		return
+	}
+
+	karyawanService := service.NewKaryawanService(database.DbCore)
+	karyawan, err := karyawanService.GetByEmailAnyStatus(body.Email)
+	if err != nil || !karyawan.CheckPassword(body.Password) {
+		respondLoginFailed(c, limiter, body.Email, clientIP)
+		return
+	}
+
+	if karyawan.Status != "Aktif" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":  http.StatusForbidden,
+			"error": "Akun karyawan tidak aktif",
+		})
+		return
+	}
+
+	var outlet model.Outlet
+	if karyawan.OutletID == nil || database.DbCore.First(&outlet, *karyawan.OutletID).Error != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":  http.StatusForbidden,
+			"error": "Karyawan belum terhubung ke outlet",
+		})
+		return
+	}
+
+	limiter.RecordLoginAttempt(body.Email, clientIP, true)
+
+	ownerUserID := fmt.Sprintf("%d", outlet.UserID)
+	sessionKey := middleware.KaryawanSessionKey(karyawan.ID)
+
+	accessToken, err := middleware.GenerateKaryawanAccessToken(ownerUserID, karyawan)
+	if err != nil {
+		middleware.LogError(err, "Failed to generate karyawan access token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":  http.StatusInternalServerError,
+			"error": "Gagal generate token",
+		})
+		return
+	}
+
+	refreshToken, err := middleware.GenerateRefreshToken()
+	if err != nil {
+		middleware.LogError(err, "Failed to generate refresh token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":  http.StatusInternalServerError,
+			"error": "Gagal generate token",
+		})
+		return
+	}
+
+	tokenData := bson.M{
+		"user_id":               sessionKey,
+		"email":                 karyawan.Email,
+		"username":              karyawan.Nama,
+		"account_type":          model.AccountTypeKaryawan,
+		"karyawan_id":           karyawan.ID,
+		"owner_user_id":         ownerUserID,
+		"outlet_id":             outlet.ID,
+		"last_ip_address":       clientIP,
+		"last_user_agent":       c.GetHeader("User-Agent"),
+		"access_token":          accessToken,
+		"refresh_token":         refreshToken,
+		"last_login":            time.Now(),
+		"is_valid_token":        "y",
+		"is_remember_me":        body.RememberMe,
+		"login_method":          "karyawan",
+		"updated_at":            time.Now(),
+	}
+
+	sessionID, err := service.SaveSession(sessionKey, deviceIDFromRequest(c, body.DeviceID), tokenData)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":  http.StatusInternalServerError,
+			"error": "Gagal menyimpan data token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "Login berhasil",
+		"data": gin.H{
+			"user_id":       sessionKey, // Dipakai sebagai user_id saat refresh token
+			"karyawan_id":   karyawan.ID,
+			"username":      karyawan.Nama,
+			"email":         karyawan.Email,
+			"role":          karyawan.Role,
+			"permissions":   karyawan.PermissionList(),
+			"outlet_id":     outlet.ID,
+			"outlet_name":   outlet.NamaOutlet,
+			"access_token":  accessToken,
+			"refresh_token": refreshToken,
+			"session_id":    sessionID,
+		},
+		"token": accessToken,
+	})
+}
+
 func Logout(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -540,8 +728,15 @@ func Logout(c *gin.Context) {
 		return
 	}
 
+	// Hanya sesi perangkat ini yang dicabut; sesi di perangkat lain tetap aktif
 	userIDStr := fmt.Sprintf("%v", userID)
-	if err := service.InvalidateToken(userIDStr); err != nil {
+	var err error
+	if sessionID := c.GetString("session_id"); sessionID != "" {
+		err = service.RevokeSession(userIDStr, sessionID, false)
+	} else {
+		err = service.InvalidateToken(userIDStr)
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":  http.StatusInternalServerError,
 			"error": "Gagal logout user",
@@ -592,6 +787,9 @@ func RefreshAccessToken(c *gin.Context) {
 
 	storedToken, err := service.ValidateRefreshToken(body.UserID, body.RefreshToken)
 	if err != nil {
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			middleware.LogError(err, "Refresh token reuse: "+body.UserID)
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code":  http.StatusUnauthorized,
 			"error": err.Error(),
@@ -599,26 +797,34 @@ func RefreshAccessToken(c *gin.Context) {
 		return
 	}
 
-	extendRefresh := false
+	// Refresh token kedaluwarsa tidak diperpanjang lagi, termasuk remember me
 	if time.Now().After(storedToken.RefreshTokenExpiredAt) {
-		if storedToken.IsRememberMe != "y" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":  http.StatusUnauthorized,
-				"error": "Refresh token kadaluarsa. Silakan login kembali",
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":  http.StatusUnauthorized,
+			"error": "Refresh token kadaluarsa. Silakan login kembali",
+		})
+		return
+	}
+
+	var newAccessToken string
+	if storedToken.KaryawanID != 0 {
+		var karyawan model.Karyawan
+		if err := database.DbCore.Where("kar_id = ?", storedToken.KaryawanID).First(&karyawan).Error; err != nil || karyawan.Status != "Aktif" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":  http.StatusForbidden,
+				"error": "Akun karyawan tidak aktif",
 			})
 			return
 		}
-		extendRefresh = true
-	}
-
-	if extendRefresh {
-		updateData := bson.M{"refresh_token_expired": time.Now().Add(config.RefreshTokenExpiry)}
-		if err := service.UpsertTokenData(storedToken.UserId, updateData); err != nil {
-			middleware.LogError(err, "Gagal memperpanjang refresh token")
+		newAccessToken, err = middleware.GenerateKaryawanAccessToken(storedToken.OwnerUserID, &karyawan)
+	} else {
+		// Outlet pilihan /auth/switch-outlet ikut dipertahankan; sesi lama tanpa outlet_id memakai outlet pertama
+		outletID := storedToken.OutletID
+		if outletID == 0 {
+			outletID = ownerDefaultOutletID(storedToken.UserId)
 		}
+		newAccessToken, err = middleware.GenerateScopedAccessToken(storedToken.UserId, outletID, storedToken.OutletScope)
 	}
-
-	newAccessToken, err := middleware.GenerateAccessToken(storedToken.UserId, 0)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":  http.StatusInternalServerError,
@@ -627,18 +833,31 @@ func RefreshAccessToken(c *gin.Context) {
 		return
 	}
 
-	if err := service.RefreshAccessToken(storedToken.UserId, newAccessToken); err != nil {
+	// Refresh token dirotasi setiap dipakai; token lama tidak berlaku lagi
+	newRefreshToken, err := middleware.GenerateRefreshToken()
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":  http.StatusInternalServerError,
-			"error": "Gagal update access token",
+			"error": "Gagal generate refresh token baru",
+		})
+		return
+	}
+
+	refreshExpiredAt, err := service.RotateRefreshToken(storedToken, body.RefreshToken, newAccessToken, newRefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":  http.StatusUnauthorized,
+			"error": err.Error(),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"code":         http.StatusOK,
-		"message":      "Access token berhasil diperbarui",
-		"access_token": newAccessToken,
+		"code":                  http.StatusOK,
+		"message":               "Access token berhasil diperbarui",
+		"access_token":          newAccessToken,
+		"refresh_token":         newRefreshToken,
+		"refresh_token_expired": refreshExpiredAt,
 	})
 }
 
@@ -662,13 +881,36 @@ func GetActiveSessions(c *gin.Context) {
 		return
 	}
 
+	// Owner juga melihat sesi karyawan di outlet miliknya
+	if _, isKaryawan := c.Get("karyawan_id"); !isKaryawan {
+		staffTokens, err := service.GetStaffActiveTokens(userIDStr)
+		if err != nil {
+			middleware.LogError(err, "Gagal mengambil sesi karyawan")
+		}
+		tokens = append(tokens, staffTokens...)
+	}
+
+	currentSessionID := c.GetString("session_id")
+
 	var sessions []gin.H
 	for _, token := range tokens {
+		accountType := token.AccountType
+		if accountType == "" {
+			accountType = model.AccountTypeOwner
+		}
+
 		sessions = append(sessions, gin.H{
+			"session_id":      token.SessionID,
+			"device_id":       token.DeviceID,
+			"is_current":      token.SessionID != "" && token.SessionID == currentSessionID,
+			"account_type":    accountType,
+			"username":        token.Username,
+			"karyawan_id":     token.KaryawanID,
 			"last_login":      token.LastLogin,
 			"last_ip_address": token.LastIpAddress,
 			"last_user_agent": token.LastUserAgent,
 			"is_remember_me":  token.IsRememberMe,
+			"created_at":      token.CreatedAt,
 		})
 	}
 
@@ -679,6 +921,153 @@ func GetActiveSessions(c *gin.Context) {
 	})
 }
 
+// RevokeSession mencabut satu sesi berdasarkan session_id; owner juga bisa mencabut sesi karyawannya
+func RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":  http.StatusUnauthorized,
+			"error": "Unauthorized",
+		})
+		return
+	}
+
+	_, isKaryawan := c.Get("karyawan_id")
+	err := service.RevokeSession(fmt.Sprintf("%v", userID), c.Param("id"), !isKaryawan)
+	if err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":  http.StatusNotFound,
+				"error": "Sesi tidak ditemukan",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":  http.StatusInternalServerError,
+			"error": "Gagal mencabut sesi",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "Sesi berhasil dicabut",
+	})
+}
+
+// ownerDefaultOutletID mengembalikan outlet pertama milik owner, 0 jika belum punya outlet
+func ownerDefaultOutletID(userID string) uint {
+	var outlet model.Outlet
+	if err := database.DbCore.Where("user_id = ?", userID).Order("id ASC").First(&outlet).Error; err != nil {
+		return 0
+	}
+	return outlet.ID
+}
+
+// SwitchOutlet menerbitkan access token owner untuk outlet lain tanpa logout. Dengan scope "all"
+// token juga berlaku untuk laporan gabungan semua outlet; outlet_id tetap menjadi outlet aktif default.
+func SwitchOutlet(c *gin.Context) {
+	if _, isKaryawan := c.Get("karyawan_id"); isKaryawan {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":  http.StatusForbidden,
+			"error": "Karyawan hanya dapat mengakses outlet tempatnya bekerja",
+		})
+		return
+	}
+
+	var input model.SwitchOutletInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":  http.StatusBadRequest,
+			"error": "Data request tidak valid",
+		})
+		return
+	}
+
+	scope := strings.ToLower(strings.TrimSpace(input.Scope))
+	if scope != "" && scope != middleware.OutletScopeAll {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":  http.StatusBadRequest,
+			"error": "scope hanya boleh kosong atau \"all\"",
+		})
+		return
+	}
+
+	userID := c.GetString("userID")
+	outletID := input.OutletID
+	if outletID == 0 && scope == middleware.OutletScopeAll {
+		outletID = c.GetUint("outlet_id")
+		if outletID == 0 {
+			outletID = ownerDefaultOutletID(userID)
+		}
+	}
+	if outletID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":  http.StatusBadRequest,
+			"error": "outlet_id wajib diisi",
+		})
+		return
+	}
+
+	var outlet model.Outlet
+	if err := database.DbCore.Where("id = ? AND user_id = ?", outletID, c.GetUint("user_id")).First(&outlet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.RespondOutletForbidden(c)
+			return
+		}
+		middleware.LogError(err, "Gagal memuat outlet untuk ganti outlet")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":  http.StatusInternalServerError,
+			"error": "Gagal mengganti outlet",
+		})
+		return
+	}
+
+	sessionID := c.GetString("session_id")
+	if sessionID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":  http.StatusUnauthorized,
+			"error": "Sesi lama tidak mendukung ganti outlet, silakan login ulang",
+		})
+		return
+	}
+
+	accessToken, err := middleware.GenerateScopedAccessToken(userID, outlet.ID, scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":  http.StatusInternalServerError,
+			"error": "Gagal generate access token",
+		})
+		return
+	}
+
+	if err := service.SwitchSessionOutlet(userID, sessionID, accessToken, outlet.ID, scope); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":  http.StatusUnauthorized,
+				"error": "Sesi tidak ditemukan, silakan login ulang",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":  http.StatusInternalServerError,
+			"error": "Gagal mengganti outlet",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "Outlet aktif berhasil diganti",
+		"data": gin.H{
+			"access_token": accessToken,
+			"outlet_id":    outlet.ID,
+			"nama_outlet":  outlet.NamaOutlet,
+			"outlet_scope": scope,
+		},
+	})
+}
+
 func GetMyReferralStats(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -782,7 +1171,7 @@ func GetMyReferrals(c *gin.Context) {
 }
 
 func ForgotPassword(c *gin.Context) {
-	var input forgotPasswordInput
+	var input model.ForgotPasswordInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":  http.StatusBadRequest,
@@ -800,17 +1189,27 @@ func ForgotPassword(c *gin.Context) {
 		return
 	}
 
-	user := service.GetOneUserByPhone(input.NomorHP)
-	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":  http.StatusNotFound,
-			"error": "Nomor HP tidak terdaftar",
-		})
-		return
-	}
-
+	// Cek nomor terdaftar dilakukan di SendOTP agar ikut terhitung kuota per IP
 	otpService := service.NewOTPService(database.DbCore)
-	if err := otpService.SendOTP(input.NomorHP, "forgot_password"); err != nil {
+	channel, err := otpService.SendOTP(input.NomorHP, service.OTPPurposeForgotPassword, c.ClientIP(), input.Channel)
+	if err != nil {
+		if respondOTPRateLimited(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrOTPChannelUnknown) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":  http.StatusBadRequest,
+				"error": "Channel OTP tidak dikenali, gunakan whatsapp, sms atau email",
+			})
+			return
+		}
+		if errors.Is(err, service.ErrOTPPhoneNotRegistered) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":  http.StatusNotFound,
+				"error": "Nomor HP tidak terdaftar",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":  http.StatusInternalServerError,
 			"error": err.Error(),
@@ -819,11 +1218,31 @@ func ForgotPassword(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"code":    http.StatusOK,
-		"message": "Kode OTP telah dikirim ke WhatsApp Anda",
+		"code":        http.StatusOK,
+		"message":     "Kode OTP telah dikirim ke " + service.OTPChannelLabel(channel) + " Anda",
+		"channel":     channel,
+		"retry_after": int(service.OTPSendCooldown.Seconds()),
+		"expires_in":  int(service.OTPExpiry.Seconds()),
 	})
 }
 
+// respondOTPRateLimited mengirim 429 dengan header Retry-After jika err adalah batas OTP; true berarti respons sudah dikirim
+func respondOTPRateLimited(c *gin.Context, err error) bool {
+	var limitErr *service.OTPRateLimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+
+	retryAfter := limitErr.RetryAfterSeconds()
+	c.Header("Retry-After", fmt.Sprintf("%d", retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"code":        http.StatusTooManyRequests,
+		"error":       limitErr.Message,
+		"retry_after": retryAfter,
+	})
+	return true
+}
+
 func VerifyOTP(c *gin.Context) {
 	var input verifyOTPInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -835,7 +1254,10 @@ func VerifyOTP(c *gin.Context) {
 	}
 
 	otpService := service.NewOTPService(database.DbCore)
-	valid, err := otpService.VerifyOTP(input.NomorHP, input.Code, "forgot_password")
+	valid, err := otpService.VerifyOTP(input.NomorHP, input.Code, service.OTPPurposeForgotPassword)
+	if respondOTPRateLimited(c, err) {
+		return
+	}
 	if err != nil || !valid {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":  http.StatusBadRequest,
@@ -844,9 +1266,22 @@ func VerifyOTP(c *gin.Context) {
 		return
 	}
 
+	// Reset password hanya diterima dengan token ini, bukan sekadar nomor HP
+	resetToken, err := otpService.IssueResetToken(input.NomorHP)
+	if err != nil {
+		middleware.LogError(err, "Gagal membuat reset token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":  http.StatusInternalServerError,
+			"error": "Gagal memproses verifikasi OTP",
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"code":    http.StatusOK,
-		"message": "Kode OTP berhasil diverifikasi",
+		"code":        http.StatusOK,
+		"message":     "Kode OTP berhasil diverifikasi",
+		"reset_token": resetToken,
+		"expires_in":  int(service.ResetTokenExpiry.Seconds()),
 	})
 }
 
@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"BackendFramework/internal/model"
+	"BackendFramework/internal/service"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CashShiftController struct {
+	service *service.CashShiftService
+}
+
+func NewCashShiftController(service *service.CashShiftService) *CashShiftController {
+	return &CashShiftController{service: service}
+}
+
+func cashShiftErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrCashShiftLocked), errors.Is(err, service.ErrCashShiftAlreadyOpen):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrCashShiftNotFound), errors.Is(err, service.ErrNoOpenCashShift):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// Open membuka shift kasir dengan modal awal laci
+func (c *CashShiftController) Open(ctx *gin.Context) {
+	var input model.OpenCashShiftInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	shift, err := c.service.Open(ctx.GetUint("outlet_id"), input, getStaffName(ctx))
+	if err != nil {
+		ctx.JSON(cashShiftErrorStatus(err), gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"success": true, "message": "Shift kasir dibuka", "data": shift})
+}
+
+// GetCurrent mengembalikan shift yang sedang berjalan beserta kas yang seharusnya ada di laci
+func (c *CashShiftController) GetCurrent(ctx *gin.Context) {
+	shift, err := c.service.GetCurrent(ctx.GetUint("outlet_id"))
+	if err != nil {
+		ctx.JSON(cashShiftErrorStatus(err), gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"success": true, "data": shift})
+}
+
+// Close menutup shift (tutup kasir) dengan jumlah uang yang dihitung kasir
+func (c *CashShiftController) Close(ctx *gin.Context) {
+	var input model.CloseCashShiftInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	shift, err := c.service.Close(ctx.GetUint("outlet_id"), input, getStaffName(ctx))
+	if err != nil {
+		ctx.JSON(cashShiftErrorStatus(err), gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"success": true, "message": "Tutup kasir berhasil", "data": shift})
+}
+
+// GetAll menampilkan riwayat shift, filter start_date dan end_date (YYYY-MM-DD)
+func (c *CashShiftController) GetAll(ctx *gin.Context) {
+	shifts, err := c.service.GetAll(ctx.GetUint("outlet_id"), ctx.Query("start_date"), ctx.Query("end_date"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"success": true, "data": shifts})
+}
+
+func (c *CashShiftController) GetByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ID shift tidak valid"})
+		return
+	}
+
+	shift, err := c.service.GetByID(uint(id), ctx.GetUint("outlet_id"))
+	if err != nil {
+		ctx.JSON(cashShiftErrorStatus(err), gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"success": true, "data": shift})
+}
+
+// Print menyiapkan laporan tutup kasir untuk printer thermal, format text (default) atau escpos
+func (c *CashShiftController) Print(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.NotaPrintResponse{Success: false, Message: "ID shift tidak valid"})
+		return
+	}
+
+	outletID := ctx.GetUint("outlet_id")
+	shift, err := c.service.GetByID(uint(id), outletID)
+	if err != nil {
+		ctx.JSON(cashShiftErrorStatus(err), model.NotaPrintResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	settings := c.service.GetPrintSettings(outletID)
+	width := 32
+	if settings.PrinterSize == 80 {
+		width = 48
+	}
+
+	switch ctx.DefaultQuery("format", "text") {
+	case "escpos":
+		ctx.JSON(http.StatusOK, model.NotaPrintResponse{
+			Success:   true,
+			Message:   "Laporan tutup kasir siap dicetak",
+			PrintData: base64.StdEncoding.EncodeToString(generateCashShiftEscpos(shift, settings, width)),
+			Format:    "escpos",
+			Encoding:  "base64",
+		})
+	case "text":
+		ctx.JSON(http.StatusOK, model.NotaPrintResponse{
+			Success:   true,
+			Message:   "Laporan tutup kasir siap dicetak",
+			PrintData: generateCashShiftText(shift, settings, width),
+			Format:    "text",
+		})
+	default:
+		ctx.JSON(http.StatusBadRequest, model.NotaPrintResponse{Success: false, Message: "format harus text atau escpos"})
+	}
+}
+
+// cashShiftHeader adalah judul laporan yang dicetak rata tengah
+func cashShiftHeader(settings *model.NotaSettings, shift *model.CashShift) []string {
+	header := make([]string, 0, 3)
+	if settings.ShowBusinessName && settings.BusinessName != "" {
+		header = append(header, settings.BusinessName)
+	}
+	if shift.Status == model.CashShiftClosed {
+		header = append(header, "LAPORAN TUTUP KASIR")
+	} else {
+		header = append(header, "LAPORAN KASIR (SEMENTARA)")
+	}
+	return header
+}
+
+// cashShiftBody menyusun isi laporan (rata kiri) yang sama untuk format text dan escpos
+func cashShiftBody(shift *model.CashShift, width int) []string {
+	row := func(label string, amount float64) string {
+		// formatCurrency tidak menangani angka negatif (selisih kurang, kas keluar)
+		value := formatCurrency(amount)
+		if amount < 0 {
+			value = "-" + formatCurrency(-amount)
+		}
+		return fmt.Sprintf("%-*s %12s", width-13, label, value)
+	}
+
+	body := []string{
+		fmt.Sprintf("Shift: #%d", shift.ID),
+		fmt.Sprintf("Buka: %s (%s)", shift.OpenedAt.Format("02/01/2006 15:04"), shift.OpenedBy),
+	}
+	if shift.ClosedAt != nil {
+		body = append(body, fmt.Sprintf("Tutup: %s (%s)", shift.ClosedAt.Format("02/01/2006 15:04"), shift.ClosedBy))
+	}
+	body = append(body, repeatChar("-", width))
+
+	if shift.Breakdown != nil && len(shift.Breakdown.PaymentMethods) > 0 {
+		body = append(body, "Pemasukan:")
+		for _, method := range shift.Breakdown.PaymentMethods {
+			body = append(body, row(fmt.Sprintf(" %s (%d)", method.Name, method.Jumlah), method.Total))
+		}
+		body = append(body, repeatChar("-", width))
+	}
+
+	if shift.Breakdown != nil && len(shift.Breakdown.Expenses) > 0 {
+		body = append(body, "Pengeluaran:")
+		for _, expense := range shift.Breakdown.Expenses {
+			body = append(body, row(fmt.Sprintf(" %s (%d)", expense.KategoriNama, expense.Jumlah), float64(expense.Total)))
+		}
+		body = append(body, repeatChar("-", width))
+	}
+
+	body = append(body,
+		row("Modal Awal", shift.OpeningCash),
+		row("Kas Masuk", shift.CashIn),
+		row("Kas Keluar", -shift.CashOut),
+		row("Kas Seharusnya", shift.ExpectedCash),
+	)
+	if shift.CountedCash != nil {
+		body = append(body,
+			row("Kas Dihitung", *shift.CountedCash),
+			row("Selisih", shift.Difference),
+		)
+	}
+	body = append(body, row("Non Tunai", shift.NonCashIn))
+
+	if shift.ClosingNotes != "" {
+		body = append(body, repeatChar("-", width), "Catatan: "+shift.ClosingNotes)
+	}
+
+	return body
+}
+
+func generateCashShiftText(shift *model.CashShift, settings *model.NotaSettings, width int) string {
+	output := ""
+	for _, line := range cashShiftHeader(settings, shift) {
+		output += centerText(line, width) + "\n"
+	}
+	output += repeatChar("=", width) + "\n"
+	for _, line := range cashShiftBody(shift, width) {
+		output += line + "\n"
+	}
+	output += repeatChar("=", width) + "\n"
+	return output
+}
+
+func generateCashShiftEscpos(shift *model.CashShift, settings *model.NotaSettings, width int) []byte {
+	w := &escposWriter{}
+
+	w.cmd(escposInit)
+	w.cmd(escposAlignCenter)
+	w.cmd(escposBoldOn)
+	for _, line := range cashShiftHeader(settings, shift) {
+		w.line(line)
+	}
+	w.cmd(escposBoldOff)
+
+	w.cmd(escposAlignLeft)
+	w.line(repeatChar("=", width))
+	for _, line := range cashShiftBody(shift, width) {
+		w.line(line)
+	}
+	w.line(repeatChar("=", width))
+	w.cmd(escposFeedAndCut)
+
+	return w.buf.Bytes()
+}
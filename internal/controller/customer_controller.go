@@ -2,21 +2,37 @@ package controller
 
 import (
 	"BackendFramework/internal/database"
+	"BackendFramework/internal/middleware"
 	"BackendFramework/internal/model"
+	"BackendFramework/internal/service"
+	"errors"
+	"fmt"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// CreateCustomer menangani pendaftaran pelanggan baru
-func CreateCustomer(c *gin.Context) {
-	var input struct {
-		Name    string  `json:"name" binding:"required"`
-		Phone   string  `json:"phone" binding:"required"`
-		Gender  string  `json:"gender"`
-		OTP     *string `json:"otp"`
-		Address string  `json:"address"`
+// respondCustomerError memetakan error CustomerService; nomor ganda dijawab 409 beserta pelanggan yang sudah ada
+func respondCustomerError(c *gin.Context, existing *model.Customer, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrCustomerPhoneExists):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error(), "data": existing})
+	case errors.Is(err, service.ErrCustomerPhoneInvalid), errors.Is(err, service.ErrCustomerMergeInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+	case errors.Is(err, service.ErrCustomerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": fallback})
 	}
+}
+
+// CreateCustomer menangani pendaftaran pelanggan baru; nomor HP dinormalisasi dan unik per outlet
+func CreateCustomer(c *gin.Context) {
+	var input model.CustomerInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
@@ -25,73 +41,221 @@ func CreateCustomer(c *gin.Context) {
 
 	outletID := c.GetUint("outlet_id")
 
-	customer := model.Customer{
-		OutletID: outletID,
-		Name:     input.Name,
-		Phone:    input.Phone,
-		Gender:   input.Gender,
-		OTP:      input.OTP,
-		Address:  input.Address,
-	}
-
-	if err := database.DbCore.Create(&customer).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal menyimpan data pelanggan"})
+	customer, err := service.NewCustomerService(database.DbCore).Create(outletID, input)
+	if err != nil {
+		respondCustomerError(c, customer, err, "Gagal menyimpan data pelanggan")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": customer})
 }
 
-// GetCustomers mengambil semua daftar pelanggan di suatu outlet
+// GetCustomers mencari pelanggan outlet (?q= nama, nomor HP atau alamat) dengan pagination
 func GetCustomers(c *gin.Context) {
-	outletID, _ := c.Get("outlet_id")
-	var customers []model.Customer
-
-	err := database.DbCore.
-		Where("outlet_id = ?", outletID).
-		Order("id desc"). // Menampilkan data terbaru di atas
-		Find(&customers).Error
+	var filter model.CustomerFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Filter tidak valid"})
+		return
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 10
+	}
 
+	customers, total, err := service.NewCustomerService(database.DbCore).List(c.GetUint("outlet_id"), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal mengambil data pelanggan"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "data": customers})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    customers,
+		"pagination": gin.H{
+			"page":        filter.Page,
+			"limit":       filter.Limit,
+			"total":       total,
+			"total_pages": (total + int64(filter.Limit) - 1) / int64(filter.Limit),
+		},
+	})
 }
 
 // UpdateCustomer untuk memperbarui informasi pelanggan
 func UpdateCustomer(c *gin.Context) {
-	customerID := c.Param("id")
-	outletID, _ := c.Get("outlet_id")
-
-	var input struct {
-		Name    string  `json:"name"`
-		Phone   string  `json:"phone"`
-		Gender  string  `json:"gender"`
-		Address string  `json:"address"`
+	customerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ID pelanggan tidak valid"})
+		return
 	}
 
+	var input model.UpdateCustomerInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
 	}
 
-	result := database.DbCore.Model(&model.Customer{}).
-		Where("id = ? AND outlet_id = ?", customerID, outletID).
-		Updates(model.Customer{
-			Name:    input.Name,
-			Phone:   input.Phone,
-			Gender:  input.Gender,
-			Address: input.Address,
-		})
+	customer, err := service.NewCustomerService(database.DbCore).Update(uint(customerID), c.GetUint("outlet_id"), input)
+	if err != nil {
+		respondCustomerError(c, customer, err, "Gagal update data")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": customer})
+}
+
+// GetCustomerSummary menampilkan profil pelanggan untuk kasir: riwayat pesanan, total belanja dan sisa tagihan
+func GetCustomerSummary(c *gin.Context) {
+	customerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ID pelanggan tidak valid"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	summary, err := service.NewCustomerService(database.DbCore).Summary(uint(customerID), c.GetUint("outlet_id"), page, limit)
+	if err != nil {
+		respondCustomerError(c, nil, err, "Gagal mengambil ringkasan pelanggan")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    summary,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       summary.HistoryTotal,
+			"total_pages": (summary.HistoryTotal + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// PreviewCustomerMerge menampilkan field yang berbeda dan hasil penggabungan tanpa mengubah data
+func PreviewCustomerMerge(c *gin.Context) {
+	var input model.CustomerMergeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
 
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal update data"})
+	preview, existing, err := service.NewCustomerService(database.DbCore).PreviewMerge(c.GetUint("outlet_id"), input)
+	if err != nil {
+		respondCustomerError(c, existing, err, "Gagal menyiapkan penggabungan pelanggan")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": preview})
+}
+
+// MergeCustomers menggabungkan pelanggan ganda ke satu pelanggan; pesanan dan nota dipindahkan ke survivor
+func MergeCustomers(c *gin.Context) {
+	var input model.CustomerMergeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	customer, merge, err := service.NewCustomerService(database.DbCore).Merge(c.GetUint("outlet_id"), input, getStaffName(c))
+	if err != nil {
+		respondCustomerError(c, customer, err, "Gagal menggabungkan pelanggan")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Pelanggan berhasil digabungkan",
+		"data": gin.H{
+			"customer": customer,
+			"merge":    merge,
+		},
+	})
+}
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// GetCustomerImportTemplate mengunduh template .xlsx untuk import pelanggan
+func GetCustomerImportTemplate(c *gin.Context) {
+	content, err := service.NewCustomerService(database.DbCore).ImportTemplate()
+	if err != nil {
+		middleware.LogError(err, "Gagal membuat template import pelanggan")
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal membuat template import"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="template-import-pelanggan.xlsx"`)
+	c.Data(http.StatusOK, xlsxContentType, content)
+}
+
+// ImportCustomers membuat pelanggan dari file .xlsx (form field "file"). dry_run=true hanya memvalidasi
+// dan mengembalikan laporan per baris tanpa menyimpan data.
+func ImportCustomers(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "File Excel wajib diunggah"})
+		return
+	}
+
+	const maxFileSize = 5 * 1024 * 1024
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if ok, errMsg := middleware.ValidateFile(maxFileSize, file.Size, ext, []string{".xlsx"}); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": errMsg})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultPostForm("dry_run", c.Query("dry_run")))
+
+	content, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "File Excel tidak bisa dibaca"})
+		return
+	}
+	defer content.Close()
+
+	result, err := service.NewCustomerService(database.DbCore).ImportExcel(c.GetUint("outlet_id"), content, dryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrCustomerImportHeader),
+			errors.Is(err, service.ErrCustomerImportTooMany),
+			errors.Is(err, service.ErrCustomerImportFile):
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		case errors.Is(err, service.ErrCustomerPhoneExists):
+			c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+		default:
+			middleware.LogError(err, "Gagal import pelanggan")
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal import pelanggan"})
+		}
+		return
+	}
+
+	message := fmt.Sprintf("%d pelanggan berhasil diimport", result.Created)
+	if dryRun {
+		message = fmt.Sprintf("Dry run: %d pelanggan akan diimport", result.Created)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": message, "data": result})
+}
+
+// ExportCustomers mengunduh pelanggan outlet (opsional ?q= seperti GetCustomers) sebagai .xlsx
+func ExportCustomers(c *gin.Context) {
+	content, err := service.NewCustomerService(database.DbCore).ExportExcel(c.GetUint("outlet_id"), c.Query("q"))
+	if err != nil {
+		middleware.LogError(err, "Gagal export pelanggan")
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal export pelanggan"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	filename := fmt.Sprintf("pelanggan-%d-%s.xlsx", c.GetUint("outlet_id"), time.Now().Format("20060102"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, xlsxContentType, content)
 }
 
 // DeleteCustomer menghapus data pelanggan
@@ -1,8 +1,11 @@
 package controller
 
 import (
+	"BackendFramework/internal/middleware"
 	"BackendFramework/internal/model"
 	"BackendFramework/internal/service"
+	"BackendFramework/internal/thirdparty"
+	"encoding/base64"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -23,6 +26,41 @@ func NewNotaController(notaService service.NotaService) *NotaController {
 	}
 }
 
+// respondNotaNotFound dipakai juga untuk nota outlet lain agar keberadaannya tidak bocor ke tenant lain
+func respondNotaNotFound(ctx *gin.Context) {
+	ctx.JSON(http.StatusNotFound, model.NotaSettingsErrorResponse{
+		Success: false,
+		Message: "Nota not found",
+	})
+}
+
+// notaInActiveOutlet memastikan nota milik outlet aktif hasil OutletScope; false berarti respons sudah dikirim
+func (c *NotaController) notaInActiveOutlet(ctx *gin.Context, notaDataID uint) bool {
+	notaData, err := c.notaService.GetNotaByID(notaDataID)
+	if err != nil || notaData.OutletID != ctx.GetUint("outlet_id") {
+		respondNotaNotFound(ctx)
+		return false
+	}
+	return true
+}
+
+// requireOutletAccess memeriksa outlet_id dari body request; false berarti respons sudah dikirim
+func requireOutletAccess(ctx *gin.Context, outletID uint) bool {
+	allowed, err := middleware.CanAccessOutlet(ctx, outletID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.NotaSettingsErrorResponse{
+			Success: false,
+			Message: "Gagal memeriksa akses outlet",
+		})
+		return false
+	}
+	if !allowed {
+		middleware.RespondOutletForbidden(ctx)
+		return false
+	}
+	return true
+}
+
 func (c *NotaController) GenerateNota(ctx *gin.Context) {
 	var input model.NotaGenerateInput
 
@@ -42,6 +80,10 @@ func (c *NotaController) GenerateNota(ctx *gin.Context) {
 		return
 	}
 
+	if !requireOutletAccess(ctx, input.OutletID) {
+		return
+	}
+
 	notaData, err := c.notaService.GenerateNota(&input)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, model.NotaSettingsErrorResponse{
@@ -77,6 +119,11 @@ func (c *NotaController) GetNotaByID(ctx *gin.Context) {
 		return
 	}
 
+	if notaData.OutletID != ctx.GetUint("outlet_id") {
+		respondNotaNotFound(ctx)
+		return
+	}
+
 	ctx.JSON(http.StatusOK, model.NotaGenerateResponse{
 		Success: true,
 		Message: "Nota retrieved successfully",
@@ -96,6 +143,11 @@ func (c *NotaController) GetNotaByTransactionID(ctx *gin.Context) {
 		return
 	}
 
+	if notaData.OutletID != ctx.GetUint("outlet_id") {
+		respondNotaNotFound(ctx)
+		return
+	}
+
 	ctx.JSON(http.StatusOK, model.NotaGenerateResponse{
 		Success: true,
 		Message: "Nota retrieved successfully",
@@ -164,6 +216,10 @@ func (c *NotaController) PrintNota(ctx *gin.Context) {
 		return
 	}
 
+	if !c.notaInActiveOutlet(ctx, input.NotaDataID) {
+		return
+	}
+
 	var printFormat *model.NotaPrintFormat
 	var err error
 
@@ -180,6 +236,19 @@ func (c *NotaController) PrintNota(ctx *gin.Context) {
 		})
 		return
 	}
+
+	// ESC/POS dikirim sebagai base64 agar bisa di-decode lalu dialirkan langsung ke printer Bluetooth
+	if input.Format == "escpos" {
+		ctx.JSON(http.StatusOK, model.NotaPrintResponse{
+			Success:   true,
+			Message:   "Nota prepared for printing",
+			PrintData: base64.StdEncoding.EncodeToString(c.generateEscposData(printFormat)),
+			Format:    "escpos",
+			Encoding:  "base64",
+		})
+		return
+	}
+
 	printData := c.generatePrintData(printFormat)
 
 	ctx.JSON(http.StatusOK, model.NotaPrintResponse{
@@ -209,6 +278,10 @@ func (c *NotaController) PreviewNota(ctx *gin.Context) {
 		return
 	}
 
+	if !requireOutletAccess(ctx, input.OutletID) {
+		return
+	}
+
 	printFormat, err := c.notaService.GenerateNotaPreview(&input)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, model.NotaSettingsErrorResponse{
@@ -251,7 +324,11 @@ func (c *NotaController) VoidNota(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.notaService.VoidNota(uint(id), body.Reason); err != nil {
+	if !c.notaInActiveOutlet(ctx, uint(id)) {
+		return
+	}
+
+	if err := c.notaService.VoidNota(uint(id), body.Reason, getStaffName(ctx)); err != nil {
 		ctx.JSON(http.StatusInternalServerError, model.NotaSettingsErrorResponse{
 			Success: false,
 			Message: err.Error(),
@@ -633,17 +710,35 @@ func (c *NotaController) generateHTMLPreview(format *model.NotaPrintFormat) stri
             <div class="payment-row">
                 <span class="total-label">Bayar</span>
                 <span class="total-value">Rp ` + formatCurrency(data.PaymentAmount) + `</span>
-            </div>
+            </div>`
+	if data.Outstanding > 0 {
+		html += `
+            <div class="payment-row">
+                <span class="total-label">Sisa Tagihan</span>
+                <span class="total-value">Rp ` + formatCurrency(data.Outstanding) + `</span>
+            </div>`
+	} else {
+		html += `
             <div class="payment-row">
                 <span class="total-label">Kembali</span>
                 <span class="total-value">Rp ` + formatCurrency(data.Change) + `</span>
-            </div>
+            </div>`
+	}
+	html += `
         </div>`
-	if settings.ShowQRCode && data.QRCodeData != "" {
+	if settings.ShowQRCode && strings.HasPrefix(data.QRCodeData, "data:") {
 		html += `
         <div class="qr-code">
             <img src="` + data.QRCodeData + `" alt="QR Code">
         </div>`
+	} else if settings.ShowQRCode && data.QRCodeData != "" {
+		// QRCodeData berupa teks (link tracking pesanan) dirender dulu menjadi gambar QR
+		if png, err := thirdparty.GenerateQrPng(data.QRCodeData, 256); err == nil {
+			html += `
+        <div class="qr-code">
+            <img src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(png) + `" alt="QR Code">
+        </div>`
+		}
 	} else if settings.ShowQRCode && format.QRCodeBase64 != "" {
 		html += `
         <div class="qr-code">
@@ -3,12 +3,16 @@ package controller
 import (
 	"BackendFramework/internal/database"
 	"BackendFramework/internal/model"
+	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// Endpoint /employees dipertahankan untuk aplikasi lama. Datanya sekarang disimpan di Karyawan
+// (ac_karyawan); permission boolean lama dipetakan ke model.AvailablePermissions.
+
 func CreateEmployee(c *gin.Context) {
 	var input struct {
 		Name        string          `json:"name" binding:"required"`
@@ -23,68 +27,43 @@ func CreateEmployee(c *gin.Context) {
 		return
 	}
 
-	// Hash Password
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	outletID := c.GetUint("outlet_id")
+	permissionsJSON, _ := json.Marshal(model.LegacyPermissionsToKaryawan(input.Permissions))
+
+	karyawan := model.Karyawan{
+		OutletID:    &outletID,
+		Nama:        input.Name,
+		Phone:       input.Phone,
+		Email:       strings.ToLower(strings.TrimSpace(input.Email)),
+		Role:        "Karyawan",
+		Permissions: string(permissionsJSON),
+		Status:      "Aktif",
+		UserUpdate:  getStaffName(c),
+	}
+
+	if err := karyawan.HashPassword(input.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal mengenkripsi password"})
+		return
+	}
 
-	employee := model.Employee{
-		OutletID: outletID,
-		Name:     input.Name,
-		Phone:    input.Phone,
-		Email:    input.Email,
-		Password: string(hashedPassword),
-		Role:     "Pegawai", // Default role
-
-		// Mapping Permissions dari Map ke Struct
-		PermMakeOrder:         input.Permissions["make_order"],
-		PermCancelOrder:       input.Permissions["cancel_order"],
-		PermManageExpenses:    input.Permissions["manage_expenses"],
-		PermManageServices:    input.Permissions["manage_services"],
-		PermViewRevenue:       input.Permissions["view_revenue"],
-		PermManageEmployees:   input.Permissions["manage_employees"],
-		PermReportTransaction: input.Permissions["report_transaction"],
-		PermReportPerformance: input.Permissions["report_performance"],
-		PermReportFinance:     input.Permissions["report_finance"],
-		PermReportCustomer:    input.Permissions["report_customer"],
-	}
-
-	if err := database.DbCore.Create(&employee).Error; err != nil {
+	if err := database.DbCore.Create(&karyawan).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Email sudah terdaftar"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "data": employee})
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": karyawan.ToLegacyEmployee()})
 }
 
 func GetEmployees(c *gin.Context) {
-	outletID, _ := c.Get("outlet_id")
-	var employees []model.Employee
-
-	database.DbCore.Where("outlet_id = ?", outletID).Find(&employees)
-
-	// Ubah format untuk Flutter agar menyertakan map permissions
-	var response []gin.H
-	for _, e := range employees {
-		response = append(response, gin.H{
-			"id":         e.ID,
-			"name":       e.Name,
-			"phone":      e.Phone,
-			"email":      e.Email,
-			"role":       e.Role,
-			"created_at": e.CreatedAt,
-			"permissions": gin.H{
-				"make_order":         e.PermMakeOrder,
-				"cancel_order":       e.PermCancelOrder,
-				"manage_expenses":    e.PermManageExpenses,
-				"manage_services":    e.PermManageServices,
-				"view_revenue":       e.PermViewRevenue,
-				"manage_employees":   e.PermManageEmployees,
-				"report_transaction": e.PermReportTransaction,
-				"report_performance": e.PermReportPerformance,
-				"report_finance":     e.PermReportFinance,
-				"report_customer":    e.PermReportCustomer,
-			},
-		})
+	outletID := c.GetUint("outlet_id")
+	var karyawans []model.Karyawan
+
+	database.DbCore.Where("kar_outlet = ?", outletID).Order("kar_created ASC").Find(&karyawans)
+
+	// Format lama untuk Flutter: permissions berupa map boolean
+	response := make([]model.LegacyEmployeeResponse, 0, len(karyawans))
+	for i := range karyawans {
+		response = append(response, karyawans[i].ToLegacyEmployee())
 	}
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": response})
@@ -107,38 +86,41 @@ func UpdateEmployee(c *gin.Context) {
 		return
 	}
 
-	// 1. Cari data lama terlebih dahulu
-	var employee model.Employee
-	if err := database.DbCore.Where("id = ? AND outlet_id = ?", id, outletID).First(&employee).Error; err != nil {
+	var karyawan model.Karyawan
+	if err := database.DbCore.Where("kar_id = ? AND kar_outlet = ?", id, outletID).First(&karyawan).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Pegawai tidak ditemukan"})
 		return
 	}
 
-	// 2. Update field data pribadi
-	employee.Name = input.Name
-	employee.Phone = input.Phone
-	employee.Email = input.Email
+	if karyawan.Role == "Ownership" {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "Tidak dapat mengupdate akun ownership"})
+		return
+	}
+
+	if input.Name != "" {
+		karyawan.Nama = input.Name
+	}
+	if input.Phone != "" {
+		karyawan.Phone = input.Phone
+	}
+	if email := strings.ToLower(strings.TrimSpace(input.Email)); email != "" {
+		karyawan.Email = email
+	}
 
-	// 3. Logic Password: Hanya ganti jika password baru tidak kosong
+	// Password hanya diganti jika diisi
 	if input.Password != "" {
-		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
-		employee.Password = string(hashedPassword)
-	}
-
-	// 4. Update Mapping Permissions
-	employee.PermMakeOrder = input.Permissions["make_order"]
-	employee.PermCancelOrder = input.Permissions["cancel_order"]
-	employee.PermManageExpenses = input.Permissions["manage_expenses"]
-	employee.PermManageServices = input.Permissions["manage_services"]
-	employee.PermViewRevenue = input.Permissions["view_revenue"]
-	employee.PermManageEmployees = input.Permissions["manage_employees"]
-	employee.PermReportTransaction = input.Permissions["report_transaction"]
-	employee.PermReportPerformance = input.Permissions["report_performance"]
-	employee.PermReportFinance = input.Permissions["report_finance"]
-	employee.PermReportCustomer = input.Permissions["report_customer"]
-
-	// 5. Simpan perubahan (GORM Save akan mengupdate semua field termasuk bool false)
-	if err := database.DbCore.Save(&employee).Error; err != nil {
+		if err := karyawan.HashPassword(input.Password); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal mengenkripsi password"})
+			return
+		}
+	}
+
+	// Endpoint lama selalu mengirim map permission lengkap, jadi permission diganti seluruhnya
+	permissionsJSON, _ := json.Marshal(model.LegacyPermissionsToKaryawan(input.Permissions))
+	karyawan.Permissions = string(permissionsJSON)
+	karyawan.UserUpdate = getStaffName(c)
+
+	if err := database.DbCore.Save(&karyawan).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal update data"})
 		return
 	}
@@ -150,8 +132,8 @@ func DeleteEmployee(c *gin.Context) {
 	id := c.Param("id")
 	outletID := c.GetUint("outlet_id")
 
-	// Pastikan hanya menghapus pegawai milik outlet tersebut
-	result := database.DbCore.Where("id = ? AND outlet_id = ?", id, outletID).Delete(&model.Employee{})
+	// Pastikan hanya menghapus pegawai milik outlet tersebut, akun ownership tidak ikut terhapus
+	result := database.DbCore.Where("kar_id = ? AND kar_outlet = ? AND kar_role <> ?", id, outletID, "Ownership").Delete(&model.Karyawan{})
 
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal menghapus data"})
@@ -164,4 +146,4 @@ func DeleteEmployee(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Pegawai berhasil dihapus"})
-}
\ No newline at end of file
+}
@@ -16,15 +16,15 @@ func NewKaryawanController(service *service.KaryawanService) *KaryawanController
 	return &KaryawanController{Service: service}
 }
 
+// activeOutletID mengembalikan outlet aktif yang sudah divalidasi OutletScope,
+// agar karyawan tenant lain tidak pernah ikut terbaca
+func activeOutletID(ctx *gin.Context) *uint {
+    outletID := ctx.GetUint("outlet_id")
+    return &outletID
+}
+
 func (c *KaryawanController) GetAllKaryawan(ctx *gin.Context) {
-    var outletID *uint
-    if outletParam := ctx.Query("outlet_id"); outletParam != "" {
-        id, err := strconv.ParseUint(outletParam, 10, 32)
-        if err == nil {
-            oid := uint(id)
-            outletID = &oid
-        }
-    }
+    outletID := activeOutletID(ctx)
 
     karyawans, err := c.Service.GetAll(outletID)
     if err != nil {
@@ -49,14 +49,7 @@ func (c *KaryawanController) GetKaryawanByID(ctx *gin.Context) {
 		return
 	}
 
-	var outletID *uint
-	if outletParam := ctx.Query("outlet_id"); outletParam != ""{
-		oid, err := strconv.ParseUint(outletParam, 10, 32)
-        if err == nil {
-            o := uint(oid)
-            outletID = &o
-        }
-	}
+	outletID := activeOutletID(ctx)
 
 	  karyawan, err := c.Service.GetByID(uint(id), outletID)
     if err != nil {
@@ -81,14 +74,7 @@ func (c *KaryawanController) CreateKaryawan(ctx *gin.Context) {
         return
     }
 
-    var outletID *uint
-    if outletParam := ctx.Query("outlet_id"); outletParam != "" {
-        id, err := strconv.ParseUint(outletParam, 10, 32)
-        if err == nil {
-            oid := uint(id)
-            outletID = &oid
-        }
-    }
+    outletID := activeOutletID(ctx)
 
     userUpdate := "admin" 
     if user, exists := ctx.Get("user"); exists {
@@ -126,14 +112,7 @@ func (c *KaryawanController) UpdateKaryawan(ctx *gin.Context) {
         return
     }
 
-    var outletID *uint
-    if outletParam := ctx.Query("outlet_id"); outletParam != "" {
-        oid, err := strconv.ParseUint(outletParam, 10, 32)
-        if err == nil {
-            o := uint(oid)
-            outletID = &o
-        }
-    }
+    outletID := activeOutletID(ctx)
 
     userUpdate := "admin"
     if user, exists := ctx.Get("user"); exists {
@@ -164,14 +143,7 @@ func (c *KaryawanController) DeleteKaryawan(ctx *gin.Context) {
         return
     }
 
-    var outletID *uint
-    if outletParam := ctx.Query("outlet_id"); outletParam != "" {
-        oid, err := strconv.ParseUint(outletParam, 10, 32)
-        if err == nil {
-            o := uint(oid)
-            outletID = &o
-        }
-    }
+    outletID := activeOutletID(ctx)
 
     if err := c.Service.Delete(uint(id), outletID); err != nil {
         ctx.JSON(http.StatusBadRequest, gin.H{
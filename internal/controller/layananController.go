@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"BackendFramework/internal/middleware"
 	"BackendFramework/internal/model"
 	"BackendFramework/internal/service"
 	"encoding/json"
@@ -181,6 +182,20 @@ func (c *LayananController) CreateLayananWithProducts(ctx *gin.Context) {
 		return
 	}
 
+	// ln_outlet dikirim di body, jadi belum diperiksa OutletScope
+	allowed, err := middleware.CanAccessOutlet(ctx, input.OutletID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Gagal memeriksa akses outlet",
+		})
+		return
+	}
+	if !allowed {
+		middleware.RespondOutletForbidden(ctx)
+		return
+	}
+
 	// Parse products
 	jenisProdukJSON := ctx.PostForm("jenis_produk")
 	products, err := c.parseProducts(jenisProdukJSON)
@@ -409,7 +424,7 @@ func (c *LayananController) UpdateLayananWithProducts(ctx *gin.Context) {
 
 	fmt.Printf(" Calling service to update layanan ID: %d\n\n", layananID)
 
-	layanan, err := c.Service.UpdateLayananWithProducts(uint(layananID), input, userID, files)
+	layanan, err := c.Service.UpdateLayananWithProducts(uint(layananID), ctx.GetUint("outlet_id"), input, userID, files)
 	if err != nil {
 		fmt.Printf(" Update failed: %v\n", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{
@@ -443,7 +458,7 @@ func min(a, b int) int {
 // ========== QUERY ENDPOINTS ==========
 
 func (c *LayananController) GetAllLayanan(ctx *gin.Context) {
-	outletID, _ := strconv.Atoi(ctx.DefaultQuery("outlet_id", "0"))
+	outletID := ctx.GetUint("outlet_id")
 	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
 
@@ -454,7 +469,7 @@ func (c *LayananController) GetAllLayanan(ctx *gin.Context) {
 		limit = 10
 	}
 
-	layananList, total, err := c.Service.GetAllLayanan(uint(outletID), page, limit)
+	layananList, total, err := c.Service.GetAllLayanan(outletID, page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -489,7 +504,7 @@ func (c *LayananController) GetLayananByID(ctx *gin.Context) {
 		return
 	}
 
-	layanan, err := c.Service.GetLayananByID(uint(id))
+	layanan, err := c.Service.GetLayananByID(uint(id), ctx.GetUint("outlet_id"))
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "tidak ditemukan") {
@@ -546,7 +561,7 @@ func (c *LayananController) DeleteLayanan(ctx *gin.Context) {
 		return
 	}
 
-	err = c.Service.DeleteLayanan(uint(id))
+	err = c.Service.DeleteLayanan(uint(id), ctx.GetUint("outlet_id"))
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "tidak ditemukan") {
@@ -8,46 +8,70 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// Endpoint /master/parfums dan /master/discounts dipertahankan untuk aplikasi lama.
+// Datanya sekarang disimpan di Parfum (ac_parfum) dan Diskon (ac_diskon) dengan format respons lama.
+
 // --- PARFUM HANDLERS ---
 
 func GetParfums(c *gin.Context) {
 	outletID := c.GetUint("outlet_id")
-	var parfums []model.Parfume
-	database.DbCore.Where("outlet_id = ?", outletID).Find(&parfums)
-	c.JSON(http.StatusOK, gin.H{"success": true, "data": parfums})
+	var parfums []model.Parfum
+	database.DbCore.Where("prf_outlet = ?", outletID).Order("prf_created ASC").Find(&parfums)
+
+	response := make([]model.Parfume, 0, len(parfums))
+	for i := range parfums {
+		response = append(response, parfums[i].ToLegacy())
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": response})
 }
 
 func CreateParfum(c *gin.Context) {
-	var parfum model.Parfume
-	if err := c.ShouldBindJSON(&parfum); err != nil {
+	var input model.Parfume
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
 		return
 	}
-	parfum.OutletID = c.GetUint("outlet_id")
-	database.DbCore.Create(&parfum)
-	c.JSON(http.StatusOK, gin.H{"success": true, "data": parfum})
+
+	outletID := c.GetUint("outlet_id")
+	parfum := model.Parfum{
+		OutletID:   &outletID,
+		Parfum:     input.Name,
+		Keterangan: input.Description,
+		Status:     "Aktif",
+		UserUpdate: getStaffName(c),
+	}
+	if err := database.DbCore.Create(&parfum).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": parfum.ToLegacy()})
 }
 
-func UpdateParfum(c *gin.Context) { {
+func UpdateParfum(c *gin.Context) {
 	id := c.Param("id")
 	outletID := c.GetUint("outlet_id")
-	var parfum model.Parfume
+	var parfum model.Parfum
 
-	if err := database.DbCore.Where("id = ? AND outlet_id = ?", id, outletID).First(&parfum).Error; err != nil {
+	if err := database.DbCore.Where("prf_id = ? AND prf_outlet = ?", id, outletID).First(&parfum).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Data tidak ditemukan"})
 		return
 	}
 
-	c.ShouldBindJSON(&parfum)
+	// Bind ke bentuk lama yang sudah terisi agar field yang tidak dikirim tetap
+	legacy := parfum.ToLegacy()
+	c.ShouldBindJSON(&legacy)
+
+	parfum.Parfum = legacy.Name
+	parfum.Keterangan = legacy.Description
+	parfum.UserUpdate = getStaffName(c)
 	database.DbCore.Save(&parfum)
-	c.JSON(http.StatusOK, gin.H{"success": true, "data": parfum})
-}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": parfum.ToLegacy()})
 }
 
 func DeleteParfum(c *gin.Context) {
 	id := c.Param("id")
 	outletID := c.GetUint("outlet_id")
-	database.DbCore.Where("id = ? AND outlet_id = ?", id, outletID).Delete(&model.Parfume{})
+	database.DbCore.Where("prf_id = ? AND prf_outlet = ?", id, outletID).Delete(&model.Parfum{})
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Berhasil dihapus"})
 }
 
@@ -55,40 +79,67 @@ func DeleteParfum(c *gin.Context) {
 
 func GetDiscounts(c *gin.Context) {
 	outletID := c.GetUint("outlet_id")
-	var discounts []model.Discount
-	database.DbCore.Where("outlet_id = ?", outletID).Find(&discounts)
-	c.JSON(http.StatusOK, gin.H{"success": true, "data": discounts})
+	var diskons []model.Diskon
+	database.DbCore.Where("dis_outlet = ?", outletID).Order("dis_created ASC").Find(&diskons)
+
+	response := make([]model.Discount, 0, len(diskons))
+	for i := range diskons {
+		response = append(response, diskons[i].ToLegacy())
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": response})
 }
 
 func CreateDiscount(c *gin.Context) {
-	var discount model.Discount
-	if err := c.ShouldBindJSON(&discount); err != nil {
+	// is_active default true seperti kolom lama
+	input := model.Discount{IsActive: true}
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
 		return
 	}
-	discount.OutletID = c.GetUint("outlet_id")
-	database.DbCore.Create(&discount)
-	c.JSON(http.StatusOK, gin.H{"success": true, "data": discount})
+
+	outletID := c.GetUint("outlet_id")
+	diskon := model.Diskon{
+		OutletID:    &outletID,
+		Diskon:      input.Name,
+		Jenis:       model.NormalizeDiskonJenis(input.Type),
+		NilaiDiskon: input.Value,
+		Keterangan:  input.Description,
+		Status:      model.DiskonStatus(input.IsActive),
+		UserUpdate:  getStaffName(c),
+	}
+	if err := database.DbCore.Create(&diskon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": diskon.ToLegacy()})
 }
 
 func UpdateDiscount(c *gin.Context) {
 	id := c.Param("id")
 	outletID := c.GetUint("outlet_id")
-	var discount model.Discount
+	var diskon model.Diskon
 
-	if err := database.DbCore.Where("id = ? AND outlet_id = ?", id, outletID).First(&discount).Error; err != nil {
+	if err := database.DbCore.Where("dis_id = ? AND dis_outlet = ?", id, outletID).First(&diskon).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Data tidak ditemukan"})
 		return
 	}
 
-	c.ShouldBindJSON(&discount)
-	database.DbCore.Save(&discount) // Pakai Save agar bool false ikut terupdate
+	legacy := diskon.ToLegacy()
+	c.ShouldBindJSON(&legacy)
+
+	diskon.Diskon = legacy.Name
+	diskon.Jenis = model.NormalizeDiskonJenis(legacy.Type)
+	diskon.NilaiDiskon = legacy.Value
+	diskon.Keterangan = legacy.Description
+	diskon.Status = model.DiskonStatus(legacy.IsActive)
+	diskon.UserUpdate = getStaffName(c)
+	database.DbCore.Save(&diskon) // Pakai Save agar status Tidak Aktif ikut terupdate
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Berhasil diupdate"})
 }
 
 func DeleteDiscount(c *gin.Context) {
 	id := c.Param("id")
 	outletID := c.GetUint("outlet_id")
-	database.DbCore.Where("id = ? AND outlet_id = ?", id, outletID).Delete(&model.Discount{})
+	database.DbCore.Where("dis_id = ? AND dis_outlet = ?", id, outletID).Delete(&model.Diskon{})
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Berhasil dihapus"})
-}
\ No newline at end of file
+}
@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"BackendFramework/internal/model"
+	"BackendFramework/internal/thirdparty"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const notaTemplatePath = "./web/html/nota_receipt.html"
+
+// notaTemplateData adalah data yang dipakai web/html/nota_receipt.html
+type notaTemplateData struct {
+	Settings      *model.NotaSettings
+	Data          *model.NotaData
+	Logo          template.URL
+	QRCode        template.URL
+	TaxLabel      string
+	DiscountLabel string
+	IsVoid        bool
+}
+
+var notaTemplateFuncs = template.FuncMap{
+	"rupiah":        formatCurrency,
+	"qty":           formatFloat,
+	"paymentMethod": formatPaymentMethod,
+}
+
+// renderNotaHTML merender nota ke HTML mengikuti toggle NotaSettings yang sama dengan nota thermal
+func renderNotaHTML(format *model.NotaPrintFormat) (string, error) {
+	tmpl, err := template.New("nota_receipt.html").Funcs(notaTemplateFuncs).ParseFiles(notaTemplatePath)
+	if err != nil {
+		return "", err
+	}
+
+	data := format.Data
+	view := notaTemplateData{
+		Settings:      format.Settings,
+		Data:          data,
+		TaxLabel:      "Pajak",
+		DiscountLabel: "Diskon",
+		IsVoid:        data.Status == "void",
+	}
+
+	if data.TaxPercentage > 0 {
+		view.TaxLabel = fmt.Sprintf("Pajak (%.0f%%)", data.TaxPercentage)
+	}
+
+	if format.Settings.ShowLogo && format.LogoBase64 != "" {
+		view.Logo = template.URL(format.LogoBase64)
+	}
+
+	if format.Settings.ShowQRCode {
+		if png, err := thirdparty.GenerateQrPng(notaQRContent(data), 256); err == nil {
+			view.QRCode = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, view); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// renderNotaPDF merender nota ke PDF lewat thirdparty.GeneratePdf
+func renderNotaPDF(format *model.NotaPrintFormat) ([]byte, error) {
+	html, err := renderNotaHTML(format)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp("", "nota-*.pdf")
+	if err != nil {
+		return nil, err
+	}
+	outputPath := file.Name()
+	file.Close()
+	defer os.Remove(outputPath)
+
+	if !thirdparty.GeneratePdf(html, outputPath) {
+		return nil, errors.New("gagal membuat PDF nota")
+	}
+
+	return os.ReadFile(outputPath)
+}
+
+func notaPDFFileName(data *model.NotaData) string {
+	return "nota-" + sanitizeFileName(data.TransactionID) + ".pdf"
+}
+
+// sanitizeFileName mengganti karakter yang tidak aman di nama file (misal "/" pada nomor invoice)
+func sanitizeFileName(name string) string {
+	out := []rune(name)
+	for i, r := range out {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			out[i] = '-'
+		}
+	}
+	return string(out)
+}
+
+// DownloadNotaPDF mengunduh nota dalam bentuk PDF
+func (c *NotaController) DownloadNotaPDF(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.NotaSettingsErrorResponse{
+			Success: false,
+			Message: "Invalid nota ID",
+		})
+		return
+	}
+
+	if !c.notaInActiveOutlet(ctx, uint(id)) {
+		return
+	}
+
+	c.writeNotaPDF(ctx, uint(id))
+}
+
+// ShareNota mengembalikan link publik nota untuk dikirim ke pelanggan
+func (c *NotaController) ShareNota(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.NotaSettingsErrorResponse{
+			Success: false,
+			Message: "Invalid nota ID",
+		})
+		return
+	}
+
+	if !c.notaInActiveOutlet(ctx, uint(id)) {
+		return
+	}
+
+	shareURL, err := c.notaService.GetShareURL(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, model.NotaSettingsErrorResponse{
+				Success: false,
+				Message: "Nota not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, model.NotaSettingsErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Share link generated successfully",
+		"data": gin.H{
+			"html_url": shareURL,
+			"pdf_url":  shareURL + "/pdf",
+		},
+	})
+}
+
+// PublicNota menampilkan nota HTML lewat link publik tanpa login
+func (c *NotaController) PublicNota(ctx *gin.Context) {
+	notaData, err := c.notaService.GetNotaByPublicToken(ctx.Param("token"))
+	if err != nil {
+		ctx.String(http.StatusNotFound, "Nota tidak ditemukan")
+		return
+	}
+
+	format, err := c.notaService.PrepareNotaForView(notaData.ID)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Nota tidak dapat ditampilkan")
+		return
+	}
+
+	html, err := renderNotaHTML(format)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Nota tidak dapat ditampilkan")
+		return
+	}
+
+	ctx.Header("X-Robots-Tag", "noindex")
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+}
+
+// PublicNotaPDF mengunduh PDF nota lewat link publik tanpa login
+func (c *NotaController) PublicNotaPDF(ctx *gin.Context) {
+	notaData, err := c.notaService.GetNotaByPublicToken(ctx.Param("token"))
+	if err != nil {
+		ctx.String(http.StatusNotFound, "Nota tidak ditemukan")
+		return
+	}
+
+	c.writeNotaPDF(ctx, notaData.ID)
+}
+
+func (c *NotaController) writeNotaPDF(ctx *gin.Context, notaDataID uint) {
+	format, err := c.notaService.PrepareNotaForView(notaDataID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, model.NotaSettingsErrorResponse{
+				Success: false,
+				Message: "Nota not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, model.NotaSettingsErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	pdf, err := renderNotaPDF(format)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.NotaSettingsErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="`+notaPDFFileName(format.Data)+`"`)
+	ctx.Data(http.StatusOK, "application/pdf", pdf)
+}
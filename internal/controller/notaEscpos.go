@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"BackendFramework/internal/model"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// Perintah ESC/POS yang dipakai untuk printer thermal 58mm/80mm
+var (
+	escposInit        = []byte{0x1B, 0x40}
+	escposAlignLeft   = []byte{0x1B, 0x61, 0x00}
+	escposAlignCenter = []byte{0x1B, 0x61, 0x01}
+	escposBoldOn      = []byte{0x1B, 0x45, 0x01}
+	escposBoldOff     = []byte{0x1B, 0x45, 0x00}
+	escposSizeNormal  = []byte{0x1D, 0x21, 0x00}
+	escposSizeTall    = []byte{0x1D, 0x21, 0x01} // Double height
+	escposFeedAndCut  = []byte{0x1D, 0x56, 0x42, 0x03}
+)
+
+// escposWriter menyusun byte ESC/POS baris demi baris
+type escposWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *escposWriter) cmd(command []byte) {
+	w.buf.Write(command)
+}
+
+// line menulis teks diikuti LF. Karakter non-ASCII diganti "?" karena code page printer tidak mendukung UTF-8.
+func (w *escposWriter) line(text string) {
+	for _, r := range text {
+		if r == '\n' || (r >= 0x20 && r < 0x7F) {
+			w.buf.WriteRune(r)
+		} else {
+			w.buf.WriteByte('?')
+		}
+	}
+	w.buf.WriteByte('\n')
+}
+
+// lines menulis teks multi-baris seperti FormattedTotal
+func (w *escposWriter) lines(text string) {
+	for _, l := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		w.line(l)
+	}
+}
+
+// qrCode mencetak QR native printer (GS ( k, model 2, error correction M)
+func (w *escposWriter) qrCode(content string, moduleSize byte) {
+	data := []byte(content)
+	storeLen := len(data) + 3
+
+	w.cmd([]byte{0x1D, 0x28, 0x6B, 0x04, 0x00, 0x31, 0x41, 0x32, 0x00})
+	w.cmd([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x43, moduleSize})
+	w.cmd([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x45, 0x31})
+	w.cmd([]byte{0x1D, 0x28, 0x6B, byte(storeLen % 256), byte(storeLen / 256), 0x31, 0x50, 0x30})
+	w.buf.Write(data)
+	w.cmd([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x51, 0x30})
+	w.buf.WriteByte('\n')
+}
+
+// rasterImage mencetak gambar 1-bit dengan GS v 0, lebar dibatasi maxDots
+func (w *escposWriter) rasterImage(img image.Image, maxDots int) {
+	if img.Bounds().Dx() > maxDots {
+		img = imaging.Resize(img, maxDots, 0, imaging.Lanczos)
+	}
+	gray := imaging.Grayscale(img)
+
+	width := gray.Bounds().Dx()
+	height := gray.Bounds().Dy()
+	bytesPerRow := (width + 7) / 8
+
+	w.cmd([]byte{0x1D, 0x76, 0x30, 0x00,
+		byte(bytesPerRow % 256), byte(bytesPerRow / 256),
+		byte(height % 256), byte(height / 256)})
+
+	for y := 0; y < height; y++ {
+		row := make([]byte, bytesPerRow)
+		for x := 0; x < width; x++ {
+			c := color.NRGBAModel.Convert(gray.At(x, y)).(color.NRGBA)
+			// Piksel transparan dianggap putih
+			if c.A >= 128 && c.R < 128 {
+				row[x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+		w.buf.Write(row)
+	}
+	w.buf.WriteByte('\n')
+}
+
+// printerDots mengembalikan lebar area cetak dalam dot (203 dpi)
+func printerDots(printerSize int) int {
+	if printerSize == 80 {
+		return 576
+	}
+	return 384
+}
+
+// decodeLogo membaca logo dari data URL atau base64 mentah
+func decodeLogo(logo string) (image.Image, error) {
+	if idx := strings.Index(logo, ","); strings.HasPrefix(logo, "data:") && idx >= 0 {
+		logo = logo[idx+1:]
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(logo)
+	if err != nil {
+		return nil, err
+	}
+
+	return imaging.Decode(bytes.NewReader(raw))
+}
+
+// notaQRContent mengambil isi QR: link tracking pesanan untuk nota dari transaksi. QRCodeData berupa gambar
+// (data URL) tidak bisa dipakai printer, jadi fallback ke nomor transaksi
+func notaQRContent(data *model.NotaData) string {
+	if data.QRCodeData != "" && !strings.HasPrefix(data.QRCodeData, "data:") {
+		return data.QRCodeData
+	}
+	return data.TransactionID
+}
+
+// generateEscposData menyusun nota yang sama dengan generatePrintData dalam bentuk perintah ESC/POS
+func (c *NotaController) generateEscposData(format *model.NotaPrintFormat) []byte {
+	settings := format.Settings
+	data := format.Data
+	w := &escposWriter{}
+
+	w.cmd(escposInit)
+	w.cmd(escposAlignCenter)
+
+	if settings.ShowLogo && format.LogoBase64 != "" {
+		if logo, err := decodeLogo(format.LogoBase64); err == nil {
+			w.rasterImage(logo, printerDots(settings.PrinterSize))
+		}
+	}
+
+	if settings.ShowBusinessName && settings.BusinessName != "" {
+		w.cmd(escposBoldOn)
+		w.cmd(escposSizeTall)
+		w.line(settings.BusinessName)
+		w.cmd(escposSizeNormal)
+		w.cmd(escposBoldOff)
+	}
+
+	if settings.Address != "" {
+		w.line(settings.Address)
+	}
+
+	if settings.Phone != "" {
+		w.line(settings.Phone)
+	}
+
+	w.cmd(escposAlignLeft)
+	w.line(repeatChar("=", format.PrintWidth))
+	w.line(fmt.Sprintf("No: %s", data.TransactionID))
+	w.line(fmt.Sprintf("Date: %s", data.TransactionDate.Format("02/01/2006 15:04")))
+	w.line(fmt.Sprintf("Cashier: %s", data.CashierName))
+
+	if data.CustomerName != "" {
+		w.line(fmt.Sprintf("Customer: %s", data.CustomerName))
+	}
+
+	w.line(repeatChar("-", format.PrintWidth))
+
+	for _, item := range format.FormattedItems {
+		w.line(item)
+	}
+
+	w.cmd(escposBoldOn)
+	w.lines(format.FormattedTotal)
+	w.cmd(escposBoldOff)
+	w.line(fmt.Sprintf("Payment: %s", data.PaymentMethod))
+
+	w.line(repeatChar("=", format.PrintWidth))
+
+	w.cmd(escposAlignCenter)
+
+	if settings.ShowFooterNote && settings.FooterNote != "" {
+		w.line(settings.FooterNote)
+	}
+
+	if settings.ShowQRCode {
+		moduleSize := byte(5)
+		if settings.PrinterSize == 80 {
+			moduleSize = 7
+		}
+		w.qrCode(notaQRContent(data), moduleSize)
+	}
+
+	if settings.ShowWhatsappFooter && settings.WhatsappNote != "" {
+		w.line(settings.WhatsappNote)
+	}
+
+	w.line("Thank You!")
+	w.cmd(escposFeedAndCut)
+
+	return w.buf.Bytes()
+}
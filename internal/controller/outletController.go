@@ -2,6 +2,7 @@ package controller
 
 import (
 	"BackendFramework/internal/database"
+	"BackendFramework/internal/middleware"
 	"BackendFramework/internal/model"
 	"BackendFramework/internal/service"
 	"fmt"
@@ -215,7 +216,8 @@ func GetAllOutletsController(c *gin.Context) {
 		limit = 10
 	}
 
-	outlets, total, err := service.GetAllOutlets(page, limit)
+	// Hanya outlet milik owner yang login; outlet tenant lain tidak pernah ikut terdaftar
+	outlets, total, err := service.GetAllOutlets(c.GetUint("user_id"), page, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -249,6 +251,19 @@ func GetOutletByIDController(c *gin.Context) {
 		return
 	}
 
+	allowed, err := middleware.CanAccessOutlet(c, uint(outletID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Gagal memeriksa akses outlet",
+		})
+		return
+	}
+	if !allowed {
+		middleware.RespondOutletForbidden(c)
+		return
+	}
+
 	outlet, err := service.GetOutletByID(uint(outletID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -0,0 +1,353 @@
+package controller
+
+import (
+    "net/http"
+    "strconv"
+    "BackendFramework/internal/middleware"
+    "BackendFramework/internal/model"
+    "BackendFramework/internal/service"
+    "github.com/gin-gonic/gin"
+)
+
+type PengeluaranController struct {
+    service *service.PengeluaranService
+}
+
+func NewPengeluaranController(service *service.PengeluaranService) *PengeluaranController {
+    return &PengeluaranController{
+        service: service,
+    }
+}
+
+// outletIDFromContext mengambil outlet_id yang di-set middleware auth
+func outletIDFromContext(ctx *gin.Context) *uint {
+    outletID, exists := ctx.Get("outlet_id")
+    if exists && outletID != nil {
+        if id, ok := outletID.(uint); ok {
+            return &id
+        }
+    }
+    return nil
+}
+
+// GetAll godoc
+// @Summary Get all pengeluaran
+// @Description Get all pengeluaran, filter by date range (start_date, end_date), kategori_id and status
+// @Tags Pengeluaran
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string false "YYYY-MM-DD"
+// @Param end_date query string false "YYYY-MM-DD"
+// @Param kategori_id query int false "Kategori ID"
+// @Param status query string false "Aktif / Tidak Aktif"
+// @Success 200 {object} map[string]interface{}
+// @Router /pengeluaran [get]
+func (c *PengeluaranController) GetAll(ctx *gin.Context) {
+    var filter model.PengeluaranFilter
+    if err := ctx.ShouldBindQuery(&filter); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "Filter tidak valid",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    pengeluarans, err := c.service.GetAll(outletIDFromContext(ctx), filter)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "Gagal mengambil data pengeluaran",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    responses := make([]model.PengeluaranResponse, 0, len(pengeluarans))
+    for i := range pengeluarans {
+        responses = append(responses, pengeluarans[i].ToResponse())
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{
+        "status":  "success",
+        "message": "Data pengeluaran berhasil diambil",
+        "data":    responses,
+    })
+}
+
+// GetByID godoc
+// @Summary Get pengeluaran by ID
+// @Description Get pengeluaran by ID
+// @Tags Pengeluaran
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Pengeluaran ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /pengeluaran/{id} [get]
+func (c *PengeluaranController) GetByID(ctx *gin.Context) {
+    id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "ID tidak valid",
+        })
+        return
+    }
+
+    pengeluaran, err := c.service.GetByID(uint(id), outletIDFromContext(ctx))
+    if err != nil {
+        ctx.JSON(http.StatusNotFound, gin.H{
+            "status":  "error",
+            "message": err.Error(),
+        })
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{
+        "status":  "success",
+        "message": "Data pengeluaran berhasil diambil",
+        "data":    pengeluaran.ToDetailResponse(),
+    })
+}
+
+// Create godoc
+// @Summary Create new pengeluaran
+// @Description Create new pengeluaran
+// @Tags Pengeluaran
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.PengeluaranInput true "Pengeluaran Input"
+// @Success 201 {object} map[string]interface{}
+// @Router /pengeluaran [post]
+func (c *PengeluaranController) Create(ctx *gin.Context) {
+    var input model.PengeluaranInput
+    if err := ctx.ShouldBindJSON(&input); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "Input tidak valid",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    username, _ := ctx.Get("username")
+    usernameStr, _ := username.(string)
+
+    pengeluaran, err := c.service.Create(input, outletIDFromContext(ctx), usernameStr)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "Gagal membuat pengeluaran",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    ctx.JSON(http.StatusCreated, gin.H{
+        "status":  "success",
+        "message": "Pengeluaran berhasil dibuat",
+        "data":    pengeluaran.ToResponse(),
+    })
+}
+
+// Update godoc
+// @Summary Update pengeluaran
+// @Description Update pengeluaran
+// @Tags Pengeluaran
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Pengeluaran ID"
+// @Param body body model.UpdatePengeluaranInput true "Update Input"
+// @Success 200 {object} map[string]interface{}
+// @Router /pengeluaran/{id} [put]
+func (c *PengeluaranController) Update(ctx *gin.Context) {
+    id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "ID tidak valid",
+        })
+        return
+    }
+
+    var input model.UpdatePengeluaranInput
+    if err := ctx.ShouldBindJSON(&input); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "Input tidak valid",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    username, _ := ctx.Get("username")
+    usernameStr, _ := username.(string)
+
+    pengeluaran, err := c.service.Update(uint(id), input, outletIDFromContext(ctx), usernameStr)
+    if err != nil {
+        if err.Error() == "pengeluaran tidak ditemukan" {
+            ctx.JSON(http.StatusNotFound, gin.H{
+                "status":  "error",
+                "message": err.Error(),
+            })
+            return
+        }
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "Gagal mengupdate pengeluaran",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{
+        "status":  "success",
+        "message": "Pengeluaran berhasil diupdate",
+        "data":    pengeluaran.ToResponse(),
+    })
+}
+
+// Delete godoc
+// @Summary Delete pengeluaran
+// @Description Delete pengeluaran
+// @Tags Pengeluaran
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Pengeluaran ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /pengeluaran/{id} [delete]
+func (c *PengeluaranController) Delete(ctx *gin.Context) {
+    id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "ID tidak valid",
+        })
+        return
+    }
+
+    if err := c.service.Delete(uint(id), outletIDFromContext(ctx)); err != nil {
+        if err.Error() == "pengeluaran tidak ditemukan" {
+            ctx.JSON(http.StatusNotFound, gin.H{
+                "status":  "error",
+                "message": err.Error(),
+            })
+            return
+        }
+        ctx.JSON(http.StatusInternalServerError, gin.H{
+            "status":  "error",
+            "message": "Gagal menghapus pengeluaran",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{
+        "status":  "success",
+        "message": "Pengeluaran berhasil dihapus",
+    })
+}
+
+// GetSummary godoc
+// @Summary Get ringkasan pengeluaran
+// @Description Total pengeluaran aktif, jumlah transaksi dan rincian per kategori dalam rentang tanggal. Token outlet_scope all tanpa X-Outlet-ID menggabungkan semua outlet owner
+// @Tags Pengeluaran
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string false "YYYY-MM-DD"
+// @Param end_date query string false "YYYY-MM-DD"
+// @Param kategori_id query int false "Kategori ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /pengeluaran/summary [get]
+func (c *PengeluaranController) GetSummary(ctx *gin.Context) {
+    var filter model.PengeluaranFilter
+    if err := ctx.ShouldBindQuery(&filter); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "Filter tidak valid",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    outletIDs, err := middleware.OutletIDsInScope(ctx)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{
+            "status":  "error",
+            "message": "Gagal memeriksa outlet",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    summary, err := c.service.GetSummary(outletIDs, filter)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "Gagal mengambil ringkasan pengeluaran",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{
+        "status":  "success",
+        "message": "Ringkasan pengeluaran berhasil diambil",
+        "data":    summary,
+    })
+}
+
+// GetPerKategori godoc
+// @Summary Get pengeluaran per kategori
+// @Description Total dan jumlah pengeluaran aktif per kategori dalam rentang tanggal. Token outlet_scope all tanpa X-Outlet-ID menggabungkan semua outlet owner
+// @Tags Pengeluaran
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string false "YYYY-MM-DD"
+// @Param end_date query string false "YYYY-MM-DD"
+// @Success 200 {object} map[string]interface{}
+// @Router /pengeluaran/summary/kategori [get]
+func (c *PengeluaranController) GetPerKategori(ctx *gin.Context) {
+    var filter model.PengeluaranFilter
+    if err := ctx.ShouldBindQuery(&filter); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "Filter tidak valid",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    outletIDs, err := middleware.OutletIDsInScope(ctx)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{
+            "status":  "error",
+            "message": "Gagal memeriksa outlet",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    perKategori, err := c.service.GetPerKategori(outletIDs, filter)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{
+            "status":  "error",
+            "message": "Gagal mengambil pengeluaran per kategori",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{
+        "status":  "success",
+        "message": "Pengeluaran per kategori berhasil diambil",
+        "data":    perKategori,
+    })
+}
@@ -3,6 +3,7 @@ package controller
 import (
     "BackendFramework/internal/model"
     "BackendFramework/internal/service"
+    "errors"
     "net/http"
     "strconv"
     "github.com/gin-gonic/gin"
@@ -99,6 +100,14 @@ func (c *NotaSettingsController) CreateOrUpdate(ctx *gin.Context) {
             })
             return
         }
+
+        if errors.Is(err, service.ErrInvalidInvoiceFormat) || errors.Is(err, service.ErrInvoiceFormatPeriod) {
+            ctx.JSON(http.StatusBadRequest, model.NotaSettingsErrorResponse{
+                Success: false,
+                Message: err.Error(),
+            })
+            return
+        }
         
         ctx.JSON(http.StatusInternalServerError, model.NotaSettingsErrorResponse{
             Success: false,
@@ -2,27 +2,20 @@ package controller
 
 import (
 	"BackendFramework/internal/database"
+	"BackendFramework/internal/middleware"
 	"BackendFramework/internal/model"
-	"fmt"
+	"BackendFramework/internal/service"
+	"bytes"
+	"errors"
+	"html/template"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 func CreateTransaction(c *gin.Context) {
-	var input struct {
-		CustomerID uint    `json:"customer_id" binding:"required"`
-		ParfumID   uint    `json:"parfum_id"`
-		DiscountID *uint   `json:"discount_id"`
-		TotalPrice float64 `json:"total_price" binding:"required"`
-		Notes      string  `json:"notes"`
-		Items      []struct {
-			ServiceName string  `json:"service_name"`
-			Price       float64 `json:"price"`
-			Qty         float64 `json:"qty"`
-		} `json:"items" binding:"required"`
-	}
+	var input model.CreateTransactionInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
@@ -30,54 +23,34 @@ func CreateTransaction(c *gin.Context) {
 	}
 
 	outletID := c.GetUint("outlet_id")
-	adminName := "Admin" // Nanti ambil dari JWT payload
-
-	// Generate Nomor Invoice Sederhana: TRX-WaktuUnix
-	invoice := fmt.Sprintf("TRX/%d/%d", outletID, time.Now().Unix())
-
-	tx := database.DbCore.Begin()
-
-	// 1. Simpan Header
-	transaction := model.Transaction{
-		InvoiceNumber: invoice,
-		OutletID:      outletID,
-		CustomerID:    input.CustomerID,
-		ParfumID:      input.ParfumID,
-		DiscountID:    input.DiscountID,
-		TotalPrice:    input.TotalPrice,
-		Notes:         input.Notes,
-	}
-
-	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal buat transaksi"})
-		return
-	}
+	trxService := service.NewTransactionService(database.DbCore)
 
-	// 2. Simpan Items
-	for _, item := range input.Items {
-		detail := model.TransactionDetail{
-			TransactionID: transaction.ID,
-			ServiceName:   item.ServiceName,
-			Price:         item.Price,
-			Qty:           item.Qty,
-			Subtotal:      item.Price * item.Qty,
+	// Harga, diskon dan total dihitung ulang di server dari master JenisProduk & Diskon
+	transaction, err := trxService.CreateTransaction(input, outletID, getStaffName(c))
+	if err != nil {
+		if errors.Is(err, service.ErrTotalMismatch) {
+			c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+			return
 		}
-		tx.Create(&detail)
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
 	}
 
-	// 3. Simpan Log Awal (Antrian)
-	log := model.OrderLog{
-		TransactionID: transaction.ID,
-		Status:        "Antrian",
-		AdminName:     adminName,
-	}
-	tx.Create(&log)
+	notifyCustomerAsync(transaction.ID, model.WhatsappEventOrderCreated, getStaffName(c))
 
-	tx.Commit()
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": transaction})
 }
 
+// notifyCustomerAsync mengirim notifikasi WhatsApp di background agar response API tidak menunggu Infobip
+func notifyCustomerAsync(transactionID uint, event string, staffName string) {
+	go func() {
+		notifier := service.NewWhatsappNotificationService(database.DbCore)
+		if _, err := notifier.NotifyTransaction(transactionID, event, staffName); err != nil {
+			middleware.LogError(err, "Failed to send WhatsApp notification")
+		}
+	}()
+}
+
 func GetTransactions(c *gin.Context) {
 	outletID := c.GetUint("outlet_id")
 	var transactions []model.Transaction
@@ -90,4 +63,230 @@ func GetTransactions(c *gin.Context) {
 		Find(&transactions)
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": transactions})
-}
\ No newline at end of file
+}
+
+// getStaffName mengambil nama staf dari payload JWT (di-set oleh JWTAuthMiddleware)
+func getStaffName(c *gin.Context) string {
+	if username, ok := c.Get("username"); ok {
+		if name, ok := username.(string); ok && name != "" {
+			return name
+		}
+	}
+	return "Admin"
+}
+
+// respondTransactionError memetakan error TransactionService: 404 untuk pesanan yang tidak ada,
+// 422 untuk aturan pesanan yang dilanggar, selain itu 500
+func respondTransactionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTransactionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": err.Error()})
+	case errors.Is(err, service.ErrTransactionCancelled),
+		errors.Is(err, service.ErrTransactionFinished),
+		errors.Is(err, service.ErrInvalidStatusTransition),
+		errors.Is(err, service.ErrPaymentMethodNotFound),
+		errors.Is(err, service.ErrPaymentMethodInactive),
+		errors.Is(err, service.ErrTransactionPaid),
+		errors.Is(err, service.ErrPaymentExceedsOutstanding),
+		errors.Is(err, service.ErrNotaVoided):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"success": false, "message": err.Error()})
+	default:
+		middleware.LogError(err, "Gagal memproses transaksi")
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Gagal memproses transaksi"})
+	}
+}
+
+// UpdateStatus memindahkan pesanan ke tahap berikutnya: Antrian -> Proses -> Siap Ambil -> Selesai
+func UpdateStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ID transaksi tidak valid"})
+		return
+	}
+
+	var input model.UpdateOrderStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	outletID := c.GetUint("outlet_id")
+	trxService := service.NewTransactionService(database.DbCore)
+
+	transaction, err := trxService.UpdateOrderStatus(uint(id), outletID, input, getStaffName(c))
+	if err != nil {
+		respondTransactionError(c, err)
+		return
+	}
+
+	if transaction.OrderStatus == model.OrderStatusSiapAmbil {
+		notifyCustomerAsync(transaction.ID, model.WhatsappEventOrderReady, getStaffName(c))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": transaction})
+}
+
+// AddPayment mencatat DP, cicilan atau pelunasan transaksi
+func AddPayment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ID transaksi tidak valid"})
+		return
+	}
+
+	var input model.AddPaymentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	outletID := c.GetUint("outlet_id")
+	trxService := service.NewTransactionService(database.DbCore)
+
+	transaction, err := trxService.AddPayment(uint(id), outletID, input, getStaffName(c))
+	if err != nil {
+		respondTransactionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": transaction})
+}
+
+// GetPayments menampilkan riwayat pembayaran satu transaksi
+func GetPayments(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ID transaksi tidak valid"})
+		return
+	}
+
+	outletID := c.GetUint("outlet_id")
+	trxService := service.NewTransactionService(database.DbCore)
+
+	payments, err := trxService.GetPayments(uint(id), outletID)
+	if err != nil {
+		respondTransactionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": payments})
+}
+
+// GetReceivables menampilkan daftar piutang (Belum Bayar / DP) milik outlet
+func GetReceivables(c *gin.Context) {
+	outletIDs, err := middleware.OutletIDsInScope(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	trxService := service.NewTransactionService(database.DbCore)
+
+	summary, err := trxService.GetReceivables(outletIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": summary})
+}
+
+// GenerateTransactionNota membuat (atau memperbarui) nota langsung dari data pesanan
+func GenerateTransactionNota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ID transaksi tidak valid"})
+		return
+	}
+
+	outletID := c.GetUint("outlet_id")
+	notaService := service.NewNotaService(database.DbCore)
+
+	nota, err := notaService.GenerateNotaFromTransaction(uint(id), outletID, getStaffName(c))
+	if err != nil {
+		respondTransactionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": nota})
+}
+
+// VoidTransaction membatalkan pesanan, nota yang terhubung ikut di-void
+func VoidTransaction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ID transaksi tidak valid"})
+		return
+	}
+
+	var input model.VoidTransactionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	outletID := c.GetUint("outlet_id")
+	trxService := service.NewTransactionService(database.DbCore)
+
+	transaction, err := trxService.VoidTransaction(uint(id), outletID, input.Reason, getStaffName(c))
+	if err != nil {
+		respondTransactionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": transaction})
+}
+
+const trackingTemplatePath = "./web/html/order_tracking.html"
+
+// renderTrackingHTML merender halaman status pesanan untuk pelanggan yang memindai QR nota
+func renderTrackingHTML(tracking *model.TransactionTracking) ([]byte, error) {
+	tmpl, err := template.New("order_tracking.html").Funcs(notaTemplateFuncs).ParseFiles(trackingTemplatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, tracking); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// TrackTransaction menampilkan status pesanan lewat link tracking publik (QR di nota) tanpa login.
+// Browser (scan QR) mendapat halaman HTML; client yang meminta Accept: application/json mendapat JSON.
+func TrackTransaction(c *gin.Context) {
+	asHTML := c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEHTML
+
+	c.Header("X-Robots-Tag", "noindex")
+	c.Header("Cache-Control", "no-store")
+
+	tracking, err := service.NewTransactionService(database.DbCore).GetTracking(c.Param("token"))
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Gagal mengambil status pesanan"
+		if errors.Is(err, service.ErrTrackingNotFound) {
+			status, message = http.StatusNotFound, err.Error()
+		} else {
+			middleware.LogError(err, "Gagal mengambil tracking pesanan")
+		}
+
+		if asHTML {
+			c.String(status, message)
+			return
+		}
+		c.JSON(status, gin.H{"success": false, "message": message})
+		return
+	}
+
+	if !asHTML {
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": tracking})
+		return
+	}
+
+	html, err := renderTrackingHTML(tracking)
+	if err != nil {
+		middleware.LogError(err, "Gagal merender halaman tracking pesanan")
+		c.String(http.StatusInternalServerError, "Status pesanan tidak dapat ditampilkan")
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", html)
+}
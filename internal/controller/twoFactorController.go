@@ -0,0 +1,345 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"BackendFramework/internal/database"
+	"BackendFramework/internal/middleware"
+	"BackendFramework/internal/model"
+	"BackendFramework/internal/service"
+)
+
+// twoFactorChallenge mengembalikan challenge token jika owner mengaktifkan 2FA, string kosong jika tidak
+func twoFactorChallenge(user *model.User, loginMethod, rememberMe, deviceID string) (string, error) {
+	enabled, err := service.NewTwoFactorService(database.DbCore).IsEnabled(user.ID)
+	if err != nil || !enabled {
+		return "", err
+	}
+	return middleware.GenerateTwoFactorChallengeToken(fmt.Sprintf("%d", user.ID), loginMethod, rememberMe, deviceID)
+}
+
+// requireTwoFactor mengirim challenge_token untuk langkah kedua login; true berarti respons sudah dikirim
+func requireTwoFactor(c *gin.Context, user *model.User, loginMethod, rememberMe, deviceID string) bool {
+	challenge, err := twoFactorChallenge(user, loginMethod, rememberMe, deviceID)
+	if err != nil {
+		middleware.LogError(err, "Gagal memeriksa 2FA")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":  http.StatusInternalServerError,
+			"error": "Gagal memeriksa status 2FA",
+		})
+		return true
+	}
+	if challenge == "" {
+		return false
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":                http.StatusOK,
+		"message":             "Masukkan kode dari aplikasi authenticator",
+		"two_factor_required": true,
+		"data": gin.H{
+			"challenge_token": challenge,
+			"expires_in":      int(middleware.TwoFactorChallengeExpiry.Seconds()),
+		},
+	})
+	return true
+}
+
+// respondIfLocked mengirim 429 jika email sedang terkunci oleh LoginLimiterService; true berarti respons sudah dikirim
+func respondIfLocked(c *gin.Context, limiter *service.LoginLimiterService, email string) bool {
+	canAttempt, remainingLock, _, err := limiter.CanAttemptLogin(email)
+	if err != nil {
+		middleware.LogError(err, "Gagal cek rate limiting")
+	}
+	if canAttempt {
+		return false
+	}
+
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"code":              http.StatusTooManyRequests,
+		"error":             "Terlalu banyak percobaan login",
+		"message":           service.FormatLockMessage(remainingLock),
+		"locked_until":      time.Now().Add(remainingLock).Unix(),
+		"remaining_seconds": int(remainingLock.Seconds()),
+	})
+	return true
+}
+
+// LoginTwoFactor adalah langkah kedua login owner: challenge_token dari /auth/login ditukar dengan token
+// setelah kode TOTP atau recovery code benar. Kode salah dihitung ke lockout login email yang sama.
+func LoginTwoFactor(c *gin.Context) {
+	var input model.TwoFactorLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":  http.StatusBadRequest,
+			"error": "Data request tidak valid",
+		})
+		return
+	}
+
+	code := strings.TrimSpace(input.Code)
+	if code == "" {
+		code = strings.TrimSpace(input.RecoveryCode)
+	}
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":  http.StatusBadRequest,
+			"error": "Kode 2FA atau recovery code wajib diisi",
+		})
+		return
+	}
+
+	claims, err := middleware.ValidateTwoFactorChallengeToken(input.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":  http.StatusUnauthorized,
+			"error": "Sesi verifikasi 2FA tidak valid atau kedaluwarsa, silakan login ulang",
+		})
+		return
+	}
+
+	var user model.User
+	if err := database.DbCore.Where("id = ?", claims.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":  http.StatusUnauthorized,
+			"error": "Sesi verifikasi 2FA tidak valid atau kedaluwarsa, silakan login ulang",
+		})
+		return
+	}
+
+	if user.IsAktif != "active" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":  http.StatusForbidden,
+			"error": "Akun tidak aktif",
+		})
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(user.Email))
+	clientIP := c.ClientIP()
+	limiter := service.NewLoginLimiterService()
+	if respondIfLocked(c, limiter, email) {
+		return
+	}
+
+	err = service.NewTwoFactorService(database.DbCore).Verify(user.ID, code)
+	switch {
+	case errors.Is(err, service.ErrTwoFactorInvalidCode):
+		respondAttemptFailed(c, limiter, email, clientIP, "Kode 2FA salah")
+		return
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		// 2FA dimatikan di antara dua langkah login; password sudah terverifikasi di langkah pertama
+	case err != nil:
+		middleware.LogError(err, "Gagal verifikasi 2FA")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":  http.StatusInternalServerError,
+			"error": "Gagal verifikasi kode 2FA",
+		})
+		return
+	}
+
+	completeOwnerLogin(c, &user, limiter, email, clientIP, claims.LoginMethod, claims.RememberMe, claims.DeviceID)
+}
+
+// twoFactorOwner memuat owner yang sedang login; 2FA hanya untuk akun owner, bukan karyawan
+func twoFactorOwner(c *gin.Context) (*model.User, bool) {
+	if _, isKaryawan := c.Get("karyawan_id"); isKaryawan {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":  http.StatusForbidden,
+			"error": "2FA hanya tersedia untuk akun owner",
+		})
+		return nil, false
+	}
+
+	var user model.User
+	if err := database.DbCore.Where("id = ?", c.GetUint("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":  http.StatusNotFound,
+			"error": "User tidak ditemukan",
+		})
+		return nil, false
+	}
+	return &user, true
+}
+
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrTwoFactorInvalidCode),
+		errors.Is(err, service.ErrTwoFactorNotSetup),
+		errors.Is(err, service.ErrTwoFactorNotEnabled):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrTwoFactorAlreadyActive):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func GetTwoFactorStatus(c *gin.Context) {
+	user, ok := twoFactorOwner(c)
+	if !ok {
+		return
+	}
+
+	status, err := service.NewTwoFactorService(database.DbCore).GetStatus(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":  http.StatusInternalServerError,
+			"error": "Gagal mengambil status 2FA",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"data": status,
+	})
+}
+
+// SetupTwoFactor membuat secret dan QR enrollment; 2FA belum aktif sampai EnableTwoFactor berhasil
+func SetupTwoFactor(c *gin.Context) {
+	user, ok := twoFactorOwner(c)
+	if !ok {
+		return
+	}
+
+	setup, err := service.NewTwoFactorService(database.DbCore).Setup(user)
+	if err != nil {
+		middleware.LogError(err, "Gagal setup 2FA")
+		c.JSON(twoFactorErrorStatus(err), gin.H{
+			"code":  twoFactorErrorStatus(err),
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "Pindai QR code dengan aplikasi authenticator lalu kirim kode untuk mengaktifkan 2FA",
+		"data":    setup,
+	})
+}
+
+// EnableTwoFactor mengaktifkan 2FA dan mengembalikan recovery code yang hanya ditampilkan sekali
+func EnableTwoFactor(c *gin.Context) {
+	user, ok := twoFactorOwner(c)
+	if !ok {
+		return
+	}
+
+	var input model.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":  http.StatusBadRequest,
+			"error": "Kode 2FA wajib diisi",
+		})
+		return
+	}
+
+	codes, err := service.NewTwoFactorService(database.DbCore).Enable(user.ID, input.Code)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{
+			"code":  twoFactorErrorStatus(err),
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "2FA berhasil diaktifkan. Simpan recovery code di tempat aman, kode hanya ditampilkan sekali",
+		"data": gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// DisableTwoFactor mematikan 2FA dengan kode TOTP atau recovery code; kode salah ikut menambah hitungan lockout
+func DisableTwoFactor(c *gin.Context) {
+	user, ok := twoFactorOwner(c)
+	if !ok {
+		return
+	}
+
+	var input model.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":  http.StatusBadRequest,
+			"error": "Kode 2FA wajib diisi",
+		})
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(user.Email))
+	limiter := service.NewLoginLimiterService()
+	if respondIfLocked(c, limiter, email) {
+		return
+	}
+
+	err := service.NewTwoFactorService(database.DbCore).Disable(user.ID, input.Code)
+	if errors.Is(err, service.ErrTwoFactorInvalidCode) {
+		respondAttemptFailed(c, limiter, email, c.ClientIP(), "Kode 2FA salah")
+		return
+	}
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{
+			"code":  twoFactorErrorStatus(err),
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "2FA berhasil dinonaktifkan",
+	})
+}
+
+// RegenerateTwoFactorRecoveryCodes mengganti seluruh recovery code; kode lama tidak berlaku lagi
+func RegenerateTwoFactorRecoveryCodes(c *gin.Context) {
+	user, ok := twoFactorOwner(c)
+	if !ok {
+		return
+	}
+
+	var input model.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":  http.StatusBadRequest,
+			"error": "Kode 2FA wajib diisi",
+		})
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(user.Email))
+	limiter := service.NewLoginLimiterService()
+	if respondIfLocked(c, limiter, email) {
+		return
+	}
+
+	codes, err := service.NewTwoFactorService(database.DbCore).RegenerateRecoveryCodes(user.ID, input.Code)
+	if errors.Is(err, service.ErrTwoFactorInvalidCode) {
+		respondAttemptFailed(c, limiter, email, c.ClientIP(), "Kode 2FA salah")
+		return
+	}
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{
+			"code":  twoFactorErrorStatus(err),
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "Recovery code baru berhasil dibuat, kode lama tidak berlaku lagi",
+		"data": gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
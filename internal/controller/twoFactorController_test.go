@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"BackendFramework/internal/database"
+	"BackendFramework/internal/middleware"
+	"BackendFramework/internal/model"
+	"BackendFramework/internal/service"
+)
+
+// useTestDbCore mengganti database.DbCore dengan SQLite in-memory berisi owner 1 yang mengaktifkan 2FA
+func useTestDbCore(t *testing.T) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.User{}, &model.UserTwoFactor{}, &model.TwoFactorRecoveryCode{}, &model.LoginAttempt{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	owner := model.User{ID: 1, NamaLengkap: "Owner", Email: "owner@example.com", Password: "x", ReferralCode: "OWNER1", IsAktif: "active"}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := db.Create(&model.UserTwoFactor{UserID: 1, Secret: "x", Enabled: true}).Error; err != nil {
+		t.Fatalf("create 2fa: %v", err)
+	}
+
+	previous := database.DbCore
+	database.DbCore = db
+	t.Cleanup(func() {
+		database.DbCore = previous
+		sqlDB.Close()
+	})
+}
+
+func TestLoginTwoFactorLockout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useTestDbCore(t)
+
+	challenge, err := middleware.GenerateTwoFactorChallengeToken("1", "email", "n", "")
+	if err != nil {
+		t.Fatalf("challenge token: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/auth/login/2fa", LoginTwoFactor)
+	attempt := func(code string) int {
+		body, _ := json.Marshal(model.TwoFactorLoginInput{ChallengeToken: challenge, RecoveryCode: code})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/login/2fa", bytes.NewReader(body)))
+		return w.Code
+	}
+
+	// Kode salah dihitung ke lockout email yang sama seperti password salah
+	for i := 1; i < service.MaxLoginAttempts; i++ {
+		if status := attempt("salah-kode"); status != http.StatusUnauthorized {
+			t.Fatalf("percobaan %d status = %d, want 401", i, status)
+		}
+	}
+	if status := attempt("salah-kode"); status != http.StatusTooManyRequests {
+		t.Fatalf("percobaan ke-%d status = %d, want 429", service.MaxLoginAttempts, status)
+	}
+
+	// Selama terkunci kode tidak diperiksa dan tidak menambah percobaan gagal
+	if status := attempt("salah-kode"); status != http.StatusTooManyRequests {
+		t.Errorf("saat terkunci status = %d, want 429", status)
+	}
+
+	var failed int64
+	database.DbCore.Model(&model.LoginAttempt{}).Where("email = ? AND success = ?", "owner@example.com", false).Count(&failed)
+	if failed != int64(service.MaxLoginAttempts) {
+		t.Errorf("%d percobaan gagal tercatat, want %d", failed, service.MaxLoginAttempts)
+	}
+}
@@ -104,7 +104,7 @@ func RegisterUser(c *gin.Context) {
 		"updated_at":            time.Now(),
 	}
 
-	if err := service.UpsertTokenData(userID, tokenData); err != nil {
+	if _, err := service.SaveSession(userID, deviceIDFromRequest(c, ""), tokenData); err != nil {
 		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
 			Success: false,
 			Error:   "Failed to save token data",
@@ -272,6 +272,30 @@ func GoogleSignIn(c *gin.Context) {
 		return
 	}
 
+	// Owner dengan 2FA aktif melanjutkan ke POST /auth/login/2fa
+	challenge, err := twoFactorChallenge(user, "google", "n", deviceIDFromRequest(c, ""))
+	if err != nil {
+		middleware.LogError(err, "[GoogleSignIn] Failed to check 2FA")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to check 2FA status",
+		})
+		return
+	}
+	if challenge != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Masukkan kode dari aplikasi authenticator",
+			"data": gin.H{
+				"two_factor_required": true,
+				"challenge_token":     challenge,
+				"expires_in":          int(middleware.TwoFactorChallengeExpiry.Seconds()),
+				"isNewUser":           false,
+			},
+		})
+		return
+	}
+
 	userID := fmt.Sprintf("%d", user.ID)
 
 	// Get outlet ID if exists
@@ -321,10 +345,11 @@ func GoogleSignIn(c *gin.Context) {
 		"is_valid_token":        "y",
 		"is_remember_me":        "n",
 		"login_method":          "google",
+		"outlet_id":             outletID,
 		"updated_at":            time.Now(),
 	}
 
-	if err := service.UpsertTokenData(userID, tokenData); err != nil {
+	if _, err := service.SaveSession(userID, deviceIDFromRequest(c, ""), tokenData); err != nil {
 		middleware.LogError(err, "[GoogleSignIn] Failed to save token data")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -462,7 +487,7 @@ func GoogleRegister(c *gin.Context) {
 		"updated_at":            time.Now(),
 	}
 
-	if err := service.UpsertTokenData(userID, tokenData); err != nil {
+	if _, err := service.SaveSession(userID, deviceIDFromRequest(c, ""), tokenData); err != nil {
 		middleware.LogError(err, "[GoogleRegister] Failed to save token data")
 		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
 			Success: false,
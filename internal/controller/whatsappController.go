@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"BackendFramework/internal/config"
+	"BackendFramework/internal/model"
+	"BackendFramework/internal/service"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WhatsappController struct {
+	service *service.WhatsappNotificationService
+}
+
+func NewWhatsappController(service *service.WhatsappNotificationService) *WhatsappController {
+	return &WhatsappController{service: service}
+}
+
+func (c *WhatsappController) GetTemplates(ctx *gin.Context) {
+	outletID := ctx.GetUint("outlet_id")
+
+	templates, err := c.service.GetTemplates(outletID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"success": true, "data": templates})
+}
+
+func (c *WhatsappController) UpdateTemplate(ctx *gin.Context) {
+	var input model.WhatsappTemplateInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	outletID := ctx.GetUint("outlet_id")
+
+	tmpl, err := c.service.UpdateTemplate(outletID, ctx.Param("event"), input)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"success": true, "data": tmpl})
+}
+
+func (c *WhatsappController) GetLogs(ctx *gin.Context) {
+	outletID := ctx.GetUint("outlet_id")
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	transactionID, _ := strconv.ParseUint(ctx.Query("transaction_id"), 10, 32)
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	logs, total, err := c.service.GetLogs(outletID, uint(transactionID), ctx.Query("status"), page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    logs,
+		"pagination": gin.H{
+			"page":       page,
+			"limit":      limit,
+			"total":      total,
+			"total_page": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// ResendNotification mengirim ulang notifikasi pesanan secara manual, misal setelah gagal terkirim
+func (c *WhatsappController) ResendNotification(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ID transaksi tidak valid"})
+		return
+	}
+
+	var input struct {
+		Event string `json:"event" binding:"required,oneof=order_created order_ready pickup_reminder"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	messageLog, err := c.service.NotifyTransactionForOutlet(uint(id), ctx.GetUint("outlet_id"), input.Event, getStaffName(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"success": false, "message": err.Error(), "data": messageLog})
+		return
+	}
+	if messageLog == nil {
+		ctx.JSON(http.StatusOK, gin.H{"success": true, "message": "Template notifikasi ini dinonaktifkan outlet"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"success": true, "data": messageLog})
+}
+
+// DeliveryReport menerima webhook delivery report dari Infobip
+func (c *WhatsappController) DeliveryReport(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if config.INFOBIP_WEBHOOK_TOKEN == "" || subtle.ConstantTimeCompare([]byte(token), []byte(config.INFOBIP_WEBHOOK_TOKEN)) != 1 {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "invalid webhook token"})
+		return
+	}
+
+	var report model.InfobipDeliveryReport
+	if err := ctx.ShouldBindJSON(&report); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	updated, err := c.service.ApplyDeliveryReport(report)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"success": true, "updated": updated})
+}
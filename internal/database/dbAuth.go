@@ -5,6 +5,7 @@ import (
 	"log"
 	"context"
 
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 
@@ -34,4 +35,17 @@ func OpenAuth() {
 	}
 
 	DbAuth = client.Database(config.DB_AUTH_DBNAME)
+
+	// access_tokens berisi satu dokumen per sesi, dicari berdasarkan access_token, session_id dan user_id
+	_, err = DbAuth.Collection("access_tokens").Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "access_token", Value: 1}}},
+		{Keys: bson.D{{Key: "session_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "device_id", Value: 1}}},
+		{Keys: bson.D{{Key: "owner_user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "refresh_token_hash", Value: 1}}},
+		{Keys: bson.D{{Key: "rotated_refresh_tokens", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("Failed to create access_tokens indexes %v", err)
+	}
 }
\ No newline at end of file
@@ -31,7 +31,10 @@ func OpenAkademik() {
 		&model.User{},
 		&model.Outlet{},
 		&model.OTP{},
+		&model.OTPSendLog{},
 		&model.LoginAttempt{},
+		&model.UserTwoFactor{},
+		&model.TwoFactorRecoveryCode{},
 		&model.Layanan{},
 		&model.JenisProduk{},
 		&model.Diskon{},
@@ -43,14 +46,18 @@ func OpenAkademik() {
 		&model.NotaData{},
 		&model.Karyawan{},
 		&model.Customer{},
+		&model.CustomerMerge{},
 		&model.ServiceCategory{},
 		&model.ServiceProduct{},
-		&model.Employee{},
-		&model.Parfume{},
-		&model.Discount{},
+		&model.LegacyIDMap{},
 		&model.Transaction{},
 		&model.OrderLog{},
 		&model.TransactionDetail{},
+		&model.TransactionPayment{},
+		&model.InvoiceSequence{},
+		&model.WhatsappTemplate{},
+		&model.WhatsappMessageLog{},
+		&model.CashShift{},
 
 	)
 	if err != nil {
@@ -6,7 +6,9 @@ import (
     "crypto/cipher"
     "crypto/rand"
     "encoding/base64"
+    "encoding/hex"
     "errors"
+    "fmt"
     "io"
     "time"
     "net/http"
@@ -25,25 +27,133 @@ import (
 var jwtSecret = []byte(config.JWT_SIGNATURE_KEY)
 
 type AccessClaims struct {
-    UserID   string `json:"user_id"`
-    OutletID uint   `json:"outlet_id"`
+    UserID      string   `json:"user_id"`
+    OutletID    uint     `json:"outlet_id"`
+    OutletScope string   `json:"outlet_scope,omitempty"` // OutletScopeAll: owner melihat laporan gabungan semua outlet
+    KaryawanID  uint     `json:"karyawan_id,omitempty"` // Terisi jika token milik karyawan, kosong untuk owner
+    Role        string   `json:"role,omitempty"`
+    Permissions []string `json:"permissions,omitempty"`
     jwt.RegisteredClaims
 }
 
+// KaryawanSessionKey adalah user_id dokumen access_tokens untuk sesi karyawan,
+// dibedakan dari owner karena UserID di token karyawan berisi ID owner outlet
+func KaryawanSessionKey(karyawanID uint) string {
+    return fmt.Sprintf("karyawan:%d", karyawanID)
+}
+
+// SessionKey mengembalikan kunci dokumen access_tokens milik token ini
+func (c *AccessClaims) SessionKey() string {
+    if c.KaryawanID != 0 {
+        return KaryawanSessionKey(c.KaryawanID)
+    }
+    return c.UserID
+}
+
+// OutletScopeAll menandai token owner yang boleh memakai endpoint laporan gabungan semua outlet;
+// endpoint biasa tetap memakai OutletID sebagai outlet aktif
+const OutletScopeAll = "all"
+
 func GenerateAccessToken(userID string, outletID uint) (string, error) {
+    return GenerateScopedAccessToken(userID, outletID, "")
+}
+
+// GenerateScopedAccessToken membuat access token owner untuk outlet tertentu, opsional dengan OutletScopeAll
+func GenerateScopedAccessToken(userID string, outletID uint, outletScope string) (string, error) {
     claims := &AccessClaims{
-        UserID:   userID,
-        OutletID: outletID,
-        RegisteredClaims: jwt.RegisteredClaims{
-            ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.AccessTokenExpiry)),
-            IssuedAt:  jwt.NewNumericDate(time.Now()),
-            Issuer:    "BackendFramework UIB",
-        },
+        UserID:      userID,
+        OutletID:    outletID,
+        OutletScope: outletScope,
+        RegisteredClaims: newRegisteredClaims(),
     }
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
     return token.SignedString(jwtSecret)
 }
 
+// newRegisteredClaims mengisi jti acak agar dua token yang dibuat di detik yang sama tetap berbeda;
+// access token dipakai sebagai kunci pencarian sesi di access_tokens
+func newRegisteredClaims() jwt.RegisteredClaims {
+    jti := make([]byte, 16)
+    rand.Read(jti)
+
+    return jwt.RegisteredClaims{
+        ID:        hex.EncodeToString(jti),
+        ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.AccessTokenExpiry)),
+        IssuedAt:  jwt.NewNumericDate(time.Now()),
+        Issuer:    "BackendFramework UIB",
+    }
+}
+
+// GenerateKaryawanAccessToken membuat access token karyawan. UserID diisi owner outlet
+// agar query yang di-scope per owner tetap berjalan, KaryawanID menandai staf yang login.
+func GenerateKaryawanAccessToken(ownerUserID string, karyawan *model.Karyawan) (string, error) {
+    var outletID uint
+    if karyawan.OutletID != nil {
+        outletID = *karyawan.OutletID
+    }
+
+    claims := &AccessClaims{
+        UserID:      ownerUserID,
+        OutletID:    outletID,
+        KaryawanID:  karyawan.ID,
+        Role:        karyawan.Role,
+        Permissions: karyawan.PermissionList(),
+        RegisteredClaims: newRegisteredClaims(),
+    }
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+    return token.SignedString(jwtSecret)
+}
+
+// TwoFactorChallengeClaims adalah token sementara antara langkah password dan langkah kode 2FA saat login
+type TwoFactorChallengeClaims struct {
+    UserID      string `json:"user_id"`
+    Purpose     string `json:"purpose"`
+    LoginMethod string `json:"login_method"`
+    RememberMe  string `json:"remember_me,omitempty"`
+    DeviceID    string `json:"device_id,omitempty"`
+    jwt.RegisteredClaims
+}
+
+const (
+    twoFactorChallengePurpose = "login_2fa"
+    TwoFactorChallengeExpiry  = 5 * time.Minute
+)
+
+// GenerateTwoFactorChallengeToken membuat token langkah kedua login; token ini tidak bisa dipakai sebagai access token
+func GenerateTwoFactorChallengeToken(userID, loginMethod, rememberMe, deviceID string) (string, error) {
+    registered := newRegisteredClaims()
+    registered.ExpiresAt = jwt.NewNumericDate(time.Now().Add(TwoFactorChallengeExpiry))
+
+    claims := TwoFactorChallengeClaims{
+        UserID:           userID,
+        Purpose:          twoFactorChallengePurpose,
+        LoginMethod:      loginMethod,
+        RememberMe:       rememberMe,
+        DeviceID:         deviceID,
+        RegisteredClaims: registered,
+    }
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+    return token.SignedString(jwtSecret)
+}
+
+// ValidateTwoFactorChallengeToken memvalidasi token langkah kedua login
+func ValidateTwoFactorChallengeToken(tokenString string) (*TwoFactorChallengeClaims, error) {
+    token, err := jwt.ParseWithClaims(tokenString, &TwoFactorChallengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+            return nil, errors.New("unexpected signing method")
+        }
+        return jwtSecret, nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    claims, ok := token.Claims.(*TwoFactorChallengeClaims)
+    if !ok || !token.Valid || claims.Purpose != twoFactorChallengePurpose || claims.UserID == "" {
+        return nil, errors.New("invalid challenge token")
+    }
+    return claims, nil
+}
+
 func GenerateRefreshToken() (string, error) {
     bytes := make([]byte, 32)
     _, err := rand.Read(bytes)
@@ -69,22 +179,28 @@ func GenerateRefreshToken() (string, error) {
 }
 
 func ValidateToken(tokenString string) (*AccessClaims, error) {
+    claims, _, err := ValidateTokenSession(tokenString)
+    return claims, err
+}
+
+// ValidateTokenSession memvalidasi JWT lalu mencari sesinya di access_tokens berdasarkan access token
+func ValidateTokenSession(tokenString string) (*AccessClaims, *model.TokenData, error) {
     token, err := jwt.ParseWithClaims(tokenString, &AccessClaims{}, func(token *jwt.Token) (interface{}, error) {
         return jwtSecret, nil
     })
     if err != nil {
-        return nil, err
+        return nil, nil, err
     }
     claims, ok := token.Claims.(*AccessClaims)
     if !ok || !token.Valid {
-        return nil, errors.New("invalid token")
+        return nil, nil, errors.New("invalid token")
     }
     var storedToken model.TokenData
-    err = database.DbAuth.Collection("access_tokens").FindOne(context.TODO(), bson.M{"user_id": claims.UserID}).Decode(&storedToken)
-    if err != nil || storedToken.AccessToken != tokenString || storedToken.IsValidToken == "n" {
-        return nil, errors.New("Token not found or expired")
+    err = database.DbAuth.Collection("access_tokens").FindOne(context.TODO(), bson.M{"access_token": tokenString}).Decode(&storedToken)
+    if err != nil || storedToken.UserId != claims.SessionKey() || storedToken.IsValidToken == "n" {
+        return nil, nil, errors.New("Token not found or expired")
     }
-    return claims, nil
+    return claims, &storedToken, nil
 }
 
 func JWTAuthMiddleware() gin.HandlerFunc {
@@ -110,7 +226,7 @@ func JWTAuthMiddleware() gin.HandlerFunc {
         }
         
         token := parts[1]
-        claims, err := ValidateToken(token)
+        claims, session, err := ValidateTokenSession(token)
         if err != nil {
             c.JSON(http.StatusOK, gin.H{
                 "code":  http.StatusUnauthorized,
@@ -130,9 +246,31 @@ func JWTAuthMiddleware() gin.HandlerFunc {
             return
         }
         
-        c.Set("userID", claims.UserID)
+        c.Set("userID", claims.SessionKey())
         c.Set("user_id", uint(userID))
         c.Set("outlet_id", claims.OutletID)
+        c.Set("outlet_scope", claims.OutletScope)
+        c.Set("session_id", session.SessionID)
+
+        if claims.KaryawanID != 0 {
+            // Karyawan yang dinonaktifkan langsung kehilangan akses walau token belum kedaluwarsa
+            var karyawan model.Karyawan
+            if err := database.DbCore.Where("kar_id = ?", claims.KaryawanID).First(&karyawan).Error; err != nil || karyawan.Status != "Aktif" {
+                c.JSON(http.StatusOK, gin.H{
+                    "code":  http.StatusUnauthorized,
+                    "error": "Akun karyawan tidak aktif",
+                })
+                c.Abort()
+                return
+            }
+
+            c.Set("karyawan_id", claims.KaryawanID)
+            c.Set("karyawan", &karyawan)
+            c.Set("role", karyawan.Role)
+            c.Set("username", karyawan.Nama)
+            c.Next()
+            return
+        }
         
         var user model.User
         if err := database.DbCore.Where("id = ?", uint(userID)).First(&user).Error; err == nil {
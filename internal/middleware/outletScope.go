@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"BackendFramework/internal/database"
+	"BackendFramework/internal/model"
+)
+
+// OutletHeader adalah header yang dipakai client untuk memilih outlet aktif
+const OutletHeader = "X-Outlet-ID"
+
+var (
+	ErrOutletIDInvalid  = errors.New("outlet_id tidak valid")
+	ErrOutletIDConflict = errors.New("outlet_id di path, query dan header " + OutletHeader + " tidak sama")
+)
+
+// OutletScope menentukan outlet aktif dari path :outlet_id, query outlet_id atau header X-Outlet-ID
+// (jika tidak ada, outlet di token) dan menolak request ke outlet yang bukan milik owner atau
+// bukan tempat karyawan ditugaskan. Outlet yang lolos ditulis ulang ke context "outlet_id".
+// Token owner OutletScopeAll yang tidak memilih outlet ditandai "all_outlets" untuk OutletIDsInScope.
+// Harus dipasang setelah JWTAuthMiddleware.
+func OutletScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		outletID, explicit, err := requestedOutletID(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":  http.StatusBadRequest,
+				"error": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+
+		allowed, err := CanAccessOutlet(c, outletID)
+		if err != nil {
+			LogError(err, "Gagal memeriksa akses outlet")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":  http.StatusInternalServerError,
+				"error": "Gagal memeriksa akses outlet",
+			})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			RespondOutletForbidden(c)
+			c.Abort()
+			return
+		}
+
+		c.Set("outlet_id", outletID)
+		if !explicit && c.GetString("outlet_scope") == OutletScopeAll {
+			c.Set("all_outlets", true)
+		}
+		c.Next()
+	}
+}
+
+// requestedOutletID membaca outlet yang diminta; semua sumber yang diisi harus menunjuk outlet yang sama.
+// explicit false berarti request tidak memilih outlet sehingga dipakai outlet di token.
+func requestedOutletID(c *gin.Context) (uint, bool, error) {
+	candidates := []string{
+		c.Param("outlet_id"),
+		c.Query("outlet_id"),
+		c.GetHeader(OutletHeader),
+	}
+
+	var outletID uint
+	for _, raw := range candidates {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil || id == 0 {
+			return 0, false, ErrOutletIDInvalid
+		}
+		if outletID != 0 && outletID != uint(id) {
+			return 0, false, ErrOutletIDConflict
+		}
+		outletID = uint(id)
+	}
+
+	if outletID == 0 {
+		return c.GetUint("outlet_id"), false, nil
+	}
+	return outletID, true, nil
+}
+
+// OutletIDsInScope mengembalikan outlet yang dicakup endpoint laporan: semua outlet milik owner
+// untuk token OutletScopeAll tanpa outlet yang dipilih, selain itu hanya outlet aktif
+func OutletIDsInScope(c *gin.Context) ([]uint, error) {
+	if !c.GetBool("all_outlets") {
+		return []uint{c.GetUint("outlet_id")}, nil
+	}
+
+	var outletIDs []uint
+	if err := database.DbCore.Model(&model.Outlet{}).
+		Where("user_id = ?", c.GetUint("user_id")).
+		Order("id ASC").
+		Pluck("id", &outletIDs).Error; err != nil {
+		return nil, err
+	}
+	return outletIDs, nil
+}
+
+// CanAccessOutlet memeriksa apakah user di token boleh mengakses outlet: owner harus pemilik outlet
+// (Outlet.UserID), karyawan harus ditugaskan di outlet tersebut. Dipakai juga oleh handler yang
+// menerima outlet_id dari body request.
+func CanAccessOutlet(c *gin.Context, outletID uint) (bool, error) {
+	if outletID == 0 {
+		return false, nil
+	}
+
+	if karyawanID, isKaryawan := c.Get("karyawan_id"); isKaryawan {
+		karyawan, err := loadKaryawan(c, karyawanID)
+		if err != nil {
+			return false, nil
+		}
+		return karyawan.OutletID != nil && *karyawan.OutletID == outletID, nil
+	}
+
+	var count int64
+	if err := database.DbCore.Model(&model.Outlet{}).
+		Where("id = ? AND user_id = ?", outletID, c.GetUint("user_id")).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// RespondOutletForbidden mengirim 403 untuk akses ke outlet milik tenant lain
+func RespondOutletForbidden(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"code":  http.StatusForbidden,
+		"error": "Akses ditolak, outlet bukan milik akun ini",
+	})
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"BackendFramework/internal/database"
+	"BackendFramework/internal/model"
+)
+
+// useTestDbCore mengganti database.DbCore dengan SQLite in-memory berisi outlet 1 dan 2 milik owner 10,
+// outlet 3 milik owner 20, serta karyawan 7 yang ditugaskan di outlet 1
+func useTestDbCore(t *testing.T) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.User{}, &model.Outlet{}, &model.Karyawan{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	for _, outlet := range []model.Outlet{{ID: 1, UserID: 10}, {ID: 2, UserID: 10}, {ID: 3, UserID: 20}} {
+		if err := db.Create(&outlet).Error; err != nil {
+			t.Fatalf("create outlet: %v", err)
+		}
+	}
+	outletID := uint(1)
+	if err := db.Create(&model.Karyawan{ID: 7, OutletID: &outletID, Nama: "Kasir", Email: "kasir@example.com", Password: "x"}).Error; err != nil {
+		t.Fatalf("create karyawan: %v", err)
+	}
+
+	previous := database.DbCore
+	database.DbCore = db
+	t.Cleanup(func() {
+		database.DbCore = previous
+		sqlDB.Close()
+	})
+}
+
+func TestOutletScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useTestDbCore(t)
+
+	owner := map[string]interface{}{"user_id": uint(10), "outlet_id": uint(1)}
+	ownerAll := map[string]interface{}{"user_id": uint(10), "outlet_id": uint(1), "outlet_scope": OutletScopeAll}
+	karyawan := map[string]interface{}{"user_id": uint(10), "outlet_id": uint(1), "karyawan_id": uint(7)}
+
+	tests := []struct {
+		name        string
+		claims      map[string]interface{}
+		path        string
+		header      string
+		wantStatus  int
+		wantOutlet  uint
+		wantOutlets []uint
+	}{
+		{name: "outlet dari token", claims: owner, path: "/items", wantStatus: http.StatusOK, wantOutlet: 1, wantOutlets: []uint{1}},
+		{name: "header outlet milik owner", claims: owner, path: "/items", header: "2", wantStatus: http.StatusOK, wantOutlet: 2, wantOutlets: []uint{2}},
+		{name: "query outlet milik owner", claims: owner, path: "/items?outlet_id=2", wantStatus: http.StatusOK, wantOutlet: 2, wantOutlets: []uint{2}},
+		{name: "path dan header sama", claims: owner, path: "/outlet/2", header: "2", wantStatus: http.StatusOK, wantOutlet: 2, wantOutlets: []uint{2}},
+		{name: "outlet owner lain", claims: owner, path: "/items", header: "3", wantStatus: http.StatusForbidden},
+		{name: "path outlet owner lain", claims: owner, path: "/outlet/3", wantStatus: http.StatusForbidden},
+		{name: "path dan header berbeda", claims: owner, path: "/outlet/1", header: "2", wantStatus: http.StatusBadRequest},
+		{name: "header bukan angka", claims: owner, path: "/items", header: "satu", wantStatus: http.StatusBadRequest},
+		{name: "header nol", claims: owner, path: "/items", header: "0", wantStatus: http.StatusBadRequest},
+		{name: "token tanpa outlet", claims: map[string]interface{}{"user_id": uint(10)}, path: "/items", wantStatus: http.StatusForbidden},
+		{name: "scope all tanpa pilihan outlet", claims: ownerAll, path: "/items", wantStatus: http.StatusOK, wantOutlet: 1, wantOutlets: []uint{1, 2}},
+		{name: "scope all memilih outlet", claims: ownerAll, path: "/items", header: "2", wantStatus: http.StatusOK, wantOutlet: 2, wantOutlets: []uint{2}},
+		{name: "karyawan di outletnya", claims: karyawan, path: "/items", header: "1", wantStatus: http.StatusOK, wantOutlet: 1, wantOutlets: []uint{1}},
+		{name: "karyawan ke outlet lain milik owner", claims: karyawan, path: "/items", header: "2", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				for key, value := range tt.claims {
+					c.Set(key, value)
+				}
+			}, OutletScope())
+			handler := func(c *gin.Context) {
+				outletIDs, err := OutletIDsInScope(c)
+				if err != nil {
+					t.Errorf("OutletIDsInScope: %v", err)
+				}
+				c.JSON(http.StatusOK, gin.H{"outlet_id": c.GetUint("outlet_id"), "outlet_ids": outletIDs})
+			}
+			r.GET("/items", handler)
+			r.GET("/outlet/:outlet_id", handler)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(OutletHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				OutletID  uint   `json:"outlet_id"`
+				OutletIDs []uint `json:"outlet_ids"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body.OutletID != tt.wantOutlet || !reflect.DeepEqual(body.OutletIDs, tt.wantOutlets) {
+				t.Errorf("outlet = %d %v, want %d %v", body.OutletID, body.OutletIDs, tt.wantOutlet, tt.wantOutlets)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"BackendFramework/internal/database"
+	"BackendFramework/internal/model"
+)
+
+// RequirePermission membatasi route untuk karyawan yang punya permission dari model.AvailablePermissions.
+// Token owner (tanpa karyawan_id) selalu lolos. Harus dipasang setelah JWTAuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return RequireAnyPermission(permission)
+}
+
+// RequireAnyPermission seperti RequirePermission, tetapi cukup satu dari permissions yang dimiliki karyawan.
+// Dipakai untuk data master yang dikelola satu permission namun juga perlu dibaca kasir saat membuat order.
+func RequireAnyPermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		karyawanID, isKaryawan := c.Get("karyawan_id")
+		if !isKaryawan {
+			c.Next()
+			return
+		}
+
+		karyawan, err := loadKaryawan(c, karyawanID)
+		if err != nil || karyawan.Status != "Aktif" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":  http.StatusForbidden,
+				"error": "Akun karyawan tidak aktif",
+			})
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if karyawan.HasPermission(permission) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":       http.StatusForbidden,
+			"error":      "Akses ditolak, karyawan tidak memiliki permission: " + strings.Join(permissions, " atau "),
+			"permission": permissions[0],
+		})
+		c.Abort()
+	}
+}
+
+// OwnerOnly menolak token karyawan untuk aksi milik owner (kelola user sistem, buat/hapus outlet)
+// yang tidak boleh didapat lewat permission apa pun. Harus dipasang setelah JWTAuthMiddleware.
+func OwnerOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isKaryawan := c.Get("karyawan_id"); isKaryawan {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":  http.StatusForbidden,
+				"error": "Akses ditolak, hanya untuk owner",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// loadKaryawan membaca karyawan sekali per request; permission diambil dari database
+// agar perubahan dari owner langsung berlaku tanpa menunggu token kedaluwarsa
+func loadKaryawan(c *gin.Context, karyawanID interface{}) (*model.Karyawan, error) {
+	if cached, ok := c.Get("karyawan"); ok {
+		return cached.(*model.Karyawan), nil
+	}
+
+	var karyawan model.Karyawan
+	if err := database.DbCore.Where("kar_id = ?", karyawanID).First(&karyawan).Error; err != nil {
+		return nil, err
+	}
+
+	c.Set("karyawan", &karyawan)
+	return &karyawan, nil
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"BackendFramework/internal/database"
+	"BackendFramework/internal/model"
+)
+
+func TestPermissionGuards(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useTestDbCore(t)
+
+	// Karyawan 7 dari useTestDbCore hanya punya akses transaksi (kasir order)
+	database.DbCore.Model(&model.Karyawan{ID: 7}).Update("kar_permissions", `["`+model.PermissionLayananTransaksi+`"]`)
+
+	owner := map[string]interface{}{"user_id": uint(10)}
+	kasir := map[string]interface{}{"user_id": uint(10), "karyawan_id": uint(7)}
+
+	tests := []struct {
+		name       string
+		claims     map[string]interface{}
+		guard      gin.HandlerFunc
+		wantStatus int
+	}{
+		{name: "owner lolos permission", claims: owner, guard: RequirePermission(model.PermissionLayananPelanggan), wantStatus: http.StatusOK},
+		{name: "kasir tanpa permission", claims: kasir, guard: RequirePermission(model.PermissionLayananPelanggan), wantStatus: http.StatusForbidden},
+		{name: "kasir dengan salah satu permission", claims: kasir, guard: RequireAnyPermission(model.PermissionLayananPelanggan, model.PermissionLayananTransaksi), wantStatus: http.StatusOK},
+		{name: "owner aksi owner", claims: owner, guard: OwnerOnly(), wantStatus: http.StatusOK},
+		{name: "karyawan aksi owner", claims: kasir, guard: OwnerOnly(), wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", func(c *gin.Context) {
+				for key, value := range tt.claims {
+					c.Set(key, value)
+				}
+			}, tt.guard, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
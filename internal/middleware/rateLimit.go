@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitWindow adalah jumlah request satu IP dalam window yang sedang berjalan
+type rateLimitWindow struct {
+	start time.Time
+	count int
+}
+
+// RateLimit membatasi request per IP client dengan fixed window, untuk endpoint publik tanpa login.
+// Hitungan hanya disimpan di memori proses ini: tidak dibagi antar instance server dan hilang saat restart,
+// sehingga di belakang load balancer dengan N instance satu IP bisa mendapat sampai N x limit request.
+// Pembatasan lintas instance harus dilakukan di reverse proxy atau dengan penyimpanan bersama.
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	var (
+		mu        sync.Mutex
+		windows   = make(map[string]*rateLimitWindow)
+		lastSweep = time.Now()
+	)
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		ip := c.ClientIP()
+
+		mu.Lock()
+		// Buang window yang sudah lewat agar map tidak terus membesar
+		if now.Sub(lastSweep) > window {
+			for key, w := range windows {
+				if now.Sub(w.start) >= window {
+					delete(windows, key)
+				}
+			}
+			lastSweep = now
+		}
+
+		w, ok := windows[ip]
+		if !ok || now.Sub(w.start) >= window {
+			w = &rateLimitWindow{start: now}
+			windows[ip] = w
+		}
+		w.count++
+		count, retryAfter := w.count, w.start.Add(window).Sub(now)
+		mu.Unlock()
+
+		if count > limit {
+			seconds := int(retryAfter.Seconds()) + 1
+			c.Header("Retry-After", fmt.Sprintf("%d", seconds))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"code":        http.StatusTooManyRequests,
+				"error":       "Terlalu banyak permintaan, coba lagi nanti",
+				"retry_after": seconds,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
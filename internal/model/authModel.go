@@ -5,17 +5,42 @@ import (
 )
 
 type TokenData struct {
+	SessionID             string    `bson:"session_id,omitempty"` // Satu dokumen per sesi / perangkat
+	DeviceID              string    `bson:"device_id,omitempty"`
 	UserId                string    `bson:"user_id"`
 	LastIpAddress         string    `bson:"last_ip_address"`
 	LastUserAgent         string    `bson:"last_user_agent"`
 	AccessToken           string    `bson:"access_token"`
-	RefreshToken          string    `bson:"refresh_token"`
+	RefreshToken          string    `bson:"refresh_token,omitempty"`      // Format lama (plain), diganti hash saat rotasi
+	RefreshTokenHash      string    `bson:"refresh_token_hash,omitempty"` // SHA-256 dari refresh token yang berlaku
+	RotatedRefreshTokens  []string  `bson:"rotated_refresh_tokens,omitempty"`
+	RevokedReason         string    `bson:"revoked_reason,omitempty"`
 	RefreshTokenExpiredAt time.Time `bson:"refresh_token_expired"`
 	LastLogin             time.Time `bson:"last_login"`
 	IsValidToken          string    `bson:"is_valid_token"`
 	IsRememberMe          string    `bson:"is_remember_me"`
+	Email                 string    `bson:"email"`
+	Username              string    `bson:"username"`
+	LoginMethod           string    `bson:"login_method"`
+	AccountType           string    `bson:"account_type,omitempty"`  // "owner" atau "karyawan"
+	KaryawanID            uint      `bson:"karyawan_id,omitempty"`   // Hanya untuk sesi karyawan
+	OwnerUserID           string    `bson:"owner_user_id,omitempty"` // Owner outlet tempat karyawan bekerja
+	OutletID              uint      `bson:"outlet_id,omitempty"`
+	OutletScope           string    `bson:"outlet_scope,omitempty"` // middleware.OutletScopeAll setelah owner memilih semua outlet
+	CreatedAt             time.Time `bson:"created_at"`
 }
 
+// SwitchOutletInput adalah body /auth/switch-outlet; scope "all" meminta token laporan gabungan semua outlet
+type SwitchOutletInput struct {
+	OutletID uint   `json:"outlet_id"`
+	Scope    string `json:"scope"`
+}
+
+const (
+	AccountTypeOwner    = "owner"
+	AccountTypeKaryawan = "karyawan"
+)
+
 type UserActivity struct {
 	Userid    string    `bson:"user_id"`
 	Endpoint  string    `bson:"endpoint"`
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CashShiftOpen   = "open"
+	CashShiftClosed = "closed"
+)
+
+// ErrCashShiftLocked dikembalikan jika ada upaya mengubah shift yang sudah ditutup
+var ErrCashShiftLocked = errors.New("shift kasir sudah ditutup dan tidak dapat diubah")
+
+// CashShift adalah satu sesi kasir dari buka sampai tutup kasir.
+// Angka kas di-snapshot saat tutup sehingga perubahan pembayaran/pengeluaran setelahnya tidak mengubah laporan.
+type CashShift struct {
+	ID            uint                `gorm:"primaryKey" json:"id"`
+	OutletID      uint                `gorm:"index;not null" json:"outlet_id"`
+	Status        string              `gorm:"type:varchar(10);default:'open';index" json:"status"`
+	OpenedBy      string              `json:"opened_by"`
+	OpenedAt      time.Time           `json:"opened_at"`
+	ClosedBy      string              `json:"closed_by"`
+	ClosedAt      *time.Time          `json:"closed_at"`
+	OpeningCash   float64             `gorm:"type:decimal(15,2)" json:"opening_cash"`  // Modal awal laci
+	CashIn        float64             `gorm:"type:decimal(15,2)" json:"cash_in"`       // Pembayaran kategori Cash
+	NonCashIn     float64             `gorm:"type:decimal(15,2)" json:"non_cash_in"`   // Transfer / E-Wallet, informasi saja
+	CashOut       float64             `gorm:"type:decimal(15,2)" json:"cash_out"`      // Pengeluaran
+	ExpectedCash  float64             `gorm:"type:decimal(15,2)" json:"expected_cash"` // OpeningCash + CashIn - CashOut
+	CountedCash   *float64            `gorm:"type:decimal(15,2)" json:"counted_cash"`  // Diisi kasir saat tutup
+	Difference    float64             `gorm:"type:decimal(15,2)" json:"difference"`    // CountedCash - ExpectedCash
+	PaymentCount  int                 `json:"payment_count"`
+	ExpenseCount  int                 `json:"expense_count"`
+	OpeningNotes  string              `gorm:"type:text" json:"opening_notes"`
+	ClosingNotes  string              `gorm:"type:text" json:"closing_notes"`
+	BreakdownJSON string              `gorm:"type:text" json:"-"`
+	Breakdown     *CashShiftBreakdown `gorm:"-" json:"breakdown,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+func (CashShift) TableName() string {
+	return "cash_shifts"
+}
+
+// BeforeUpdate mengunci shift yang sudah ditutup. Hook hanya melihat status di memori, jadi update
+// lewat map atau UpdateColumn harus menyaring status = open sendiri (lihat CashShiftService.Close).
+func (s *CashShift) BeforeUpdate(tx *gorm.DB) error {
+	if s.Status == CashShiftClosed {
+		return ErrCashShiftLocked
+	}
+	return nil
+}
+
+// BeforeDelete mencegah laporan tutup kasir dihapus
+func (s *CashShift) BeforeDelete(tx *gorm.DB) error {
+	if s.Status == CashShiftClosed {
+		return ErrCashShiftLocked
+	}
+	return nil
+}
+
+// CashShiftBreakdown adalah rincian pemasukan per metode bayar dan pengeluaran per kategori
+type CashShiftBreakdown struct {
+	PaymentMethods []CashShiftMethodTotal   `json:"payment_methods"`
+	Expenses       []PengeluaranPerKategori `json:"expenses"`
+}
+
+type CashShiftMethodTotal struct {
+	PaymentMethodID uint    `json:"payment_method_id"`
+	Name            string  `json:"name"`
+	Category        string  `json:"category"`
+	Total           float64 `json:"total"`
+	Jumlah          int64   `json:"jumlah"`
+}
+
+type OpenCashShiftInput struct {
+	OpeningCash float64 `json:"opening_cash" binding:"gte=0"`
+	Notes       string  `json:"notes"`
+}
+
+type CloseCashShiftInput struct {
+	CountedCash *float64 `json:"counted_cash" binding:"required,gte=0"`
+	Notes       string   `json:"notes"`
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 )
 
@@ -8,10 +9,146 @@ type Customer struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	OutletID  uint      `json:"outlet_id"` // FK ke Outlet
 	Name      string    `json:"name"`
-	Phone     string    `json:"phone"`
+	// Phone selalu disimpan dalam format NormalizePhone dan unik per outlet
+	Phone     string    `gorm:"type:varchar(20)" json:"phone"`
 	Gender    string    `json:"gender"`  // Pria / Wanita
 	OTP       *string   `json:"otp"`     // Nullable
 	Address   string    `json:"address"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-}
\ No newline at end of file
+}
+
+type CustomerInput struct {
+	Name    string  `json:"name" binding:"required"`
+	Phone   string  `json:"phone" binding:"required"`
+	Gender  string  `json:"gender"`
+	OTP     *string `json:"otp"`
+	Address string  `json:"address"`
+}
+
+type UpdateCustomerInput struct {
+	Name    string `json:"name"`
+	Phone   string `json:"phone"`
+	Gender  string `json:"gender"`
+	Address string `json:"address"`
+}
+
+// CustomerFilter adalah query GET /customers; q dicocokkan ke nama, nomor HP dan alamat
+type CustomerFilter struct {
+	Q     string `form:"q"`
+	Page  int    `form:"page"`
+	Limit int    `form:"limit"`
+}
+
+// NormalizePhone menyeragamkan nomor HP ke format internasional tanpa "+" dan pemisah,
+// misalnya "0812-3456 789" dan "+62 812 3456 789" menjadi "628123456789". Nomor yang diawali "8"
+// (0 di depan hilang, misal diketik sebagai angka di Excel) dianggap nomor Indonesia.
+func NormalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+
+	normalized := digits.String()
+	switch {
+	case strings.HasPrefix(normalized, "0"):
+		return "62" + normalized[1:]
+	case strings.HasPrefix(normalized, "8"):
+		return "62" + normalized
+	}
+	return normalized
+}
+
+// CustomerSummary adalah profil pelanggan di kasir: agregat dari seluruh pesanan yang tidak dibatalkan
+// dan riwayat pesanan terbaru beserta OrderLog-nya
+type CustomerSummary struct {
+	Customer           Customer               `json:"customer"`
+	TotalSpend         float64                `json:"total_spend"`
+	VisitCount         int64                  `json:"visit_count"`
+	AverageBasket      float64                `json:"average_basket"`
+	OutstandingBalance float64                `json:"outstanding_balance"` // Sisa tagihan (TotalPrice - PaidAmount)
+	UnpaidCount        int64                  `json:"unpaid_count"`
+	FavouriteParfum    *CustomerParfumSummary `json:"favourite_parfum"`
+	LastVisit          *time.Time             `json:"last_visit"`
+	History            []Transaction          `json:"history"`
+	HistoryTotal       int64                  `json:"history_total"` // Termasuk pesanan Batal
+}
+
+type CustomerParfumSummary struct {
+	ParfumID   uint   `json:"parfum_id"`
+	NamaParfum string `json:"nama_parfum"`
+	OrderCount int64  `json:"order_count"`
+}
+
+// CustomerMerge adalah catatan audit penggabungan pelanggan ganda. Data pelanggan yang dihapus
+// disimpan sebagai snapshot JSON agar penggabungan bisa ditelusuri atau dibetulkan manual.
+type CustomerMerge struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	OutletID          uint      `gorm:"index;not null" json:"outlet_id"`
+	SurvivorID        uint      `gorm:"index;not null" json:"survivor_id"`
+	MergedIDs         string    `gorm:"type:text" json:"merged_ids"`       // JSON array ID pelanggan yang digabung
+	SurvivorBefore    string    `gorm:"type:text" json:"survivor_before"`  // JSON Customer sebelum digabung
+	MergedCustomers   string    `gorm:"type:text" json:"merged_customers"` // JSON []Customer yang dihapus
+	TransactionsMoved int64     `json:"transactions_moved"`
+	NotasUpdated      int64     `json:"notas_updated"` // NotaData pesanan yang nama/nomor HP pelanggannya disesuaikan
+	Notes             string    `gorm:"type:text" json:"notes"`
+	MergedBy          string    `gorm:"type:varchar(100)" json:"merged_by"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func (CustomerMerge) TableName() string {
+	return "customer_merges"
+}
+
+// CustomerMergeInput dipakai /customers/merge dan /customers/merge/preview. Field yang diisi
+// menimpa data pelanggan yang dipertahankan, kosong berarti data survivor tetap dipakai.
+type CustomerMergeInput struct {
+	SurvivorID uint    `json:"survivor_id" binding:"required"`
+	MergedIDs  []uint  `json:"merged_ids" binding:"required,min=1"`
+	Name       *string `json:"name"`
+	Phone      *string `json:"phone"`
+	Gender     *string `json:"gender"`
+	Address    *string `json:"address"`
+	Notes      string  `json:"notes"`
+}
+
+// CustomerMergeConflict adalah field yang nilainya berbeda antar pelanggan yang akan digabung
+type CustomerMergeConflict struct {
+	Field  string                       `json:"field"`
+	Values []CustomerMergeConflictValue `json:"values"`
+}
+
+type CustomerMergeConflictValue struct {
+	CustomerID uint   `json:"customer_id"`
+	Value      string `json:"value"`
+}
+
+type CustomerMergePreview struct {
+	Survivor          Customer                `json:"survivor"`
+	Merged            []Customer              `json:"merged"`
+	Result            Customer                `json:"result"` // Data survivor setelah penggabungan
+	Conflicts         []CustomerMergeConflict `json:"conflicts"`
+	TransactionCounts map[uint]int64          `json:"transaction_counts"`
+	TransactionsMoved int64                   `json:"transactions_moved"`
+	NotasUpdated      int64                   `json:"notas_updated"`
+}
+
+// CustomerImportResult adalah laporan import pelanggan dari Excel. Pada dry run tidak ada data yang disimpan
+// dan Created berisi jumlah pelanggan yang akan dibuat.
+type CustomerImportResult struct {
+	DryRun    bool                     `json:"dry_run"`
+	TotalRows int                      `json:"total_rows"` // Baris berisi data, baris kosong tidak dihitung
+	Created   int                      `json:"created"`
+	Skipped   []CustomerImportRowError `json:"skipped"` // Nomor HP sudah terdaftar atau muncul lebih dari sekali di file
+	Errors    []CustomerImportRowError `json:"errors"`  // Baris yang tidak valid
+}
+
+type CustomerImportRowError struct {
+	Row                int    `json:"row"` // Nomor baris di Excel, baris 1 adalah header
+	Name               string `json:"name"`
+	Phone              string `json:"phone"`
+	Reason             string `json:"reason"`
+	ExistingCustomerID *uint  `json:"existing_customer_id,omitempty"`
+}
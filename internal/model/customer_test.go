@@ -0,0 +1,48 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+	}{
+		{"0812-3456-789", "628123456789"},
+		{"+62 812 3456 789", "628123456789"},
+		{"62 812 3456 789", "628123456789"},
+		{"(0812) 3456.789", "628123456789"},
+		{"8123456789", "628123456789"}, // Angka di Excel kehilangan 0 di depan
+		{"+65 9123 4567", "6591234567"},
+		{"", ""},
+		{"tidak ada", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizePhone(tt.phone); got != tt.want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", tt.phone, got, tt.want)
+		}
+	}
+}
+
+func TestCustomerSummaryHistoryJSON(t *testing.T) {
+	summary := CustomerSummary{
+		Customer: Customer{ID: 1, Name: "Budi"},
+		History:  []Transaction{{ID: 10, CustomerID: 1}},
+	}
+	body, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		History []map[string]json.RawMessage `json:"history"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.History[0]["customer"]; ok {
+		t.Errorf("riwayat pesanan masih memuat customer: %s", decoded.History[0]["customer"])
+	}
+}
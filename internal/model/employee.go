@@ -4,15 +4,17 @@ import (
 	"time"
 )
 
+// Employee adalah model staf lama (tabel employees) di balik /employees.
+// Deprecated: data sudah dipindahkan ke Karyawan; struct ini hanya dipakai migrasi data lama.
 type Employee struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	OutletID  uint      `json:"outlet_id"`
-	Name      string    `json:"name"`
-	Phone     string    `json:"phone"`
-	Email     string    `gorm:"unique" json:"email"`
-	Password  string    `json:"-"` // Password disembunyikan dari JSON
-	Role      string    `json:"role"` // Owner / Pegawai
-	
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	OutletID uint   `json:"outlet_id"`
+	Name     string `json:"name"`
+	Phone    string `json:"phone"`
+	Email    string `gorm:"unique" json:"email"`
+	Password string `json:"-"`    // Password disembunyikan dari JSON
+	Role     string `json:"role"` // Owner / Pegawai
+
 	// Permissions (Boolean Columns)
 	PermMakeOrder         bool `json:"perm_make_order"`
 	PermCancelOrder       bool `json:"perm_cancel_order"`
@@ -27,4 +29,88 @@ type Employee struct {
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-}
\ No newline at end of file
+}
+
+// legacyEmployeePermissions memetakan key permission lama (/employees) ke AvailablePermissions.
+// Beberapa key lama berbagi satu permission, jadi pemetaan balik menyalakan semua key tersebut.
+var legacyEmployeePermissions = []struct {
+	Key        string
+	Permission string
+}{
+	{"make_order", PermissionMembuatOrder},
+	{"cancel_order", PermissionBatalkanOrder},
+	{"manage_expenses", PermissionLayananKeuangan},
+	{"manage_services", PermissionLayananProduk},
+	{"view_revenue", PermissionNilaiOmzet},
+	{"manage_employees", PermissionDataKaryawan},
+	{"report_transaction", PermissionLayananTransaksi},
+	{"report_performance", PermissionNilaiOmzet},
+	{"report_finance", PermissionLayananKeuangan},
+	{"report_customer", PermissionLayananPelanggan},
+}
+
+// LegacyPermissionsToKaryawan mengubah map permission lama menjadi daftar permission Karyawan
+func LegacyPermissionsToKaryawan(legacy map[string]bool) []string {
+	permissions := []string{}
+	seen := map[string]bool{}
+	for _, m := range legacyEmployeePermissions {
+		if legacy[m.Key] && !seen[m.Permission] {
+			seen[m.Permission] = true
+			permissions = append(permissions, m.Permission)
+		}
+	}
+	return permissions
+}
+
+// KaryawanPermissionsToLegacy mengubah daftar permission Karyawan menjadi map permission lama
+func KaryawanPermissionsToLegacy(permissions []string) map[string]bool {
+	has := map[string]bool{}
+	for _, p := range permissions {
+		has[p] = true
+	}
+
+	legacy := map[string]bool{}
+	for _, m := range legacyEmployeePermissions {
+		legacy[m.Key] = has[m.Permission]
+	}
+	return legacy
+}
+
+// LegacyPermissions membaca kolom Perm* milik Employee sebagai map permission lama
+func (e *Employee) LegacyPermissions() map[string]bool {
+	return map[string]bool{
+		"make_order":         e.PermMakeOrder,
+		"cancel_order":       e.PermCancelOrder,
+		"manage_expenses":    e.PermManageExpenses,
+		"manage_services":    e.PermManageServices,
+		"view_revenue":       e.PermViewRevenue,
+		"manage_employees":   e.PermManageEmployees,
+		"report_transaction": e.PermReportTransaction,
+		"report_performance": e.PermReportPerformance,
+		"report_finance":     e.PermReportFinance,
+		"report_customer":    e.PermReportCustomer,
+	}
+}
+
+// LegacyEmployeeResponse adalah bentuk respons /employees yang dipakai aplikasi lama
+type LegacyEmployeeResponse struct {
+	ID          uint            `json:"id"`
+	Name        string          `json:"name"`
+	Phone       string          `json:"phone"`
+	Email       string          `json:"email"`
+	Role        string          `json:"role"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Permissions map[string]bool `json:"permissions"`
+}
+
+func (k *Karyawan) ToLegacyEmployee() LegacyEmployeeResponse {
+	return LegacyEmployeeResponse{
+		ID:          k.ID,
+		Name:        k.Nama,
+		Phone:       k.Phone,
+		Email:       k.Email,
+		Role:        k.Role,
+		CreatedAt:   k.CreatedAt,
+		Permissions: KaryawanPermissionsToLegacy(k.PermissionList()),
+	}
+}
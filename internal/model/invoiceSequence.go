@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Periode reset nomor urut invoice
+const (
+	InvoiceResetDaily   = "daily"
+	InvoiceResetMonthly = "monthly"
+	InvoiceResetYearly  = "yearly"
+
+	DefaultInvoiceFormat = "{OUTLET}/{YYMM}/{SEQ:5}"
+)
+
+// InvoiceSequence menyimpan nomor urut terakhir per outlet per periode.
+// Baris dikunci (SELECT ... FOR UPDATE) saat mengambil nomor berikutnya agar tidak ada duplikat maupun lompatan.
+type InvoiceSequence struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	OutletID   uint      `json:"outlet_id" gorm:"not null;uniqueIndex:idx_invoice_seq_outlet_period"`
+	Period     string    `json:"period" gorm:"type:varchar(8);not null;uniqueIndex:idx_invoice_seq_outlet_period"` // 20261017 / 202610 / 2026
+	LastNumber int64     `json:"last_number" gorm:"not null;default:0"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (InvoiceSequence) TableName() string {
+	return "invoice_sequences"
+}
@@ -62,10 +62,7 @@ type KaryawanResponse struct {
 }
 
 func (k *Karyawan) ToResponse() KaryawanResponse {
-    permissions := []string{}
-    if k.Permissions != "" {
-        json.Unmarshal([]byte(k.Permissions), &permissions)
-    }
+    permissions := k.PermissionList()
 
     return KaryawanResponse{
         ID:          k.ID,
@@ -97,15 +94,49 @@ func (k *Karyawan) CheckPassword(password string) bool {
     return err == nil
 }
 
+const (
+    PermissionMembuatOrder      = "Membuat Order / Transaksi"
+    PermissionMenambahkanOrder  = "Menambahkan Order / Transaksi"
+    PermissionPengaturan        = "Membuat Pengaturan"
+    PermissionLayananProduk     = "Mengelola Layanan / Produk"
+    PermissionNilaiOmzet        = "Menampilkan Nilai Omzet"
+    PermissionDataKaryawan      = "Mengelola Data Karyawan"
+    PermissionLayananTransaksi  = "Akses Layanan Transaksi"
+    PermissionLayananKonsep     = "Akses Layanan Konsep"
+    PermissionLayananKeuangan   = "Akses Layanan Keuangan"
+    PermissionLayananPelanggan  = "Akses Layanan Pelanggan"
+    PermissionBatalkanOrder     = "Membatalkan Order / Transaksi"
+)
+
 var AvailablePermissions = []string{
-    "Membuat Order / Transaksi",
-    "Menambahkan Order / Transaksi",
-    "Membuat Pengaturan",
-    "Mengelola Layanan / Produk",
-    "Menampilkan Nilai Omzet",
-    "Mengelola Data Karyawan",
-    "Akses Layanan Transaksi",
-    "Akses Layanan Konsep",
-    "Akses Layanan Keuangan",
-    "Akses Layanan Pelanggan",
+    PermissionMembuatOrder,
+    PermissionMenambahkanOrder,
+    PermissionPengaturan,
+    PermissionLayananProduk,
+    PermissionNilaiOmzet,
+    PermissionDataKaryawan,
+    PermissionLayananTransaksi,
+    PermissionLayananKonsep,
+    PermissionLayananKeuangan,
+    PermissionLayananPelanggan,
+    PermissionBatalkanOrder,
+}
+
+// PermissionList mengurai kolom kar_permissions (JSON array)
+func (k *Karyawan) PermissionList() []string {
+    permissions := []string{}
+    if k.Permissions != "" {
+        json.Unmarshal([]byte(k.Permissions), &permissions)
+    }
+    return permissions
+}
+
+// HasPermission mengecek apakah karyawan punya permission tertentu
+func (k *Karyawan) HasPermission(permission string) bool {
+    for _, p := range k.PermissionList() {
+        if p == permission {
+            return true
+        }
+    }
+    return false
 }
\ No newline at end of file
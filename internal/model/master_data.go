@@ -2,6 +2,8 @@ package model
 
 import "time"
 
+// Parfume adalah model parfum lama (tabel parfumes) di balik /master/parfums.
+// Deprecated: data sudah dipindahkan ke Parfum; struct ini dipakai migrasi dan sebagai bentuk respons endpoint lama.
 type Parfume struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	OutletID    uint      `json:"outlet_id"`
@@ -10,6 +12,8 @@ type Parfume struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// Discount adalah model diskon lama (tabel discounts) di balik /master/discounts.
+// Deprecated: data sudah dipindahkan ke Diskon; struct ini dipakai migrasi dan sebagai bentuk respons endpoint lama.
 type Discount struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	OutletID    uint      `json:"outlet_id"`
@@ -19,4 +23,76 @@ type Discount struct {
 	Description string    `json:"description"`
 	IsActive    bool      `gorm:"default:true" json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
-}
\ No newline at end of file
+}
+
+// ToLegacy mengubah Parfum ke bentuk Parfume untuk endpoint /master/parfums
+func (p *Parfum) ToLegacy() Parfume {
+	legacy := Parfume{
+		ID:          p.ID,
+		Name:        p.Parfum,
+		Description: p.Keterangan,
+		CreatedAt:   p.CreatedAt,
+	}
+	if p.OutletID != nil {
+		legacy.OutletID = *p.OutletID
+	}
+	return legacy
+}
+
+// ToLegacy mengubah Diskon ke bentuk Discount untuk endpoint /master/discounts
+func (d *Diskon) ToLegacy() Discount {
+	legacy := Discount{
+		ID:          d.ID,
+		Name:        d.Diskon,
+		Type:        d.Jenis,
+		Value:       d.NilaiDiskon,
+		Description: d.Keterangan,
+		IsActive:    d.Status == "Aktif",
+		CreatedAt:   d.CreatedAt,
+	}
+	if d.OutletID != nil {
+		legacy.OutletID = *d.OutletID
+	}
+	return legacy
+}
+
+// NormalizeDiskonJenis memetakan tipe diskon lama ke Jenis Diskon (Nominal / Persen)
+func NormalizeDiskonJenis(value string) string {
+	switch value {
+	case "Persen", "persen", "percent", "Percent", "%":
+		return "Persen"
+	}
+	return "Nominal"
+}
+
+// DiskonStatus mengubah flag is_active lama ke Status Diskon
+func DiskonStatus(isActive bool) string {
+	if isActive {
+		return "Aktif"
+	}
+	return "Tidak Aktif"
+}
+
+// LegacyIDMap mencatat ID lama yang sudah dimigrasikan ke model kanonik agar migrasi tidak diulang
+type LegacyIDMap struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Entity      string    `gorm:"type:varchar(30);uniqueIndex:idx_legacy_entity_id;not null" json:"entity"` // employee / parfume / discount / cutover
+	LegacyID    uint      `gorm:"uniqueIndex:idx_legacy_entity_id;not null" json:"legacy_id"`
+	CanonicalID uint      `gorm:"not null" json:"canonical_id"` // 0 jika baris lama dilewati (tidak dimigrasikan)
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (LegacyIDMap) TableName() string {
+	return "legacy_id_maps"
+}
+
+const (
+	LegacyEntityEmployee = "employee"
+	LegacyEntityParfume  = "parfume"
+	LegacyEntityDiscount = "discount"
+	// LegacyEntityCutover adalah satu baris penanda; CreatedAt-nya waktu migrasi pertama kali berjalan.
+	// Transaksi yang dibuat setelahnya sudah mengacu ke Parfum/Diskon sehingga tidak ikut dipetakan ulang.
+	LegacyEntityCutover = "cutover"
+	// LegacyEntityVoidPermission menandai PermissionBatalkanOrder sudah diberikan ke karyawan lama (sekali jalan)
+	LegacyEntityVoidPermission = "void_permission"
+)
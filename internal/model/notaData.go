@@ -18,6 +18,8 @@ type NotaData struct {
     ID              uint           `json:"id" gorm:"primaryKey"`
     OutletID        uint           `json:"outlet_id" gorm:"not null;index"`
     TransactionID   string         `json:"transaction_id" gorm:"type:varchar(100);uniqueIndex"`
+    TransactionRefID *uint         `json:"transaction_ref_id" gorm:"uniqueIndex"` // FK ke Transaction jika nota dibuat dari pesanan
+    PublicToken     *string        `json:"-" gorm:"type:varchar(64);uniqueIndex"`   // Token acak untuk link nota publik
     TransactionDate time.Time      `json:"transaction_date" gorm:"not null"`
     CustomerName    string         `json:"customer_name" gorm:"type:varchar(255)"`
     CustomerPhone   string         `json:"customer_phone" gorm:"type:varchar(20)"`
@@ -33,6 +35,8 @@ type NotaData struct {
     Total           float64        `json:"total" gorm:"type:decimal(15,2);not null"`
     PaymentAmount   float64        `json:"payment_amount" gorm:"type:decimal(15,2)"`
     Change          float64        `json:"change_amount" gorm:"type:decimal(15,2);default:0"`
+    Outstanding     float64        `json:"outstanding" gorm:"type:decimal(15,2);default:0"` // Sisa tagihan jika baru DP
+    PaymentStatus   string         `json:"payment_status" gorm:"type:varchar(20)"`          // Belum Bayar / DP / Lunas
     PaymentMethod   string         `json:"payment_method" gorm:"type:varchar(50)"` 
     
     // Additional Info
@@ -46,6 +50,7 @@ type NotaData struct {
     UpdatedAt       time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
     DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
     Outlet          Outlet         `json:"outlet,omitempty" gorm:"foreignKey:OutletID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+    Transaction     *Transaction   `json:"-" gorm:"foreignKey:TransactionRefID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
     NotaSettings    *NotaSettings  `json:"nota_settings,omitempty" gorm:"-"` 
 }
 
@@ -76,7 +81,7 @@ func (NotaItemDetail) TableName() string {
 
 type NotaGenerateInput struct {
     OutletID        uint       `json:"outlet_id" validate:"required"`
-    TransactionID   string     `json:"transaction_id" validate:"required,max=100"`
+    TransactionID   string     `json:"transaction_id" validate:"omitempty,max=100"` // Kosongkan agar dibuat otomatis dari nomor urut outlet
     TransactionDate time.Time  `json:"transaction_date" validate:"required"`
     CustomerName    string     `json:"customer_name" validate:"omitempty,max=255"`
     CustomerPhone   string     `json:"customer_phone" validate:"omitempty,max=20"`
@@ -87,14 +92,15 @@ type NotaGenerateInput struct {
     Discount        float64    `json:"discount" validate:"gte=0"`
     DiscountType    string     `json:"discount_type" validate:"omitempty,oneof=percentage fixed"`
     ServiceCharge   float64    `json:"service_charge" validate:"gte=0"`
-    PaymentAmount   float64    `json:"payment_amount" validate:"required,gt=0"`
+    PaymentAmount   float64    `json:"payment_amount" validate:"gte=0"` // Boleh kurang dari total (DP)
     PaymentMethod   string     `json:"payment_method" validate:"required,oneof=cash card qris transfer ewallet"`
     Notes           string     `json:"notes" validate:"omitempty"`
 }
 
 type NotaPrintInput struct {
-    NotaDataID uint `json:"nota_data_id" validate:"required"`
-    Reprint    bool `json:"reprint"`
+    NotaDataID uint   `json:"nota_data_id" validate:"required"`
+    Reprint    bool   `json:"reprint"`
+    Format     string `json:"format" validate:"omitempty,oneof=text escpos"` // Default text
 }
 
 type NotaPreviewInput struct {
@@ -113,6 +119,7 @@ type NotaPrintResponse struct {
     Message   string `json:"message"`
     PrintData string `json:"print_data,omitempty"` 
     Format    string `json:"format,omitempty"`     
+    Encoding  string `json:"encoding,omitempty"` // "base64" untuk format escpos
 }
 
 type NotaPreviewResponse struct {
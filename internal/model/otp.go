@@ -12,6 +12,7 @@ type OTP struct {
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
 	IsUsed    bool      `json:"is_used" gorm:"default:false"`
 	Purpose   string    `json:"purpose" gorm:"type:varchar(50);not null"` 
+	Attempts  int       `json:"-" gorm:"default:0"` // Jumlah verifikasi salah untuk kode ini
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 }
 
@@ -19,9 +20,26 @@ func (OTP) TableName() string {
 	return "otps"
 }
 
+// OTPSendLog mencatat setiap permintaan kirim OTP untuk kuota per nomor HP dan per IP.
+// Disimpan di database agar batas tetap berlaku setelah server restart.
+type OTPSendLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	NomorHP   string    `json:"nomor_hp" gorm:"index;size:20"`
+	IPAddress string    `json:"ip_address" gorm:"index;size:45"`
+	Purpose   string    `json:"purpose" gorm:"type:varchar(50)"`
+	Channel   string    `json:"channel" gorm:"type:varchar(20)"`
+	Sent      bool      `json:"sent" gorm:"default:false"` // false jika nomor tidak terdaftar atau pengiriman gagal
+	CreatedAt time.Time `json:"created_at" gorm:"index;autoCreateTime"`
+}
+
+func (OTPSendLog) TableName() string {
+	return "otp_send_logs"
+}
+
 
 type ForgotPasswordInput struct {
-	NomorHP string `json:"nomor_hp" validate:"required,min=10,max=15"`
+	NomorHP string `json:"nomor_hp" binding:"required" validate:"required,min=10,max=15"`
+	Channel string `json:"channel"` // whatsapp, sms atau email; kosong = urutan fallback dari config
 }
 
 
@@ -38,6 +38,13 @@ type UpdatePengeluaranInput struct {
     Status                string `json:"pengeluaran_status" binding:"omitempty,oneof=Aktif 'Tidak Aktif'"`
 }
 
+type PengeluaranFilter struct {
+    StartDate  string `form:"start_date"` // Format 2006-01-02, inklusif
+    EndDate    string `form:"end_date"`   // Format 2006-01-02, inklusif
+    KategoriID *uint  `form:"kategori_id"`
+    Status     string `form:"status" binding:"omitempty,oneof=Aktif 'Tidak Aktif'"`
+}
+
 type PengeluaranResponse struct {
     ID                    uint      `json:"pengeluaran_id"`
     OutletID              *uint     `json:"pengeluaran_outlet,omitempty"`
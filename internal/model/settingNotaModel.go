@@ -21,6 +21,8 @@ type NotaSettings struct {
     ShowWhatsappFooter bool           `json:"show_whatsapp_footer" gorm:"default:true"`
     PrinterSize        int            `json:"printer_size" gorm:"default:58"`
     PrinterType        string         `json:"printer_type" gorm:"type:varchar(1);default:'A'"`
+    InvoiceFormat      string         `json:"invoice_format" gorm:"type:varchar(100);default:'{OUTLET}/{YYMM}/{SEQ:5}'"`
+    InvoiceReset       string         `json:"invoice_reset" gorm:"type:varchar(10);default:'monthly'"`
     CreatedAt          time.Time      `json:"created_at" gorm:"autoCreateTime"`
     UpdatedAt          time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
     DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
@@ -46,6 +48,8 @@ type NotaSettingsInput struct {
     ShowWhatsappFooter bool   `json:"show_whatsapp_footer"`
     PrinterSize        int    `json:"printer_size" validate:"required,oneof=58 80"`
     PrinterType        string `json:"printer_type" validate:"required,oneof=A B"`
+    InvoiceFormat      string `json:"invoice_format" validate:"omitempty,max=100"`
+    InvoiceReset       string `json:"invoice_reset" validate:"omitempty,oneof=daily monthly yearly"`
 }
 
 type NotaSettingsResponse struct {
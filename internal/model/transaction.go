@@ -2,32 +2,77 @@ package model
 
 import "time"
 
+// Status pesanan (OrderStatus)
+const (
+	OrderStatusAntrian   = "Antrian"
+	OrderStatusProses    = "Proses"
+	OrderStatusSiapAmbil = "Siap Ambil"
+	OrderStatusSelesai   = "Selesai"
+	OrderStatusBatal     = "Batal" // Pesanan di-void, tidak bisa diproses lagi
+)
+
+// orderStatusFlow memetakan status saat ini ke satu-satunya status berikutnya yang diizinkan
+var orderStatusFlow = map[string]string{
+	OrderStatusAntrian:   OrderStatusProses,
+	OrderStatusProses:    OrderStatusSiapAmbil,
+	OrderStatusSiapAmbil: OrderStatusSelesai,
+}
+
 // Transaction Header
 type Transaction struct {
-	ID            uint                `gorm:"primaryKey" json:"id"`
-	InvoiceNumber string              `gorm:"unique;not null" json:"invoice_number"` // Contoh: TRX/251029001
-	OutletID      uint                `json:"outlet_id"`
-	CustomerID    uint                `json:"customer_id"`
-	Customer      Customer            `gorm:"foreignKey:CustomerID" json:"customer"`
-	ParfumID      uint                `json:"parfum_id"`
-	DiscountID    *uint               `json:"discount_id"` // Pointer agar bisa null
-	TotalPrice    float64             `json:"total_price"`
-	PaymentStatus string              `gorm:"default:'Belum Bayar'" json:"payment_status"` // Belum Bayar / Lunas
-	OrderStatus   string              `gorm:"default:'Antrian'" json:"order_status"`     // Antrian / Proses / Siap Ambil / Selesai
-	Notes         string              `json:"notes"`
-	Items         []TransactionDetail `gorm:"foreignKey:TransactionID" json:"items"`
-	Logs          []OrderLog          `gorm:"foreignKey:TransactionID" json:"logs"`
-	CreatedAt     time.Time           `json:"created_at"`
-	UpdatedAt     time.Time           `json:"updated_at"`
+	ID               uint                 `gorm:"primaryKey" json:"id"`
+	InvoiceNumber    string               `gorm:"unique;not null" json:"invoice_number"` // Contoh: TRX/251029001
+	OutletID         uint                 `json:"outlet_id"`
+	CustomerID       uint                 `json:"customer_id"`
+	Customer         *Customer            `gorm:"foreignKey:CustomerID" json:"customer,omitempty"` // Hanya terisi jika di-Preload
+	ParfumID         uint                 `json:"parfum_id"`
+	DiscountID       *uint                `json:"discount_id"`    // Pointer agar bisa null
+	Subtotal         float64              `json:"subtotal"`       // Sebelum diskon
+	DiscountTotal    float64              `json:"discount_total"` // Potongan dari Diskon
+	TotalPrice       float64              `json:"total_price"`
+	PaidAmount       float64              `json:"paid_amount"`                                 // Akumulasi dari TransactionPayment
+	PaymentStatus    string               `gorm:"default:'Belum Bayar'" json:"payment_status"` // Belum Bayar / DP / Lunas
+	OrderStatus      string               `gorm:"default:'Antrian'" json:"order_status"`       // Antrian / Proses / Siap Ambil / Selesai
+	Notes            string               `json:"notes"`
+	VoidReason       string               `gorm:"type:text" json:"void_reason,omitempty"`
+	VoidedAt         *time.Time           `json:"voided_at,omitempty"`
+	TrackingToken    *string              `gorm:"type:varchar(64);uniqueIndex" json:"-"` // Token acak untuk link tracking publik
+	EstimatedReadyAt *time.Time           `json:"estimated_ready_at"`                    // Dari LamaPengerjaan terlama di antara item
+	Items            []TransactionDetail  `gorm:"foreignKey:TransactionID" json:"items"`
+	Logs             []OrderLog           `gorm:"foreignKey:TransactionID" json:"logs"`
+	Payments         []TransactionPayment `gorm:"foreignKey:TransactionID" json:"payments"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+}
+
+// NextOrderStatus mengembalikan status berikutnya, kosong jika pesanan sudah Selesai
+func (t *Transaction) NextOrderStatus() string {
+	return orderStatusFlow[t.OrderStatus]
+}
+
+// CanTransitionTo mengecek apakah perpindahan status tidak melompati tahapan
+func (t *Transaction) CanTransitionTo(status string) bool {
+	next, ok := orderStatusFlow[t.OrderStatus]
+	return ok && next == status
+}
+
+// Outstanding mengembalikan sisa tagihan yang belum dibayar
+func (t *Transaction) Outstanding() float64 {
+	if t.PaidAmount >= t.TotalPrice {
+		return 0
+	}
+	return t.TotalPrice - t.PaidAmount
 }
 
 // Transaction Detail (Satu baris per layanan)
 type TransactionDetail struct {
 	ID            uint    `gorm:"primaryKey" json:"id"`
 	TransactionID uint    `json:"transaction_id"`
+	JenisProdukID *uint   `json:"jenis_produk_id"`
 	ServiceName   string  `json:"service_name"` // Simpan nama saat transaksi
-	Price         float64 `json:"price"`
-	Qty           float64 `json:"qty"` // Mendukung desimal (Kg)
+	Unit          string  `json:"unit"`         // Snapshot JenisProduk.Satuan
+	Price         float64 `json:"price"`        // Snapshot JenisProduk.HargaPer
+	Qty           float64 `json:"qty"`          // Mendukung desimal (Kg)
 	Subtotal      float64 `json:"subtotal"`
 }
 
@@ -37,5 +82,57 @@ type OrderLog struct {
 	TransactionID uint      `json:"transaction_id"`
 	Status        string    `json:"status"`
 	AdminName     string    `json:"admin_name"`
+	Reason        string    `gorm:"type:text" json:"reason,omitempty"`
 	CreatedAt     time.Time `json:"created_at"`
-}
\ No newline at end of file
+}
+
+type CreateTransactionInput struct {
+	CustomerID uint                         `json:"customer_id" binding:"required"`
+	ParfumID   uint                         `json:"parfum_id"`
+	DiscountID *uint                        `json:"discount_id"`
+	TotalPrice *float64                     `json:"total_price"` // Opsional, hanya untuk dicocokkan dengan hitungan server
+	Notes      string                       `json:"notes"`
+	Items      []CreateTransactionItemInput `json:"items" binding:"required,min=1,dive"`
+	Payment    *AddPaymentInput             `json:"payment"` // Opsional, DP atau pembayaran penuh saat drop-off
+}
+
+type CreateTransactionItemInput struct {
+	JenisProdukID uint    `json:"jenis_produk_id" binding:"required"`
+	Qty           float64 `json:"qty" binding:"required,gt=0"`
+}
+
+type VoidTransactionInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type UpdateOrderStatusInput struct {
+	Status string `json:"status" binding:"required,oneof=Antrian Proses 'Siap Ambil' Selesai"`
+	Reason string `json:"reason"`
+}
+
+// TransactionTracking adalah data pesanan yang boleh dilihat pelanggan lewat link tracking publik.
+// Sengaja tidak memuat data pelanggan, nama karyawan maupun ID internal.
+type TransactionTracking struct {
+	InvoiceNumber    string                     `json:"invoice_number"`
+	OutletName       string                     `json:"outlet_name"`
+	Items            []TransactionTrackingItem  `json:"items"`
+	OrderStatus      string                     `json:"order_status"`
+	Timeline         []TransactionTrackingEvent `json:"timeline"`
+	EstimatedReadyAt *time.Time                 `json:"estimated_ready_at"`
+	PaymentStatus    string                     `json:"payment_status"`
+	TotalPrice       float64                    `json:"total_price"`
+	Outstanding      float64                    `json:"outstanding"`
+	CreatedAt        time.Time                  `json:"created_at"`
+}
+
+type TransactionTrackingItem struct {
+	ServiceName string  `json:"service_name"`
+	Qty         float64 `json:"qty"`
+	Unit        string  `json:"unit"`
+	Subtotal    float64 `json:"subtotal"`
+}
+
+type TransactionTrackingEvent struct {
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,67 @@
+package model
+
+import "time"
+
+// Status pembayaran (PaymentStatus), diturunkan dari total pembayaran terhadap TotalPrice
+const (
+	PaymentStatusBelumBayar = "Belum Bayar"
+	PaymentStatusDP         = "DP"
+	PaymentStatusLunas      = "Lunas"
+)
+
+// DerivePaymentStatus menentukan status pembayaran dari jumlah yang sudah dibayar
+func DerivePaymentStatus(paid, total float64) string {
+	switch {
+	case paid <= 0 && total > 0:
+		return PaymentStatusBelumBayar
+	case paid < total:
+		return PaymentStatusDP
+	default:
+		return PaymentStatusLunas
+	}
+}
+
+// TransactionPayment adalah satu baris ledger pembayaran (DP, cicilan, pelunasan)
+type TransactionPayment struct {
+	ID              uint          `gorm:"primaryKey" json:"id"`
+	TransactionID   uint          `gorm:"index;not null" json:"transaction_id"`
+	OutletID        uint          `gorm:"index;not null" json:"outlet_id"`
+	PaymentMethodID uint          `gorm:"not null" json:"payment_method_id"`
+	PaymentMethod   PaymentMethod `gorm:"foreignKey:PaymentMethodID" json:"payment_method"`
+	Amount          float64       `gorm:"type:decimal(15,2);not null" json:"amount"`
+	Notes           string        `json:"notes"`
+	ReceivedBy      string        `json:"received_by"`
+	CreatedAt       time.Time     `json:"created_at"`
+}
+
+func (TransactionPayment) TableName() string {
+	return "transaction_payments"
+}
+
+type AddPaymentInput struct {
+	PaymentMethodID uint    `json:"payment_method_id" binding:"required"`
+	Amount          float64 `json:"amount" binding:"required,gt=0"`
+	Notes           string  `json:"notes"`
+}
+
+// Receivable adalah satu transaksi yang masih punya sisa tagihan
+type Receivable struct {
+	TransactionID uint      `json:"transaction_id"`
+	OutletID      uint      `json:"outlet_id"`
+	InvoiceNumber string    `json:"invoice_number"`
+	CustomerID    uint      `json:"customer_id"`
+	CustomerName  string    `json:"customer_name"`
+	CustomerPhone string    `json:"customer_phone"`
+	TotalPrice    float64   `json:"total_price"`
+	PaidAmount    float64   `json:"paid_amount"`
+	Outstanding   float64   `json:"outstanding"`
+	PaymentStatus string    `json:"payment_status"`
+	OrderStatus   string    `json:"order_status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ReceivableSummary struct {
+	TotalOutstanding float64      `json:"total_outstanding"`
+	Count            int          `json:"count"`
+	Items            []Receivable `json:"items"`
+}
@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// UserTwoFactor menyimpan secret TOTP (RFC 6238) milik owner. Secret disimpan terenkripsi
+// dan baru berlaku setelah kode pertama diverifikasi (Enabled).
+type UserTwoFactor struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"uniqueIndex;not null" json:"user_id"`
+	Secret       string     `gorm:"size:255;not null" json:"-"`
+	Enabled      bool       `gorm:"default:false" json:"enabled"`
+	EnabledAt    *time.Time `json:"enabled_at"`
+	LastUsedStep int64      `gorm:"default:0" json:"-"` // Time step terakhir yang dipakai agar kode yang sama tidak bisa dipakai ulang
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (UserTwoFactor) TableName() string {
+	return "user_two_factors"
+}
+
+// TwoFactorRecoveryCode adalah kode cadangan sekali pakai, hanya hash-nya yang disimpan
+type TwoFactorRecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;index;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (TwoFactorRecoveryCode) TableName() string {
+	return "two_factor_recovery_codes"
+}
+
+type TwoFactorCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorLoginInput adalah langkah kedua login; isi code (TOTP) atau recovery_code
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"` // PNG base64 (data URI)
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+	RemainingRecoveryCodes int64      `json:"remaining_recovery_codes"`
+}
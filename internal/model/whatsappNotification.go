@@ -0,0 +1,81 @@
+package model
+
+import "time"
+
+// Jenis notifikasi WhatsApp ke pelanggan
+const (
+	WhatsappEventOrderCreated   = "order_created"
+	WhatsappEventOrderReady     = "order_ready"
+	WhatsappEventPickupReminder = "pickup_reminder"
+)
+
+// Status pengiriman pesan WhatsApp
+const (
+	WhatsappStatusPending   = "pending"
+	WhatsappStatusSent      = "sent"
+	WhatsappStatusDelivered = "delivered"
+	WhatsappStatusRead      = "read"
+	WhatsappStatusFailed    = "failed"
+)
+
+// WhatsappTemplate adalah isi pesan per outlet per jenis notifikasi.
+// Placeholder: {nama}, {invoice}, {total}, {sisa}, {status}, {link}, {outlet}
+type WhatsappTemplate struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	OutletID   uint      `gorm:"not null;uniqueIndex:idx_wa_template_outlet_event" json:"outlet_id"`
+	Event      string    `gorm:"type:varchar(30);not null;uniqueIndex:idx_wa_template_outlet_event" json:"event"`
+	Body       string    `gorm:"type:text;not null" json:"body"`
+	IsActive   bool      `gorm:"default:true" json:"is_active"`
+	DelayHours int       `gorm:"default:0" json:"delay_hours"` // Khusus pickup_reminder: jeda sejak Siap Ambil / pengingat terakhir
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (WhatsappTemplate) TableName() string {
+	return "whatsapp_templates"
+}
+
+type WhatsappTemplateInput struct {
+	Body       string `json:"body" binding:"required"`
+	IsActive   *bool  `json:"is_active"`
+	DelayHours *int   `json:"delay_hours" binding:"omitempty,min=1"`
+}
+
+// WhatsappMessageLog mencatat setiap pengiriman beserta status deliverynya
+type WhatsappMessageLog struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	OutletID          uint       `gorm:"index;not null" json:"outlet_id"`
+	TransactionID     *uint      `gorm:"index" json:"transaction_id"`
+	CustomerID        *uint      `gorm:"index" json:"customer_id"`
+	Phone             string     `gorm:"type:varchar(20)" json:"phone"`
+	Event             string     `gorm:"type:varchar(30);index" json:"event"`
+	Message           string     `gorm:"type:text" json:"message"`
+	ProviderMessageID string     `gorm:"type:varchar(100);index" json:"provider_message_id"`
+	Status            string     `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	StatusDescription string     `gorm:"type:text" json:"status_description"`
+	SentAt            *time.Time `json:"sent_at"`
+	DeliveredAt       *time.Time `json:"delivered_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (WhatsappMessageLog) TableName() string {
+	return "whatsapp_message_logs"
+}
+
+// InfobipDeliveryReport adalah payload webhook delivery report dari Infobip
+type InfobipDeliveryReport struct {
+	Results []struct {
+		MessageID string `json:"messageId"`
+		DoneAt    string `json:"doneAt"`
+		Status    struct {
+			GroupName   string `json:"groupName"`
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"status"`
+		Error struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"results"`
+}
@@ -6,14 +6,26 @@ import (
 	"BackendFramework/internal/middleware"
 	"BackendFramework/internal/model"
 	"BackendFramework/internal/service"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func InitRoutes(r *gin.RouterGroup) {
+	// Data master dibaca kasir saat membuat order dan menerima pembayaran; mengubahnya tetap butuh permission pengelola
+	readProduk := middleware.RequireAnyPermission(model.PermissionLayananProduk, model.PermissionLayananTransaksi)
+	manageProduk := middleware.RequirePermission(model.PermissionLayananProduk)
+	readPaymentMethod := middleware.RequireAnyPermission(model.PermissionPengaturan, model.PermissionLayananTransaksi)
+	managePaymentMethod := middleware.RequirePermission(model.PermissionPengaturan)
+	// Kasir dengan akses transaksi saja tetap bisa mencari dan menambah pelanggan untuk order baru
+	orderCustomer := middleware.RequireAnyPermission(model.PermissionLayananPelanggan, model.PermissionLayananTransaksi)
+	manageCustomer := middleware.RequirePermission(model.PermissionLayananPelanggan)
+
 	auth := r.Group("/auth")
 	{
 		auth.POST("/login", controller.Login)
+		auth.POST("/karyawan/login", controller.KaryawanLogin) // Login staf outlet
+		auth.POST("/login/2fa", controller.LoginTwoFactor)     // Langkah kedua login owner dengan 2FA
 		auth.POST("/register", controller.RegisterUser)
 		auth.POST("/google/signin", controller.GoogleSignIn)
 		auth.POST("/google/register", controller.GoogleRegister)
@@ -34,33 +46,48 @@ func InitRoutes(r *gin.RouterGroup) {
 		authProtected.GET("/profile", controller.GetProfile)
         authProtected.POST("/update-profile", controller.UpdateProfile)
         authProtected.GET("/logout/:usrId", controller.Logout)
+        authProtected.POST("/logout-all", controller.LogoutAllDevices)
+        authProtected.GET("/sessions", controller.GetActiveSessions)
+        authProtected.DELETE("/sessions/:id", controller.RevokeSession)
+        authProtected.POST("/switch-outlet", controller.SwitchOutlet) // Ganti outlet aktif owner tanpa logout
+        authProtected.GET("/2fa", controller.GetTwoFactorStatus)
+        authProtected.POST("/2fa/setup", controller.SetupTwoFactor)
+        authProtected.POST("/2fa/enable", controller.EnableTwoFactor)
+        authProtected.POST("/2fa/disable", controller.DisableTwoFactor)
+        authProtected.POST("/2fa/recovery-codes", controller.RegenerateTwoFactorRecoveryCodes)
 		
     }
 
 	services := r.Group("/services")
 	{
-		services.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
-		services.GET("", controller.GetServices)
+		services.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.LogUserActivity())
+		services.GET("", readProduk, controller.GetServices)
 		// services.GET("/:id", controller.GetServiceByID)
-		services.POST("", controller.CreateService)
-		services.PUT("/:id", controller.UpdateService)
-		services.DELETE("/:id", controller.DeleteService)
+		services.POST("", manageProduk, controller.CreateService)
+		services.PUT("/:id", manageProduk, controller.UpdateService)
+		services.DELETE("/:id", manageProduk, controller.DeleteService)
 	}
 
 	// Customer Routes
     customers := r.Group("/customers")
     {
         // Pastikan menggunakan middleware yang sama agar outlet_id tersedia di context
-        customers.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
-        customers.GET("", controller.GetCustomers)      // Ambil semua pelanggan
-        customers.POST("", controller.CreateCustomer)    // Tambah pelanggan baru
-        customers.PUT("/:id", controller.UpdateCustomer) // Edit data pelanggan
-        customers.DELETE("/:id", controller.DeleteCustomer) // Hapus pelanggan
+        customers.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.LogUserActivity())
+        customers.GET("", orderCustomer, controller.GetCustomers)      // Ambil semua pelanggan
+        customers.GET("/:id/summary", manageCustomer, controller.GetCustomerSummary) // Profil pelanggan: riwayat, total belanja, sisa tagihan
+        customers.POST("", orderCustomer, controller.CreateCustomer)    // Tambah pelanggan baru
+        customers.POST("/merge/preview", manageCustomer, controller.PreviewCustomerMerge) // Cek field yang berbeda sebelum digabung
+        customers.POST("/merge", manageCustomer, controller.MergeCustomers)               // Gabungkan pelanggan ganda ke satu pelanggan
+        customers.GET("/import/template", manageCustomer, controller.GetCustomerImportTemplate) // Template .xlsx import pelanggan
+        customers.POST("/import", manageCustomer, controller.ImportCustomers)                  // Import pelanggan dari .xlsx (dry_run=true untuk cek saja)
+        customers.GET("/export", manageCustomer, controller.ExportCustomers)                   // Export pelanggan outlet ke .xlsx
+        customers.PUT("/:id", manageCustomer, controller.UpdateCustomer) // Edit data pelanggan
+        customers.DELETE("/:id", manageCustomer, controller.DeleteCustomer) // Hapus pelanggan
     }
 
 	employees := r.Group("/employees")
 	{
-		employees.Use(middleware.JWTAuthMiddleware())
+		employees.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.RequirePermission(model.PermissionDataKaryawan))
 		employees.GET("", controller.GetEmployees)
 		employees.POST("", controller.CreateEmployee)
 		employees.PUT("/:id", controller.UpdateEmployee) // Implementasi Updates mirip Create
@@ -68,31 +95,51 @@ func InitRoutes(r *gin.RouterGroup) {
 	}
 
 	// Master Data Routes
-	master := r.Group("/master").Use(middleware.JWTAuthMiddleware())
+	master := r.Group("/master").Use(middleware.JWTAuthMiddleware(), middleware.OutletScope())
 	{
 		// Parfum
-		master.GET("/parfums", controller.GetParfums)
-		master.POST("/parfums", controller.CreateParfum)
-		master.PUT("/parfums/:id", controller.UpdateParfum)
-		master.DELETE("/parfums/:id", controller.DeleteParfum)
+		master.GET("/parfums", readProduk, controller.GetParfums)
+		master.POST("/parfums", manageProduk, controller.CreateParfum)
+		master.PUT("/parfums/:id", manageProduk, controller.UpdateParfum)
+		master.DELETE("/parfums/:id", manageProduk, controller.DeleteParfum)
 		
 		// Diskon
-		master.GET("/discounts", controller.GetDiscounts)
-		master.POST("/discounts", controller.CreateDiscount)
-		master.PUT("/discounts/:id", controller.UpdateDiscount)
-		master.DELETE("/discounts/:id", controller.DeleteDiscount)
+		master.GET("/discounts", readProduk, controller.GetDiscounts)
+		master.POST("/discounts", manageProduk, controller.CreateDiscount)
+		master.PUT("/discounts/:id", manageProduk, controller.UpdateDiscount)
+		master.DELETE("/discounts/:id", manageProduk, controller.DeleteDiscount)
 	}
 
-	trx := r.Group("/transactions").Use(middleware.JWTAuthMiddleware())
+	trx := r.Group("/transactions").Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.RequirePermission(model.PermissionLayananTransaksi))
 	{
-		trx.POST("", controller.CreateTransaction) // Buat Order Baru
+		trx.POST("", middleware.RequireAnyPermission(model.PermissionMembuatOrder, model.PermissionMenambahkanOrder), controller.CreateTransaction) // Buat Order Baru
 		trx.GET("", controller.GetTransactions)    // List Pesanan
-		// trx.PUT("/:id/status", controller.UpdateStatus) // Nanti untuk pindah status
+		trx.GET("/receivables", middleware.RequirePermission(model.PermissionNilaiOmzet), controller.GetReceivables) // Daftar piutang outlet
+		trx.PUT("/:id/status", controller.UpdateStatus) // Pindah status pesanan
+		trx.POST("/:id/payments", controller.AddPayment) // DP / pelunasan
+		trx.GET("/:id/payments", controller.GetPayments) // Riwayat pembayaran
+		trx.POST("/:id/nota", controller.GenerateTransactionNota) // Nota dari pesanan
+		trx.PATCH("/:id/void", middleware.RequirePermission(model.PermissionBatalkanOrder), controller.VoidTransaction) // Batalkan pesanan + nota
+	}
+
+	whatsappService := service.NewWhatsappNotificationService(database.DbCore)
+	whatsappController := controller.NewWhatsappController(whatsappService)
+
+	whatsapp := r.Group("/whatsapp")
+	{
+		whatsapp.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.RequirePermission(model.PermissionLayananTransaksi), middleware.LogUserActivity())
+		whatsapp.GET("/templates", whatsappController.GetTemplates)
+		whatsapp.PUT("/templates/:event", middleware.RequirePermission(model.PermissionPengaturan), whatsappController.UpdateTemplate)
+		whatsapp.GET("/logs", whatsappController.GetLogs)
+		whatsapp.POST("/transactions/:id/notify", whatsappController.ResendNotification)
 	}
 
+	// Webhook delivery report Infobip (diamankan dengan token di query string)
+	r.POST("/public/whatsapp/delivery-report", whatsappController.DeliveryReport)
+
 	user := r.Group("/user")
 	{
-		user.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
+		user.Use(middleware.JWTAuthMiddleware(), middleware.OwnerOnly(), middleware.LogUserActivity())
 		user.GET("/", controller.GetAllUsers)
 		user.GET("/:id", controller.GetOneUser)
 		user.GET("/search/email", controller.GetUserByEmail)
@@ -106,7 +153,7 @@ func InitRoutes(r *gin.RouterGroup) {
 
 	outlet := r.Group("/outlet")
 	{
-		outlet.Use(middleware.JWTAuthMiddleware())
+		outlet.Use(middleware.JWTAuthMiddleware(), middleware.OwnerOnly())
 
 		outlet.POST("", controller.CreateOutletController)
 		outlet.POST("/create", controller.CreateOutlet)
@@ -129,17 +176,17 @@ func InitRoutes(r *gin.RouterGroup) {
 
 	layanan := r.Group("/layanan")
 	{
-		layanan.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
-		layanan.POST("/with-products", layananController.CreateLayananWithProducts)
-		layanan.PUT("/with-products/:id", layananController.UpdateLayananWithProducts)
+		layanan.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.LogUserActivity())
+		layanan.POST("/with-products", manageProduk, layananController.CreateLayananWithProducts)
+		layanan.PUT("/with-products/:id", manageProduk, layananController.UpdateLayananWithProducts)
 
-		layanan.POST("/simple", layananController.CreateLayananWithProducts)
-		layanan.PUT("/simple/:id", layananController.UpdateLayananWithProducts)
+		layanan.POST("/simple", manageProduk, layananController.CreateLayananWithProducts)
+		layanan.PUT("/simple/:id", manageProduk, layananController.UpdateLayananWithProducts)
 
-		layanan.GET("", layananController.GetAllLayanan)
-		layanan.GET("/:id", layananController.GetLayananByID)
-		layanan.GET("/outlet/:outlet_id", layananController.GetLayananByOutlet)
-		layanan.DELETE("/:id", layananController.DeleteLayanan)
+		layanan.GET("", readProduk, layananController.GetAllLayanan)
+		layanan.GET("/:id", readProduk, layananController.GetLayananByID)
+		layanan.GET("/outlet/:outlet_id", readProduk, layananController.GetLayananByOutlet)
+		layanan.DELETE("/:id", manageProduk, layananController.DeleteLayanan)
 	}
 
 	kategoriPengeluaranService := service.NewKategoriPengeluaranService()
@@ -147,7 +194,7 @@ func InitRoutes(r *gin.RouterGroup) {
 
 	kategoriPengeluaran := r.Group("/kategori-pengeluaran")
 	{
-		kategoriPengeluaran.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
+		kategoriPengeluaran.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.RequirePermission(model.PermissionLayananKeuangan), middleware.LogUserActivity())
 		kategoriPengeluaran.GET("", kategoriPengeluaranController.GetAll)
 		kategoriPengeluaran.GET("/:id", kategoriPengeluaranController.GetByID)
 		kategoriPengeluaran.POST("", kategoriPengeluaranController.Create)
@@ -155,20 +202,50 @@ func InitRoutes(r *gin.RouterGroup) {
 		kategoriPengeluaran.DELETE("/:id", kategoriPengeluaranController.Delete)
 	}
 
+	pengeluaranService := service.NewPengeluaranService()
+	pengeluaranController := controller.NewPengeluaranController(pengeluaranService)
+
+	pengeluaran := r.Group("/pengeluaran")
+	{
+		pengeluaran.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.RequirePermission(model.PermissionLayananKeuangan), middleware.LogUserActivity())
+		pengeluaran.GET("", pengeluaranController.GetAll)
+		pengeluaran.GET("/summary", pengeluaranController.GetSummary)
+		pengeluaran.GET("/summary/kategori", pengeluaranController.GetPerKategori)
+		pengeluaran.GET("/:id", pengeluaranController.GetByID)
+		pengeluaran.POST("", pengeluaranController.Create)
+		pengeluaran.PUT("/:id", pengeluaranController.Update)
+		pengeluaran.DELETE("/:id", pengeluaranController.Delete)
+	}
+
+	cashShiftService := service.NewCashShiftService(database.DbCore)
+	cashShiftController := controller.NewCashShiftController(cashShiftService)
+
+	// Buka / tutup kasir harian
+	cashShifts := r.Group("/cash-shifts")
+	{
+		cashShifts.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.RequirePermission(model.PermissionLayananKeuangan), middleware.LogUserActivity())
+		cashShifts.GET("", cashShiftController.GetAll)
+		cashShifts.POST("/open", cashShiftController.Open)
+		cashShifts.GET("/current", cashShiftController.GetCurrent)
+		cashShifts.POST("/close", cashShiftController.Close)
+		cashShifts.GET("/:id", cashShiftController.GetByID)
+		cashShifts.GET("/:id/print", cashShiftController.Print)
+	}
+
 	diskonService := service.NewDiskonService()
 	diskonController := controller.NewDiskonController(diskonService)
 
 	diskon := r.Group("/diskon")
 	{
-		diskon.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
-		diskon.GET("", diskonController.GetAllDiskon)
-		diskon.GET("/active", diskonController.GetActiveDiskon)
-		diskon.GET("/outlet/:outlet_id", diskonController.GetDiskonByOutlet)
-		diskon.GET("/:id", diskonController.GetDiskonByID)
-		diskon.POST("", diskonController.CreateDiskon)
-		diskon.PUT("/:id", diskonController.UpdateDiskon)
-		diskon.PATCH("/:id/toggle", diskonController.ToggleStatus)
-		diskon.DELETE("/:id", diskonController.DeleteDiskon)
+		diskon.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.LogUserActivity())
+		diskon.GET("", readProduk, diskonController.GetAllDiskon)
+		diskon.GET("/active", readProduk, diskonController.GetActiveDiskon)
+		diskon.GET("/outlet/:outlet_id", readProduk, diskonController.GetDiskonByOutlet)
+		diskon.GET("/:id", readProduk, diskonController.GetDiskonByID)
+		diskon.POST("", manageProduk, diskonController.CreateDiskon)
+		diskon.PUT("/:id", manageProduk, diskonController.UpdateDiskon)
+		diskon.PATCH("/:id/toggle", manageProduk, diskonController.ToggleStatus)
+		diskon.DELETE("/:id", manageProduk, diskonController.DeleteDiskon)
 	}
 
 	parfumService := service.NewParfumService()
@@ -176,12 +253,12 @@ func InitRoutes(r *gin.RouterGroup) {
 
 	parfum := r.Group("/parfum")
 	{
-		parfum.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
-		parfum.GET("", parfumController.GetAllParfum)
-		parfum.GET("/:id", parfumController.GetParfumByID)
-		parfum.POST("", parfumController.CreateParfum)
-		parfum.PUT("/:id", parfumController.UpdateParfum)
-		parfum.DELETE("/:id", parfumController.DeleteParfum)
+		parfum.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.LogUserActivity())
+		parfum.GET("", readProduk, parfumController.GetAllParfum)
+		parfum.GET("/:id", readProduk, parfumController.GetParfumByID)
+		parfum.POST("", manageProduk, parfumController.CreateParfum)
+		parfum.PUT("/:id", manageProduk, parfumController.UpdateParfum)
+		parfum.DELETE("/:id", manageProduk, parfumController.DeleteParfum)
 	}
 
 	notaSettingService := service.NewNotaSettingsService()
@@ -189,7 +266,7 @@ func InitRoutes(r *gin.RouterGroup) {
 
 	notaSettings := r.Group("/nota-settings")
 	{
-		notaSettings.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
+		notaSettings.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.RequirePermission(model.PermissionPengaturan), middleware.LogUserActivity())
 		notaSettings.GET("/outlet/:outlet_id", notaSettingController.GetByOutletID)
 		notaSettings.POST("/outlet/:outlet_id", notaSettingController.CreateOrUpdate)
 		notaSettings.DELETE("/outlet/:outlet_id", notaSettingController.Delete)
@@ -200,7 +277,7 @@ func InitRoutes(r *gin.RouterGroup) {
 
 	nota := r.Group("/nota")
 	{
-		nota.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
+		nota.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.RequirePermission(model.PermissionLayananTransaksi), middleware.LogUserActivity())
 
 		nota.POST("", notaController.GenerateNota)
 		nota.GET("/:id", notaController.GetNotaByID)
@@ -209,7 +286,23 @@ func InitRoutes(r *gin.RouterGroup) {
 
 		nota.POST("/print", notaController.PrintNota)
 		nota.POST("/preview", notaController.PreviewNota)
-		nota.PATCH("/:id/void", notaController.VoidNota)
+		nota.PATCH("/:id/void", middleware.RequirePermission(model.PermissionBatalkanOrder), notaController.VoidNota)
+		nota.GET("/:id/pdf", notaController.DownloadNotaPDF)
+		nota.GET("/:id/share", notaController.ShareNota)
+	}
+
+	// Link publik nota untuk pelanggan (tanpa login, token acak)
+	publicNota := r.Group("/public/nota")
+	{
+		publicNota.GET("/:token", notaController.PublicNota)
+		publicNota.GET("/:token/pdf", notaController.PublicNotaPDF)
+	}
+
+	// Tracking pesanan untuk pelanggan (tanpa login, token acak di QR nota), dibatasi per IP
+	publicTrack := r.Group("/public/track")
+	{
+		publicTrack.Use(middleware.RateLimit(30, time.Minute))
+		publicTrack.GET("/:token", controller.TrackTransaction)
 	}
 
 	karyawanService := service.NewKaryawanService(database.DbCore)
@@ -217,7 +310,7 @@ func InitRoutes(r *gin.RouterGroup) {
 
 	karyawan := r.Group("/karyawan")
 	{
-		karyawan.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
+		karyawan.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.RequirePermission(model.PermissionDataKaryawan), middleware.LogUserActivity())
 
 		karyawan.GET("", karyawanController.GetAllKaryawan)
 		karyawan.GET("/:id", karyawanController.GetKaryawanByID)
@@ -230,13 +323,13 @@ func InitRoutes(r *gin.RouterGroup) {
 
 	paymentMethods := r.Group("/payment-methods")
 	{
-		paymentMethods.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
-		paymentMethods.GET("/outlet/:outlet_id", paymentMethodController.GetAllPaymentMethods)
-		paymentMethods.POST("/outlet/:outlet_id", paymentMethodController.CreatePaymentMethod)
-		paymentMethods.GET("/:id", paymentMethodController.GetPaymentMethodByID)
-		paymentMethods.PUT("/:id", paymentMethodController.UpdatePaymentMethod)
-		paymentMethods.DELETE("/:id", paymentMethodController.DeletePaymentMethod)
-		paymentMethods.PATCH("/:id/toggle-active", paymentMethodController.ToggleActiveStatus)
+		paymentMethods.Use(middleware.JWTAuthMiddleware(), middleware.OutletScope(), middleware.LogUserActivity())
+		paymentMethods.GET("/outlet/:outlet_id", readPaymentMethod, paymentMethodController.GetAllPaymentMethods)
+		paymentMethods.POST("/outlet/:outlet_id", managePaymentMethod, paymentMethodController.CreatePaymentMethod)
+		paymentMethods.GET("/:id", readPaymentMethod, paymentMethodController.GetPaymentMethodByID)
+		paymentMethods.PUT("/:id", managePaymentMethod, paymentMethodController.UpdatePaymentMethod)
+		paymentMethods.DELETE("/:id", managePaymentMethod, paymentMethodController.DeletePaymentMethod)
+		paymentMethods.PATCH("/:id/toggle-active", managePaymentMethod, paymentMethodController.ToggleActiveStatus)
 	}
 
 	// customerService := service.NewCustomerService(database.DbCore)
@@ -255,7 +348,7 @@ func InitRoutes(r *gin.RouterGroup) {
 
 	misc := r.Group("/misc")
 	{
-		misc.Use(middleware.JWTAuthMiddleware(), middleware.LogUserActivity())
+		misc.Use(middleware.JWTAuthMiddleware(), middleware.RequirePermission(model.PermissionPengaturan), middleware.LogUserActivity())
 
 		fileInput := &model.FileInput{}
 		misc.POST("/upload-data-s3-local", middleware.InputValidator(fileInput), controller.UploadFile)
@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -10,6 +13,7 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 
+	"BackendFramework/internal/config"
 	"BackendFramework/internal/database"
 	"BackendFramework/internal/middleware"
 	"BackendFramework/internal/model"
@@ -141,59 +145,155 @@ func InvalidateAllUserTokens(userId string) error {
 	return nil
 }
 
-func RefreshAccessToken(userId string, newAccessToken string) error {
+// ==================== REFRESH TOKEN ROTATION ====================
+
+// ErrRefreshTokenReused dikembalikan jika refresh token yang sudah dirotasi dipakai lagi;
+// seluruh sesi (keluarga token) langsung dicabut karena token kemungkinan dicuri
+var ErrRefreshTokenReused = errors.New("refresh token sudah pernah dipakai, sesi dicabut")
+
+// maxRotatedRefreshTokens membatasi riwayat hash yang disimpan per sesi untuk deteksi pemakaian ulang
+const maxRotatedRefreshTokens = 50
+
+// HashRefreshToken menghasilkan hash SHA-256 refresh token; hanya hash yang disimpan di Mongo
+func HashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshTokenExpiry menentukan masa berlaku refresh token berdasarkan is_remember_me
+func RefreshTokenExpiry(isRememberMe string) time.Duration {
+	if isRememberMe == "y" {
+		return config.RememberMeRefreshTokenExpiry
+	}
+	return config.RefreshTokenExpiry
+}
+
+// refreshTokenFilter mencocokkan sesi aktif yang refresh token-nya masih berlaku
+func refreshTokenFilter(userId string, refreshToken string) bson.M {
+	return bson.M{
+		"user_id":        userId,
+		"is_valid_token": "y",
+		"$or": bson.A{
+			bson.M{"refresh_token_hash": HashRefreshToken(refreshToken)},
+			bson.M{"refresh_token": refreshToken}, // Dokumen lama sebelum refresh token di-hash
+		},
+	}
+}
+
+// reusedRefreshTokenFilter mencocokkan sesi aktif yang pernah memakai refresh token ini sebelum dirotasi
+func reusedRefreshTokenFilter(userId string, refreshToken string) bson.M {
+	return bson.M{"user_id": userId, "rotated_refresh_tokens": HashRefreshToken(refreshToken), "is_valid_token": "y"}
+}
+
+// ValidateRefreshToken mencari sesi aktif dari refresh token. Jika token ternyata sudah pernah dirotasi,
+// sesi pemiliknya dicabut dan ErrRefreshTokenReused dikembalikan.
+func ValidateRefreshToken(userId string, refreshToken string) (*model.TokenData, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	update := bson.M{
-		"$set": bson.M{
-			"access_token": newAccessToken,
-			"updated_at":   time.Now(),
-		},
+	collection := database.DbAuth.Collection("access_tokens")
+
+	var tokenData model.TokenData
+	err := collection.FindOne(ctx, refreshTokenFilter(userId, refreshToken)).Decode(&tokenData)
+	if err == nil {
+		return &tokenData, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		middleware.LogError(err, "MongoDB Failed to Validate Refresh Token")
+		return nil, err
 	}
 
-	result, err := database.DbAuth.Collection("access_tokens").UpdateOne(
+	// Token lama dari keluarga yang sama dipakai lagi: cabut seluruh sesi
+	result, err := collection.UpdateMany(
 		ctx,
-		bson.M{"user_id": userId, "is_valid_token": "y"},
-		update,
+		reusedRefreshTokenFilter(userId, refreshToken),
+		bson.M{"$set": bson.M{
+			"is_valid_token": "n",
+			"revoked_reason": "refresh_token_reuse",
+			"updated_at":     time.Now(),
+		}},
 	)
-
 	if err != nil {
-		middleware.LogError(err, "MongoDB Failed to Refresh Access Token")
-		return err
+		middleware.LogError(err, "MongoDB Failed to Revoke Reused Refresh Token Session")
+		return nil, err
 	}
-
-	if result.MatchedCount == 0 {
-		return errors.New("valid token not found for user")
+	if result.MatchedCount > 0 {
+		fmt.Printf("🚨 Refresh token reuse detected, session revoked for user_id: %s\n", userId)
+		return nil, ErrRefreshTokenReused
 	}
 
-	fmt.Printf("✅ Access token refreshed for user_id: %s\n", userId)
-	return nil
+	return nil, errors.New("invalid or expired refresh token")
 }
 
-func ValidateRefreshToken(userId string, refreshToken string) (*model.TokenData, error) {
+// RotateRefreshToken mengganti access token dan refresh token milik sesi. Update bersyarat pada hash lama
+// sehingga dua refresh bersamaan dengan token yang sama tidak bisa sama-sama berhasil.
+// Sesi remember me diperpanjang setiap rotasi, sesi biasa tetap memakai batas waktu sejak login.
+func RotateRefreshToken(token *model.TokenData, oldRefreshToken, newAccessToken, newRefreshToken string) (time.Time, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var tokenData model.TokenData
-	err := database.DbAuth.Collection("access_tokens").FindOne(
-		ctx,
-		bson.M{
-			"user_id":        userId,
-			"refresh_token":  refreshToken,
-			"is_valid_token": "y",
-		},
-	).Decode(&tokenData)
+	filter, update, expiredAt, err := refreshTokenRotation(token, oldRefreshToken, newAccessToken, newRefreshToken, time.Now())
+	if err != nil {
+		return time.Time{}, err
+	}
 
+	result, err := database.DbAuth.Collection("access_tokens").UpdateOne(ctx, filter, update)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("invalid or expired refresh token")
+		middleware.LogError(err, "MongoDB Failed to Rotate Refresh Token")
+		return time.Time{}, err
+	}
+
+	if result.MatchedCount == 0 {
+		return time.Time{}, errors.New("invalid or expired refresh token")
+	}
+
+	fmt.Printf("✅ Refresh token rotated for user_id: %s\n", token.UserId)
+	return expiredAt, nil
+}
+
+// refreshTokenRotation menyusun filter dan update untuk RotateRefreshToken: hash lama dipindah ke
+// rotated_refresh_tokens (untuk deteksi pemakaian ulang) dan hanya hash token baru yang disimpan
+func refreshTokenRotation(token *model.TokenData, oldRefreshToken, newAccessToken, newRefreshToken string, now time.Time) (filter, update bson.M, expiredAt time.Time, err error) {
+	oldHash := HashRefreshToken(oldRefreshToken)
+	filter = bson.M{"user_id": token.UserId, "is_valid_token": "y"}
+	if token.RefreshTokenHash != "" {
+		filter["refresh_token_hash"] = oldHash
+	} else {
+		filter["refresh_token"] = oldRefreshToken
+	}
+	if token.SessionID != "" {
+		filter["session_id"] = token.SessionID
+	}
+
+	expiredAt = token.RefreshTokenExpiredAt
+	if token.IsRememberMe == "y" {
+		expiredAt = now.Add(RefreshTokenExpiry(token.IsRememberMe))
+	}
+
+	set := bson.M{
+		"access_token":          newAccessToken,
+		"refresh_token_hash":    HashRefreshToken(newRefreshToken),
+		"refresh_token_expired": expiredAt,
+		"updated_at":            now,
+	}
+	if token.SessionID == "" {
+		// Dokumen lama mendapat session_id agar bisa dikelola lewat /auth/sessions
+		sessionID, err := newSessionID()
+		if err != nil {
+			return nil, nil, time.Time{}, err
 		}
-		middleware.LogError(err, "MongoDB Failed to Validate Refresh Token")
-		return nil, err
+		set["session_id"] = sessionID
 	}
 
-	return &tokenData, nil
+	update = bson.M{
+		"$set":   set,
+		"$unset": bson.M{"refresh_token": ""},
+		"$push": bson.M{"rotated_refresh_tokens": bson.M{
+			"$each":  bson.A{oldHash},
+			"$slice": -maxRotatedRefreshTokens,
+		}},
+	}
+	return filter, update, expiredAt, nil
 }
 
 func CleanupExpiredTokens(daysOld int) error {
@@ -242,6 +342,163 @@ func GetAllActiveTokens(userId string) ([]model.TokenData, error) {
 	return tokens, nil
 }
 
+// GetStaffActiveTokens mengambil sesi aktif karyawan dari outlet milik owner
+func GetStaffActiveTokens(ownerUserId string) ([]model.TokenData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := database.DbAuth.Collection("access_tokens").Find(
+		ctx,
+		bson.M{"owner_user_id": ownerUserId, "account_type": model.AccountTypeKaryawan, "is_valid_token": "y"},
+	)
+
+	if err != nil {
+		middleware.LogError(err, "MongoDB Failed to Get Staff Active Tokens")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []model.TokenData
+	if err = cursor.All(ctx, &tokens); err != nil {
+		middleware.LogError(err, "MongoDB Failed to Decode Tokens")
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// ==================== SESSION MANAGEMENT ====================
+
+// Satu dokumen access_tokens = satu sesi (perangkat). Dokumen lama tanpa session_id tetap
+// bisa dipakai sampai kedaluwarsa karena validasi token mencari berdasarkan access_token.
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SaveSession menyimpan sesi login baru. Jika deviceID diisi, sesi lama dari perangkat yang sama
+// ditimpa sehingga login ulang di HP yang sama tidak menumpuk sesi.
+func SaveSession(userId string, deviceID string, dbData bson.M) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sessionID, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	// Refresh token hanya disimpan dalam bentuk hash. Masa berlakunya mengikuti is_remember_me,
+	// kecuali pemanggil mengisi refresh_token_expired sendiri.
+	if refreshToken, ok := dbData["refresh_token"].(string); ok {
+		dbData["refresh_token_hash"] = HashRefreshToken(refreshToken)
+		delete(dbData, "refresh_token")
+	}
+	if expiredAt, ok := dbData["refresh_token_expired"].(time.Time); !ok || expiredAt.IsZero() {
+		isRememberMe, _ := dbData["is_remember_me"].(string)
+		dbData["refresh_token_expired"] = now.Add(RefreshTokenExpiry(isRememberMe))
+	}
+
+	dbData["user_id"] = userId
+	dbData["device_id"] = deviceID
+	dbData["updated_at"] = now
+	delete(dbData, "created_at")
+
+	filter := bson.M{"session_id": sessionID}
+	if deviceID != "" {
+		filter = bson.M{"user_id": userId, "device_id": deviceID}
+	}
+
+	// Session ID diganti setiap login agar sesi yang sudah di-revoke tidak hidup lagi
+	dbData["session_id"] = sessionID
+
+	opts := options.UpdateOne().SetUpsert(true)
+	_, err = database.DbAuth.Collection("access_tokens").UpdateOne(
+		ctx,
+		filter,
+		bson.M{"$set": dbData, "$setOnInsert": bson.M{"created_at": now}},
+		opts,
+	)
+	if err != nil {
+		middleware.LogError(err, "MongoDB Failed to Save Session")
+		return "", err
+	}
+
+	fmt.Printf("✅ Session %s saved for user_id: %s\n", sessionID, userId)
+	return sessionID, nil
+}
+
+// ErrSessionNotFound dikembalikan jika sesi tidak ada, sudah dicabut, atau bukan milik user
+var ErrSessionNotFound = errors.New("sesi tidak ditemukan")
+
+// RevokeSession mencabut satu sesi milik userId. Owner (includeStaff) juga boleh mencabut sesi karyawan outletnya.
+func RevokeSession(userId string, sessionID string, includeStaff bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	owners := bson.A{bson.M{"user_id": userId}}
+	if includeStaff {
+		owners = append(owners, bson.M{"owner_user_id": userId, "account_type": model.AccountTypeKaryawan})
+	}
+
+	result, err := database.DbAuth.Collection("access_tokens").UpdateOne(
+		ctx,
+		bson.M{"session_id": sessionID, "is_valid_token": "y", "$or": owners},
+		bson.M{"$set": bson.M{"is_valid_token": "n", "updated_at": time.Now()}},
+	)
+	if err != nil {
+		middleware.LogError(err, "MongoDB Failed to Revoke Session")
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+
+	fmt.Printf("🔒 Session %s revoked by user_id: %s\n", sessionID, userId)
+	return nil
+}
+
+// SwitchSessionOutlet memindahkan sesi owner ke outlet lain dengan access token baru. Access token lama
+// langsung tidak berlaku karena validasi mencari berdasarkan access_token; refresh token tetap sama
+// dan refresh berikutnya mempertahankan outlet yang dipilih.
+func SwitchSessionOutlet(userId string, sessionID string, newAccessToken string, outletID uint, outletScope string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{
+		"access_token": newAccessToken,
+		"outlet_id":    outletID,
+		"updated_at":   time.Now(),
+	}
+	update := bson.M{"$set": set}
+	if outletScope != "" {
+		set["outlet_scope"] = outletScope
+	} else {
+		update["$unset"] = bson.M{"outlet_scope": ""}
+	}
+
+	result, err := database.DbAuth.Collection("access_tokens").UpdateOne(
+		ctx,
+		bson.M{"session_id": sessionID, "user_id": userId, "is_valid_token": "y"},
+		update,
+	)
+	if err != nil {
+		middleware.LogError(err, "MongoDB Failed to Switch Session Outlet")
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
 // ==================== REFERRAL TRACKING IN AUTH ====================
 
 // TrackReferralLogin mencatat login dari user yang direferral
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"BackendFramework/internal/config"
+	"BackendFramework/internal/model"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// sessionStore meniru collection access_tokens untuk operator yang dipakai filter dan update refresh token:
+// kesamaan nilai, elemen array, $or, $set, $unset dan $push dengan $each/$slice
+type sessionStore []bson.M
+
+func matchSession(doc, filter bson.M) bool {
+	for key, want := range filter {
+		if key == "$or" {
+			matched := false
+			for _, alt := range want.(bson.A) {
+				if matchSession(doc, alt.(bson.M)) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+			continue
+		}
+
+		switch got := doc[key].(type) {
+		case []string:
+			found := false
+			for _, value := range got {
+				if value == want {
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+		case nil:
+			return false
+		default:
+			if got != want {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// update menerapkan update ke setiap dokumen yang cocok dan mengembalikan jumlahnya
+func (s sessionStore) update(filter, update bson.M, many bool) int {
+	matched := 0
+	for _, doc := range s {
+		if !matchSession(doc, filter) {
+			continue
+		}
+		matched++
+
+		if set, ok := update["$set"].(bson.M); ok {
+			for key, value := range set {
+				doc[key] = value
+			}
+		}
+		if unset, ok := update["$unset"].(bson.M); ok {
+			for key := range unset {
+				delete(doc, key)
+			}
+		}
+		if push, ok := update["$push"].(bson.M); ok {
+			for key, spec := range push {
+				values, _ := doc[key].([]string)
+				for _, value := range spec.(bson.M)["$each"].(bson.A) {
+					values = append(values, value.(string))
+				}
+				if limit := -spec.(bson.M)["$slice"].(int); len(values) > limit {
+					values = values[len(values)-limit:]
+				}
+				doc[key] = values
+			}
+		}
+
+		if !many {
+			break
+		}
+	}
+	return matched
+}
+
+func (s sessionStore) find(t *testing.T, filter bson.M) *model.TokenData {
+	t.Helper()
+
+	for _, doc := range s {
+		if !matchSession(doc, filter) {
+			continue
+		}
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatalf("marshal session: %v", err)
+		}
+		var token model.TokenData
+		if err := bson.Unmarshal(raw, &token); err != nil {
+			t.Fatalf("unmarshal session: %v", err)
+		}
+		return &token
+	}
+	return nil
+}
+
+// rotate menjalankan RotateRefreshToken terhadap store; false jika tidak ada sesi yang cocok
+func (s sessionStore) rotate(t *testing.T, token *model.TokenData, oldRefreshToken, newRefreshToken string, now time.Time) (time.Time, bool) {
+	t.Helper()
+
+	filter, update, expiredAt, err := refreshTokenRotation(token, oldRefreshToken, "access-"+newRefreshToken, newRefreshToken, now)
+	if err != nil {
+		t.Fatalf("refreshTokenRotation: %v", err)
+	}
+	return expiredAt, s.update(filter, update, false) == 1
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	loginAt := time.Date(2026, 1, 10, 8, 0, 0, 0, time.UTC)
+	now := loginAt.Add(2 * time.Hour)
+
+	tests := []struct {
+		name       string
+		session    bson.M
+		wantExpiry time.Time
+	}{
+		{
+			name: "sesi biasa tidak diperpanjang",
+			session: bson.M{"session_id": "s1", "user_id": "1", "is_valid_token": "y", "is_remember_me": "n",
+				"refresh_token_hash": HashRefreshToken("A"), "refresh_token_expired": loginAt.Add(config.RefreshTokenExpiry)},
+			wantExpiry: loginAt.Add(config.RefreshTokenExpiry),
+		},
+		{
+			name: "sesi remember me diperpanjang",
+			session: bson.M{"session_id": "s1", "user_id": "1", "is_valid_token": "y", "is_remember_me": "y",
+				"refresh_token_hash": HashRefreshToken("A"), "refresh_token_expired": loginAt.Add(config.RememberMeRefreshTokenExpiry)},
+			wantExpiry: now.Add(config.RememberMeRefreshTokenExpiry),
+		},
+		{
+			name: "dokumen lama dengan refresh token plain",
+			session: bson.M{"user_id": "1", "is_valid_token": "y", "is_remember_me": "n",
+				"refresh_token": "A", "refresh_token_expired": loginAt.Add(config.RefreshTokenExpiry)},
+			wantExpiry: loginAt.Add(config.RefreshTokenExpiry),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := sessionStore{tt.session}
+			token := store.find(t, refreshTokenFilter("1", "A"))
+			if token == nil {
+				t.Fatal("refresh token awal tidak ditemukan")
+			}
+
+			expiredAt, ok := store.rotate(t, token, "A", "B", now)
+			if !ok {
+				t.Fatal("rotasi tidak mengenai sesi")
+			}
+			if !expiredAt.Equal(tt.wantExpiry) {
+				t.Errorf("expired = %v, want %v", expiredAt, tt.wantExpiry)
+			}
+
+			rotated := store.find(t, refreshTokenFilter("1", "B"))
+			if rotated == nil {
+				t.Fatal("refresh token baru tidak berlaku")
+			}
+			if rotated.RefreshToken != "" || rotated.RefreshTokenHash != HashRefreshToken("B") {
+				t.Errorf("refresh token tersimpan plain atau hash salah: %+v", rotated)
+			}
+			if rotated.SessionID == "" {
+				t.Error("sesi tidak mendapat session_id")
+			}
+			if store.find(t, refreshTokenFilter("1", "A")) != nil {
+				t.Error("refresh token lama masih berlaku setelah rotasi")
+			}
+
+			// Refresh kedua dengan token lama yang sama (request paralel) tidak ikut berhasil
+			if _, ok := store.rotate(t, token, "A", "C", now); ok {
+				t.Error("token lama berhasil dirotasi dua kali")
+			}
+		})
+	}
+}
+
+func TestRefreshTokenReuse(t *testing.T) {
+	now := time.Now()
+	store := sessionStore{
+		{"session_id": "hp", "user_id": "1", "is_valid_token": "y", "refresh_token_hash": HashRefreshToken("A")},
+		{"session_id": "laptop", "user_id": "1", "is_valid_token": "y", "refresh_token_hash": HashRefreshToken("X")},
+	}
+
+	// A -> B -> C di sesi hp
+	for _, step := range [][2]string{{"A", "B"}, {"B", "C"}} {
+		token := store.find(t, refreshTokenFilter("1", step[0]))
+		if token == nil {
+			t.Fatalf("refresh token %s tidak berlaku", step[0])
+		}
+		if _, ok := store.rotate(t, token, step[0], step[1], now); !ok {
+			t.Fatalf("rotasi %s -> %s gagal", step[0], step[1])
+		}
+	}
+
+	tests := []struct {
+		name       string
+		userID     string
+		token      string
+		wantReused bool
+	}{
+		{name: "token yang berlaku bukan pemakaian ulang", userID: "1", token: "C"},
+		{name: "token asing", userID: "1", token: "Z"},
+		{name: "token lama milik user lain", userID: "2", token: "A"},
+		{name: "token pertama dipakai lagi", userID: "1", token: "A", wantReused: true},
+		{name: "token sebelumnya dipakai lagi", userID: "1", token: "B", wantReused: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantReused && store.find(t, refreshTokenFilter(tt.userID, tt.token)) != nil {
+				t.Fatalf("token %s yang sudah dirotasi masih berlaku", tt.token)
+			}
+			reused := store.find(t, reusedRefreshTokenFilter(tt.userID, tt.token))
+			if (reused != nil) != tt.wantReused {
+				t.Fatalf("pemakaian ulang %s terdeteksi = %v, want %v", tt.token, reused != nil, tt.wantReused)
+			}
+			if tt.wantReused && reused.SessionID != "hp" {
+				t.Errorf("pemakaian ulang menunjuk sesi %q, want hp", reused.SessionID)
+			}
+		})
+	}
+
+	// Pencabutan dari ValidateRefreshToken mematikan token terbaru sesi itu, sesi lain tetap berlaku
+	store.update(reusedRefreshTokenFilter("1", "A"), bson.M{"$set": bson.M{"is_valid_token": "n"}}, true)
+	if store.find(t, refreshTokenFilter("1", "C")) != nil {
+		t.Error("token terbaru masih berlaku setelah sesi dicabut")
+	}
+	if store.find(t, refreshTokenFilter("1", "X")) == nil {
+		t.Error("sesi lain ikut dicabut")
+	}
+}
+
+func TestRefreshTokenRotationHistoryLimit(t *testing.T) {
+	store := sessionStore{{"session_id": "hp", "user_id": "1", "is_valid_token": "y", "refresh_token_hash": HashRefreshToken("0")}}
+
+	rotations := maxRotatedRefreshTokens + 5
+	for i := 0; i < rotations; i++ {
+		old, next := string(rune('0'+i)), string(rune('0'+i+1))
+		token := store.find(t, refreshTokenFilter("1", old))
+		if _, ok := store.rotate(t, token, old, next, time.Now()); !ok {
+			t.Fatalf("rotasi ke-%d gagal", i+1)
+		}
+	}
+
+	history := store[0]["rotated_refresh_tokens"].([]string)
+	if len(history) != maxRotatedRefreshTokens {
+		t.Fatalf("riwayat berisi %d hash, want %d", len(history), maxRotatedRefreshTokens)
+	}
+	if history[len(history)-1] != HashRefreshToken(string(rune('0'+rotations-1))) {
+		t.Error("hash terbaru tidak ada di akhir riwayat")
+	}
+}
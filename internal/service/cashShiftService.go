@@ -0,0 +1,256 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCashShiftAlreadyOpen = errors.New("masih ada shift kasir yang belum ditutup")
+	ErrNoOpenCashShift      = errors.New("tidak ada shift kasir yang sedang dibuka")
+	ErrCashShiftNotFound    = errors.New("shift kasir tidak ditemukan")
+)
+
+type CashShiftService struct {
+	db *gorm.DB
+}
+
+func NewCashShiftService(db *gorm.DB) *CashShiftService {
+	return &CashShiftService{db: db}
+}
+
+// Open membuka shift baru; satu outlet hanya boleh punya satu shift terbuka
+func (s *CashShiftService) Open(outletID uint, input model.OpenCashShiftInput, adminName string) (*model.CashShift, error) {
+	var shift model.CashShift
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.CashShift
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("outlet_id = ? AND status = ?", outletID, model.CashShiftOpen).
+			First(&existing).Error
+		if err == nil {
+			return ErrCashShiftAlreadyOpen
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		shift = model.CashShift{
+			OutletID:     outletID,
+			Status:       model.CashShiftOpen,
+			OpenedBy:     adminName,
+			OpenedAt:     time.Now(),
+			OpeningCash:  roundPrice(input.OpeningCash),
+			OpeningNotes: input.Notes,
+		}
+		return tx.Create(&shift).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &shift, nil
+}
+
+// GetCurrent mengembalikan shift terbuka beserta hitungan kas berjalan (belum disimpan)
+func (s *CashShiftService) GetCurrent(outletID uint) (*model.CashShift, error) {
+	var shift model.CashShift
+	if err := s.db.Where("outlet_id = ? AND status = ?", outletID, model.CashShiftOpen).First(&shift).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoOpenCashShift
+		}
+		return nil, err
+	}
+
+	if err := s.calculate(s.db, &shift, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &shift, nil
+}
+
+// Close menghitung kas, menyimpan selisih dengan uang yang dihitung kasir lalu mengunci shift
+func (s *CashShiftService) Close(outletID uint, input model.CloseCashShiftInput, adminName string) (*model.CashShift, error) {
+	var shift model.CashShift
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("outlet_id = ? AND status = ?", outletID, model.CashShiftOpen).
+			First(&shift).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoOpenCashShift
+			}
+			return err
+		}
+
+		now := time.Now()
+		if err := s.calculate(tx, &shift, now); err != nil {
+			return err
+		}
+
+		breakdownJSON, err := json.Marshal(shift.Breakdown)
+		if err != nil {
+			return err
+		}
+
+		counted := roundPrice(*input.CountedCash)
+		difference := roundPrice(counted - shift.ExpectedCash)
+
+		// Kunci juga lewat WHERE: hook BeforeUpdate hanya melihat status di memori, bukan di database
+		result := tx.Model(&shift).Where("status = ?", model.CashShiftOpen).Updates(map[string]interface{}{
+			"status":         model.CashShiftClosed,
+			"closed_by":      adminName,
+			"closed_at":      now,
+			"cash_in":        shift.CashIn,
+			"non_cash_in":    shift.NonCashIn,
+			"cash_out":       shift.CashOut,
+			"expected_cash":  shift.ExpectedCash,
+			"counted_cash":   counted,
+			"difference":     difference,
+			"payment_count":  shift.PaymentCount,
+			"expense_count":  shift.ExpenseCount,
+			"closing_notes":  input.Notes,
+			"breakdown_json": string(breakdownJSON),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return model.ErrCashShiftLocked
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(shift.ID, outletID)
+}
+
+// calculate mengisi CashIn, NonCashIn, CashOut, ExpectedCash dan Breakdown untuk rentang OpenedAt s.d. until
+func (s *CashShiftService) calculate(db *gorm.DB, shift *model.CashShift, until time.Time) error {
+	methods := make([]model.CashShiftMethodTotal, 0)
+	if err := db.Model(&model.TransactionPayment{}).
+		Select("payment_methods.id AS payment_method_id, payment_methods.name AS name, payment_methods.category AS category, "+
+			"COALESCE(SUM(transaction_payments.amount), 0) AS total, COUNT(transaction_payments.id) AS jumlah").
+		Joins("JOIN payment_methods ON payment_methods.id = transaction_payments.payment_method_id").
+		Joins("JOIN transactions ON transactions.id = transaction_payments.transaction_id").
+		Where("transaction_payments.outlet_id = ?", shift.OutletID).
+		Where("transaction_payments.created_at >= ? AND transaction_payments.created_at <= ?", shift.OpenedAt, until).
+		Where("transactions.order_status <> ?", model.OrderStatusBatal).
+		Group("payment_methods.id, payment_methods.name, payment_methods.category").
+		Order("payment_methods.category, payment_methods.name").
+		Scan(&methods).Error; err != nil {
+		return err
+	}
+
+	expenses := make([]model.PengeluaranPerKategori, 0)
+	if err := db.Model(&model.Pengeluaran{}).
+		Select("COALESCE(ac_kategori_pengeluaran.ktg_id, 0) AS kategori_id, COALESCE(ac_kategori_pengeluaran.ktg_nama, 'Tanpa Kategori') AS kategori_nama, "+
+			"COALESCE(SUM(ac_pengeluaran.pengeluaran_nominal), 0) AS total, COUNT(ac_pengeluaran.pengeluaran_id) AS jumlah").
+		Joins("LEFT JOIN ac_kategori_pengeluaran ON ac_kategori_pengeluaran.ktg_id = ac_pengeluaran.pengeluaran_kategori").
+		Where("ac_pengeluaran.pengeluaran_outlet = ?", shift.OutletID).
+		Where("ac_pengeluaran.pengeluaran_status = ?", "Aktif").
+		Where("ac_pengeluaran.pengeluaran_created >= ? AND ac_pengeluaran.pengeluaran_created <= ?", shift.OpenedAt, until).
+		Group("ac_kategori_pengeluaran.ktg_id, ac_kategori_pengeluaran.ktg_nama").
+		Scan(&expenses).Error; err != nil {
+		return err
+	}
+
+	shift.CashIn, shift.NonCashIn, shift.CashOut = 0, 0, 0
+	shift.PaymentCount, shift.ExpenseCount = 0, 0
+
+	for _, method := range methods {
+		if method.Category == "Cash" {
+			shift.CashIn += method.Total
+		} else {
+			shift.NonCashIn += method.Total
+		}
+		shift.PaymentCount += int(method.Jumlah)
+	}
+	for _, expense := range expenses {
+		shift.CashOut += float64(expense.Total)
+		shift.ExpenseCount += int(expense.Jumlah)
+	}
+
+	shift.CashIn = roundPrice(shift.CashIn)
+	shift.NonCashIn = roundPrice(shift.NonCashIn)
+	shift.CashOut = roundPrice(shift.CashOut)
+	shift.ExpectedCash = roundPrice(shift.OpeningCash + shift.CashIn - shift.CashOut)
+	shift.Breakdown = &model.CashShiftBreakdown{
+		PaymentMethods: methods,
+		Expenses:       expenses,
+	}
+
+	return nil
+}
+
+func (s *CashShiftService) GetByID(id uint, outletID uint) (*model.CashShift, error) {
+	var shift model.CashShift
+	if err := s.db.Where("id = ? AND outlet_id = ?", id, outletID).First(&shift).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCashShiftNotFound
+		}
+		return nil, err
+	}
+
+	// Shift terbuka dihitung langsung, shift tertutup memakai snapshot saat tutup
+	if shift.Status == model.CashShiftOpen {
+		if err := s.calculate(s.db, &shift, time.Now()); err != nil {
+			return nil, err
+		}
+	} else if shift.BreakdownJSON != "" {
+		var breakdown model.CashShiftBreakdown
+		if err := json.Unmarshal([]byte(shift.BreakdownJSON), &breakdown); err == nil {
+			shift.Breakdown = &breakdown
+		}
+	}
+
+	return &shift, nil
+}
+
+// GetAll mengembalikan riwayat shift outlet, filter tanggal buka (YYYY-MM-DD, inklusif)
+func (s *CashShiftService) GetAll(outletID uint, startDate, endDate string) ([]model.CashShift, error) {
+	query := s.db.Where("outlet_id = ?", outletID)
+
+	if startDate != "" {
+		start, err := parseTanggal(startDate)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("opened_at >= ?", start)
+	}
+	if endDate != "" {
+		end, err := parseTanggal(endDate)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("opened_at < ?", end.AddDate(0, 0, 1))
+	}
+
+	var shifts []model.CashShift
+	if err := query.Order("opened_at DESC").Find(&shifts).Error; err != nil {
+		return nil, err
+	}
+
+	return shifts, nil
+}
+
+// GetPrintSettings mengambil NotaSettings outlet untuk mencetak laporan tutup kasir
+func (s *CashShiftService) GetPrintSettings(outletID uint) *model.NotaSettings {
+	var settings model.NotaSettings
+	if err := s.db.Where("outlet_id = ?", outletID).First(&settings).Error; err != nil {
+		return &model.NotaSettings{
+			OutletID:         outletID,
+			ShowBusinessName: true,
+			PrinterSize:      58,
+		}
+	}
+	return &settings
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"BackendFramework/internal/thirdparty"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CustomerImportMaxRows adalah batas baris data per file import pelanggan
+const CustomerImportMaxRows = 5000
+
+const customerSheetName = "Pelanggan"
+
+// Kolom template import; file export memakai kolom yang sama sehingga bisa diimport ulang ke outlet lain
+const (
+	customerColumnName    = "Nama"
+	customerColumnPhone   = "Nomor HP"
+	customerColumnGender  = "Jenis Kelamin"
+	customerColumnAddress = "Alamat"
+	customerColumnCreated = "Terdaftar"
+)
+
+var (
+	ErrCustomerImportHeader  = errors.New("baris pertama file harus berisi kolom " + customerColumnName + " dan " + customerColumnPhone)
+	ErrCustomerImportTooMany = fmt.Errorf("file import maksimal berisi %d baris pelanggan", CustomerImportMaxRows)
+	ErrCustomerImportFile    = errors.New("file Excel tidak bisa dibaca")
+)
+
+var customerExcelHeaders = []thirdparty.Header{
+	{Text: customerColumnName, Width: 30},
+	{Text: customerColumnPhone, Width: 20, AsText: true},
+	{Text: customerColumnGender, Width: 15},
+	{Text: customerColumnAddress, Width: 50},
+}
+
+// customerImportAliases memetakan judul kolom (huruf kecil) dari aplikasi atau buku lain ke kolom template
+var customerImportAliases = map[string]string{
+	"nama":           customerColumnName,
+	"nama pelanggan": customerColumnName,
+	"name":           customerColumnName,
+	"nomor hp":       customerColumnPhone,
+	"no hp":          customerColumnPhone,
+	"no. hp":         customerColumnPhone,
+	"telepon":        customerColumnPhone,
+	"no telepon":     customerColumnPhone,
+	"whatsapp":       customerColumnPhone,
+	"phone":          customerColumnPhone,
+	"jenis kelamin":  customerColumnGender,
+	"gender":         customerColumnGender,
+	"alamat":         customerColumnAddress,
+	"address":        customerColumnAddress,
+}
+
+// ImportTemplate membuat file .xlsx kosong berisi kolom yang dibaca ImportExcel
+func (s *CustomerService) ImportTemplate() ([]byte, error) {
+	return thirdparty.GenerateExcelBytes(customerExcelHeaders, nil, customerSheetName)
+}
+
+// ExportExcel membuat file .xlsx berisi pelanggan outlet (opsional difilter q seperti List), urut nama
+func (s *CustomerService) ExportExcel(outletID uint, q string) ([]byte, error) {
+	var customers []model.Customer
+	if err := searchCustomers(s.db, outletID, q).Order("name ASC, id ASC").Find(&customers).Error; err != nil {
+		return nil, err
+	}
+
+	headers := append(append([]thirdparty.Header{}, customerExcelHeaders...), thirdparty.Header{Text: customerColumnCreated, Width: 20})
+	rows := make([]map[string]interface{}, 0, len(customers))
+	for _, customer := range customers {
+		rows = append(rows, map[string]interface{}{
+			customerColumnName:    customer.Name,
+			customerColumnPhone:   customer.Phone,
+			customerColumnGender:  customer.Gender,
+			customerColumnAddress: customer.Address,
+			customerColumnCreated: customer.CreatedAt.Format("2006-01-02 15:04"),
+		})
+	}
+	return thirdparty.GenerateExcelBytes(headers, rows, customerSheetName)
+}
+
+// ImportExcel membaca sheet pertama file .xlsx lalu membuat pelanggan dari setiap baris yang valid.
+// Nomor HP yang sudah terdaftar di outlet, atau muncul lagi di file, dilewati. Baris tidak valid dicatat
+// beserta nomor barisnya dan tidak menggagalkan baris lain. Dengan dryRun tidak ada data yang disimpan.
+func (s *CustomerService) ImportExcel(outletID uint, file io.Reader, dryRun bool) (*model.CustomerImportResult, error) {
+	headers, rows, err := thirdparty.ReadExcel(file, "")
+	if err != nil {
+		if errors.Is(err, thirdparty.ErrExcelEmpty) {
+			return nil, ErrCustomerImportHeader
+		}
+		return nil, ErrCustomerImportFile
+	}
+
+	columns := make(map[string]string, len(headers))
+	for _, header := range headers {
+		if column, ok := customerImportAliases[strings.ToLower(strings.TrimSpace(header.Text))]; ok {
+			if _, exists := columns[column]; !exists {
+				columns[column] = header.Text
+			}
+		}
+	}
+	if columns[customerColumnName] == "" || columns[customerColumnPhone] == "" {
+		return nil, ErrCustomerImportHeader
+	}
+	if len(rows) > CustomerImportMaxRows {
+		return nil, ErrCustomerImportTooMany
+	}
+
+	var existing []model.Customer
+	if err := s.db.Select("id", "phone").Where("outlet_id = ?", outletID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	known := make(map[string]uint, len(existing))
+	for _, customer := range existing {
+		known[customer.Phone] = customer.ID
+	}
+	inFile := make(map[string]int)
+
+	result := &model.CustomerImportResult{
+		DryRun:  dryRun,
+		Skipped: make([]model.CustomerImportRowError, 0),
+		Errors:  make([]model.CustomerImportRowError, 0),
+	}
+	customers := make([]model.Customer, 0, len(rows))
+
+	for i, row := range rows {
+		cell := func(column string) string {
+			value, _ := row[columns[column]].(string)
+			return strings.TrimSpace(value)
+		}
+		name, rawPhone := cell(customerColumnName), cell(customerColumnPhone)
+		gender, address := cell(customerColumnGender), cell(customerColumnAddress)
+		if name == "" && rawPhone == "" && gender == "" && address == "" {
+			continue
+		}
+
+		result.TotalRows++
+		report := model.CustomerImportRowError{Row: i + 2, Name: name, Phone: rawPhone}
+
+		phone := model.NormalizePhone(rawPhone)
+		gender, genderOK := importGender(gender)
+		switch {
+		case name == "":
+			report.Reason = "nama wajib diisi"
+		case rawPhone == "":
+			report.Reason = "nomor HP wajib diisi"
+		case len(phone) < 9 || len(phone) > 15:
+			report.Reason = ErrCustomerPhoneInvalid.Error()
+		case !genderOK:
+			report.Reason = "jenis kelamin harus Pria atau Wanita"
+		}
+		if report.Reason != "" {
+			result.Errors = append(result.Errors, report)
+			continue
+		}
+
+		report.Phone = phone
+		if id, ok := known[phone]; ok {
+			report.Reason = ErrCustomerPhoneExists.Error()
+			report.ExistingCustomerID = &id
+			result.Skipped = append(result.Skipped, report)
+			continue
+		}
+		if firstRow, ok := inFile[phone]; ok {
+			report.Reason = fmt.Sprintf("nomor HP sama dengan baris %d", firstRow)
+			result.Skipped = append(result.Skipped, report)
+			continue
+		}
+		inFile[phone] = report.Row
+
+		customers = append(customers, model.Customer{
+			OutletID: outletID,
+			Name:     name,
+			Phone:    phone,
+			Gender:   gender,
+			Address:  address,
+		})
+	}
+
+	result.Created = len(customers)
+	if dryRun || len(customers) == 0 {
+		return result, nil
+	}
+
+	// CreateInBatches berjalan dalam satu transaksi: import gagal tidak meninggalkan sebagian pelanggan
+	if err := s.db.CreateInBatches(&customers, 200).Error; err != nil {
+		if isDuplicateKey(err) {
+			// Nomor yang sama baru saja didaftarkan lewat request lain; import diulang akan melewatinya
+			return nil, ErrCustomerPhoneExists
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// importGender menerima Pria/Wanita beserta sebutan umum lainnya; kosong tetap kosong
+func importGender(raw string) (string, bool) {
+	switch strings.ToLower(raw) {
+	case "":
+		return "", true
+	case "pria", "laki-laki", "laki laki", "l", "male", "m":
+		return "Pria", true
+	case "wanita", "perempuan", "p", "female", "f":
+		return "Wanita", true
+	}
+	return raw, false
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"BackendFramework/internal/thirdparty"
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// customerWorkbook membuat file .xlsx dengan kolom headers; nilai angka tersimpan sebagai angka seperti diketik di Excel
+func customerWorkbook(t *testing.T, headers []string, rows ...[]interface{}) *bytes.Reader {
+	t.Helper()
+
+	excelHeaders := make([]thirdparty.Header, 0, len(headers))
+	for _, header := range headers {
+		excelHeaders = append(excelHeaders, thirdparty.Header{Text: header})
+	}
+	data := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		values := make(map[string]interface{}, len(row))
+		for i, value := range row {
+			values[headers[i]] = value
+		}
+		data = append(data, values)
+	}
+
+	file, err := thirdparty.GenerateExcelBytes(excelHeaders, data, customerSheetName)
+	if err != nil {
+		t.Fatalf("GenerateExcelBytes: %v", err)
+	}
+	return bytes.NewReader(file)
+}
+
+func TestCustomerImportExcel(t *testing.T) {
+	template := []string{customerColumnName, customerColumnPhone, customerColumnGender, customerColumnAddress}
+
+	tests := []struct {
+		name        string
+		file        func(t *testing.T) *bytes.Reader
+		dryRun      bool
+		wantErr     error
+		wantCreated []string // Nomor HP yang tersimpan setelah import, urut baris
+		wantSkipped []int    // Nomor baris yang dilewati karena nomor ganda
+		wantErrors  []int    // Nomor baris yang tidak valid
+	}{
+		{
+			name: "baris valid",
+			file: func(t *testing.T) *bytes.Reader {
+				return customerWorkbook(t, template,
+					[]interface{}{"Sari", "0813-1111-2222", "Perempuan", "Jl. Mawar"},
+					[]interface{}{"Andi", "+62 857 0000 1111", "", ""},
+				)
+			},
+			wantCreated: []string{"6281311112222", "6285700001111"},
+		},
+		{
+			name: "nomor diketik sebagai angka di Excel",
+			file: func(t *testing.T) *bytes.Reader {
+				return customerWorkbook(t, template, []interface{}{"Sari", 81311112222, "", ""})
+			},
+			wantCreated: []string{"6281311112222"},
+		},
+		{
+			name: "judul kolom dari aplikasi lain",
+			file: func(t *testing.T) *bytes.Reader {
+				return customerWorkbook(t, []string{" Nama Pelanggan ", "No HP", "Gender"}, []interface{}{"Sari", "081311112222", "F"})
+			},
+			wantCreated: []string{"6281311112222"},
+		},
+		{
+			name: "nomor ganda dilewati",
+			file: func(t *testing.T) *bytes.Reader {
+				return customerWorkbook(t, template,
+					[]interface{}{"Budi", "0812 3456 789", "", ""}, // Sudah terdaftar di outlet
+					[]interface{}{"Sari", "081311112222", "", ""},
+					[]interface{}{"Sari 2", "6281311112222", "", ""}, // Sama dengan baris 3
+				)
+			},
+			wantCreated: []string{"6281311112222"},
+			wantSkipped: []int{2, 4},
+		},
+		{
+			name: "baris tidak valid dicatat, baris kosong diabaikan",
+			file: func(t *testing.T) *bytes.Reader {
+				return customerWorkbook(t, template,
+					[]interface{}{"", "081311112222", "", ""},
+					[]interface{}{"", "", "", ""},
+					[]interface{}{"Andi", "", "", ""},
+					[]interface{}{"Dewi", "1234", "", ""},
+					[]interface{}{"Rina", "085700001111", "X", ""},
+					[]interface{}{"Sari", "081311112222", "", ""},
+				)
+			},
+			wantCreated: []string{"6281311112222"},
+			wantErrors:  []int{2, 4, 5, 6},
+		},
+		{
+			name: "dry run tidak menyimpan",
+			file: func(t *testing.T) *bytes.Reader {
+				return customerWorkbook(t, template, []interface{}{"Sari", "081311112222", "", ""})
+			},
+			dryRun: true,
+		},
+		{
+			name: "kolom nomor HP tidak ada",
+			file: func(t *testing.T) *bytes.Reader {
+				return customerWorkbook(t, []string{customerColumnName, customerColumnAddress}, []interface{}{"Sari", "Jl. Mawar"})
+			},
+			wantErr: ErrCustomerImportHeader,
+		},
+		{
+			name: "bukan file Excel",
+			file: func(t *testing.T) *bytes.Reader {
+				return bytes.NewReader([]byte("Nama,Nomor HP\nSari,081311112222\n"))
+			},
+			wantErr: ErrCustomerImportFile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, db := newTestCustomerService(t)
+			budi, err := s.Create(1, model.CustomerInput{Name: "Budi", Phone: "08123456789"})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			result, err := s.ImportExcel(1, tt.file(t), tt.dryRun)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ImportExcel err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ImportExcel: %v", err)
+			}
+
+			var created []model.Customer
+			db.Where("id <> ?", budi.ID).Order("id ASC").Find(&created)
+			phones := make([]string, 0, len(created))
+			for _, customer := range created {
+				phones = append(phones, customer.Phone)
+			}
+			if !slices.Equal(phones, tt.wantCreated) {
+				t.Errorf("pelanggan tersimpan = %v, want %v", phones, tt.wantCreated)
+			}
+			if !tt.dryRun && result.Created != len(tt.wantCreated) {
+				t.Errorf("result.Created = %d, want %d", result.Created, len(tt.wantCreated))
+			}
+			if tt.dryRun && (!result.DryRun || result.Created != 1) {
+				t.Errorf("dry run result = %+v, want 1 pelanggan akan dibuat", result)
+			}
+
+			if rows := importRows(result.Skipped); !slices.Equal(rows, tt.wantSkipped) {
+				t.Errorf("baris dilewati = %v, want %v", rows, tt.wantSkipped)
+			}
+			if rows := importRows(result.Errors); !slices.Equal(rows, tt.wantErrors) {
+				t.Errorf("baris tidak valid = %v, want %v (%+v)", rows, tt.wantErrors, result.Errors)
+			}
+			for _, skipped := range result.Skipped {
+				if skipped.Row == 2 && (skipped.ExistingCustomerID == nil || *skipped.ExistingCustomerID != budi.ID) {
+					t.Errorf("baris %d tidak menunjuk pelanggan yang sudah ada", skipped.Row)
+				}
+			}
+		})
+	}
+}
+
+func importRows(reports []model.CustomerImportRowError) []int {
+	rows := make([]int, 0, len(reports))
+	for _, report := range reports {
+		rows = append(rows, report.Row)
+	}
+	return rows
+}
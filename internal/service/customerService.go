@@ -0,0 +1,581 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// customerPhoneIndex adalah unique index (outlet_id, phone) pada tabel customers
+const customerPhoneIndex = "idx_customers_outlet_phone"
+
+var (
+	ErrCustomerNotFound     = errors.New("pelanggan tidak ditemukan")
+	ErrCustomerPhoneInvalid = errors.New("nomor HP pelanggan tidak valid")
+	// ErrCustomerPhoneExists dikembalikan bersama pelanggan yang sudah memakai nomor tersebut
+	ErrCustomerPhoneExists = errors.New("nomor HP sudah terdaftar sebagai pelanggan outlet ini")
+	// ErrCustomerMergeInvalid berarti pelanggan yang dipertahankan ikut masuk daftar yang digabung
+	ErrCustomerMergeInvalid = errors.New("pelanggan yang dipertahankan tidak boleh ada di daftar pelanggan yang digabung")
+)
+
+type CustomerService struct {
+	db *gorm.DB
+}
+
+func NewCustomerService(db *gorm.DB) *CustomerService {
+	return &CustomerService{db: db}
+}
+
+// isDuplicateKey mengenali pelanggaran unique index: MySQL error 1062, atau gorm.ErrDuplicatedKey
+// jika koneksi memakai TranslateError
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.Is(err, gorm.ErrDuplicatedKey) || (errors.As(err, &mysqlErr) && mysqlErr.Number == 1062)
+}
+
+// phoneConflict mengembalikan pelanggan yang memakai nomor HP bersama ErrCustomerPhoneExists, untuk insert/update
+// yang lolos pengecekan awal tetapi ditolak unique index karena request paralel dengan nomor yang sama
+func (s *CustomerService) phoneConflict(outletID uint, phone string) (*model.Customer, error) {
+	existing, err := s.FindByPhone(outletID, phone)
+	if err != nil {
+		return nil, ErrCustomerPhoneExists
+	}
+	return existing, ErrCustomerPhoneExists
+}
+
+// searchCustomers membatasi query ke pelanggan outlet yang nama, nomor HP atau alamatnya cocok dengan q
+func searchCustomers(db *gorm.DB, outletID uint, q string) *gorm.DB {
+	query := db.Model(&model.Customer{}).Where("outlet_id = ?", outletID)
+
+	if q = strings.TrimSpace(q); q != "" {
+		like := "%" + q + "%"
+		conditions := "name LIKE ? OR phone LIKE ? OR address LIKE ?"
+		args := []interface{}{like, like, like}
+
+		// "0812..." juga harus menemukan nomor yang tersimpan sebagai "62812..."
+		if phone := model.NormalizePhone(q); phone != "" && phone != q {
+			conditions += " OR phone LIKE ?"
+			args = append(args, "%"+phone+"%")
+		}
+		query = query.Where(conditions, args...)
+	}
+	return query
+}
+
+// List mencari pelanggan outlet berdasarkan nama, nomor HP atau alamat, terbaru di atas
+func (s *CustomerService) List(outletID uint, filter model.CustomerFilter) ([]model.Customer, int64, error) {
+	query := searchCustomers(s.db, outletID, filter.Q)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	customers := make([]model.Customer, 0)
+	if err := query.Order("id DESC").
+		Limit(filter.Limit).
+		Offset((filter.Page - 1) * filter.Limit).
+		Find(&customers).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return customers, total, nil
+}
+
+// FindByPhone mencari pelanggan outlet dengan nomor HP yang sama setelah dinormalisasi
+func (s *CustomerService) FindByPhone(outletID uint, phone string) (*model.Customer, error) {
+	var customer model.Customer
+	err := s.db.Where("outlet_id = ? AND phone = ?", outletID, model.NormalizePhone(phone)).First(&customer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// Create menambah pelanggan baru. Jika nomor HP sudah dipakai, pelanggan lama dikembalikan bersama ErrCustomerPhoneExists.
+func (s *CustomerService) Create(outletID uint, input model.CustomerInput) (*model.Customer, error) {
+	phone := model.NormalizePhone(input.Phone)
+	if phone == "" {
+		return nil, ErrCustomerPhoneInvalid
+	}
+
+	existing, err := s.FindByPhone(outletID, phone)
+	if err == nil {
+		return existing, ErrCustomerPhoneExists
+	}
+	if !errors.Is(err, ErrCustomerNotFound) {
+		return nil, err
+	}
+
+	customer := model.Customer{
+		OutletID: outletID,
+		Name:     strings.TrimSpace(input.Name),
+		Phone:    phone,
+		Gender:   input.Gender,
+		OTP:      input.OTP,
+		Address:  input.Address,
+	}
+	if err := s.db.Create(&customer).Error; err != nil {
+		if isDuplicateKey(err) {
+			return s.phoneConflict(outletID, phone)
+		}
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// Update mengubah data pelanggan; nomor HP baru tidak boleh dipakai pelanggan lain di outlet yang sama
+func (s *CustomerService) Update(id, outletID uint, input model.UpdateCustomerInput) (*model.Customer, error) {
+	var customer model.Customer
+	if err := s.db.Where("id = ? AND outlet_id = ?", id, outletID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, err
+	}
+
+	updates := model.Customer{
+		Name:    strings.TrimSpace(input.Name),
+		Gender:  input.Gender,
+		Address: input.Address,
+	}
+	if strings.TrimSpace(input.Phone) != "" {
+		updates.Phone = model.NormalizePhone(input.Phone)
+		if updates.Phone == "" {
+			return nil, ErrCustomerPhoneInvalid
+		}
+
+		existing, err := s.FindByPhone(outletID, updates.Phone)
+		if err == nil && existing.ID != customer.ID {
+			return existing, ErrCustomerPhoneExists
+		}
+		if err != nil && !errors.Is(err, ErrCustomerNotFound) {
+			return nil, err
+		}
+	}
+
+	if err := s.db.Model(&customer).Updates(updates).Error; err != nil {
+		if isDuplicateKey(err) {
+			return s.phoneConflict(outletID, updates.Phone)
+		}
+		return nil, err
+	}
+	if err := s.db.First(&customer, customer.ID).Error; err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// Summary mengumpulkan profil pelanggan: total belanja, jumlah kunjungan, rata-rata per pesanan, parfum favorit,
+// kunjungan terakhir dan sisa tagihan dari pesanan yang tidak Batal, ditambah riwayat pesanan terbaru per halaman
+func (s *CustomerService) Summary(id, outletID uint, page, limit int) (*model.CustomerSummary, error) {
+	var customer model.Customer
+	if err := s.db.Where("id = ? AND outlet_id = ?", id, outletID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, err
+	}
+
+	orders := func() *gorm.DB {
+		return s.db.Model(&model.Transaction{}).
+			Where("customer_id = ? AND outlet_id = ? AND order_status <> ?", customer.ID, outletID, model.OrderStatusBatal)
+	}
+
+	var totals struct {
+		TotalSpend  float64
+		VisitCount  int64
+		Outstanding float64
+		UnpaidCount int64
+		LastVisit   *time.Time
+	}
+	if err := orders().
+		Select("COALESCE(SUM(total_price), 0) AS total_spend, COUNT(*) AS visit_count, " +
+			"COALESCE(SUM(GREATEST(total_price - paid_amount, 0)), 0) AS outstanding, " +
+			"COALESCE(SUM(CASE WHEN paid_amount < total_price THEN 1 ELSE 0 END), 0) AS unpaid_count, " +
+			"MAX(created_at) AS last_visit").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &model.CustomerSummary{
+		Customer:           customer,
+		TotalSpend:         roundPrice(totals.TotalSpend),
+		VisitCount:         totals.VisitCount,
+		OutstandingBalance: roundPrice(totals.Outstanding),
+		UnpaidCount:        totals.UnpaidCount,
+		LastVisit:          totals.LastVisit,
+		History:            make([]model.Transaction, 0),
+	}
+	if totals.VisitCount > 0 {
+		summary.AverageBasket = roundPrice(totals.TotalSpend / float64(totals.VisitCount))
+	}
+
+	var favourite model.CustomerParfumSummary
+	if err := orders().
+		Select("parfum_id, COUNT(*) AS order_count").
+		Where("parfum_id <> 0").
+		Group("parfum_id").
+		Order("order_count DESC, MAX(created_at) DESC").
+		Limit(1).
+		Scan(&favourite).Error; err != nil {
+		return nil, err
+	}
+	if favourite.ParfumID != 0 {
+		var parfum model.Parfum
+		if err := s.db.Where("prf_id = ?", favourite.ParfumID).First(&parfum).Error; err == nil {
+			favourite.NamaParfum = parfum.Parfum
+		}
+		summary.FavouriteParfum = &favourite
+	}
+
+	history := s.db.Model(&model.Transaction{}).Where("customer_id = ? AND outlet_id = ?", customer.ID, outletID)
+	if err := history.Session(&gorm.Session{}).Count(&summary.HistoryTotal).Error; err != nil {
+		return nil, err
+	}
+	if err := history.
+		Preload("Items").
+		Preload("Logs", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Payments").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&summary.History).Error; err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// loadMergeSet memuat pelanggan yang dipertahankan dan pelanggan yang akan digabung; semuanya harus milik outlet yang sama
+func (s *CustomerService) loadMergeSet(db *gorm.DB, outletID uint, input model.CustomerMergeInput) (*model.Customer, []model.Customer, error) {
+	mergedIDs := make([]uint, 0, len(input.MergedIDs))
+	seen := make(map[uint]bool, len(input.MergedIDs))
+	for _, id := range input.MergedIDs {
+		if id == input.SurvivorID {
+			return nil, nil, ErrCustomerMergeInvalid
+		}
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		mergedIDs = append(mergedIDs, id)
+	}
+	if len(mergedIDs) == 0 {
+		return nil, nil, ErrCustomerNotFound
+	}
+
+	var survivor model.Customer
+	if err := db.Where("id = ? AND outlet_id = ?", input.SurvivorID, outletID).First(&survivor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrCustomerNotFound
+		}
+		return nil, nil, err
+	}
+
+	var merged []model.Customer
+	if err := db.Where("id IN ? AND outlet_id = ?", mergedIDs, outletID).Order("id ASC").Find(&merged).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(merged) != len(mergedIDs) {
+		return nil, nil, ErrCustomerNotFound
+	}
+
+	return &survivor, merged, nil
+}
+
+// mergeResult adalah data survivor setelah penggabungan: field input yang diisi menimpa data survivor.
+// Nomor HP baru tidak boleh dipakai pelanggan lain di luar pelanggan yang digabung.
+func (s *CustomerService) mergeResult(db *gorm.DB, survivor *model.Customer, merged []model.Customer, input model.CustomerMergeInput) (model.Customer, *model.Customer, error) {
+	result := *survivor
+	if input.Name != nil && strings.TrimSpace(*input.Name) != "" {
+		result.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Gender != nil {
+		result.Gender = *input.Gender
+	}
+	if input.Address != nil {
+		result.Address = *input.Address
+	}
+	if input.Phone == nil || strings.TrimSpace(*input.Phone) == "" {
+		return result, nil, nil
+	}
+
+	result.Phone = model.NormalizePhone(*input.Phone)
+	if result.Phone == "" {
+		return result, nil, ErrCustomerPhoneInvalid
+	}
+
+	ids := []uint{survivor.ID}
+	for _, customer := range merged {
+		ids = append(ids, customer.ID)
+	}
+	var existing model.Customer
+	err := db.Where("outlet_id = ? AND phone = ? AND id NOT IN ?", survivor.OutletID, result.Phone, ids).First(&existing).Error
+	if err == nil {
+		return result, &existing, ErrCustomerPhoneExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return result, nil, err
+	}
+	return result, nil, nil
+}
+
+// mergeConflicts mengumpulkan field yang nilainya berbeda antar pelanggan, nilai kosong diabaikan
+func mergeConflicts(survivor *model.Customer, merged []model.Customer) []model.CustomerMergeConflict {
+	customers := append([]model.Customer{*survivor}, merged...)
+	fields := []struct {
+		name  string
+		value func(model.Customer) string
+	}{
+		{"name", func(c model.Customer) string { return c.Name }},
+		{"phone", func(c model.Customer) string { return c.Phone }},
+		{"gender", func(c model.Customer) string { return c.Gender }},
+		{"address", func(c model.Customer) string { return c.Address }},
+	}
+
+	conflicts := make([]model.CustomerMergeConflict, 0)
+	for _, field := range fields {
+		values := make([]model.CustomerMergeConflictValue, 0, len(customers))
+		distinct := make(map[string]bool)
+		for _, customer := range customers {
+			value := strings.TrimSpace(field.value(customer))
+			if value == "" {
+				continue
+			}
+			distinct[strings.ToLower(value)] = true
+			values = append(values, model.CustomerMergeConflictValue{CustomerID: customer.ID, Value: value})
+		}
+		if len(distinct) > 1 {
+			conflicts = append(conflicts, model.CustomerMergeConflict{Field: field.name, Values: values})
+		}
+	}
+	return conflicts
+}
+
+// mergeTransactionIDs mengembalikan ID pesanan milik pelanggan yang akan digabung
+func mergeTransactionIDs(db *gorm.DB, merged []model.Customer) ([]uint, error) {
+	customerIDs := make([]uint, 0, len(merged))
+	for _, customer := range merged {
+		customerIDs = append(customerIDs, customer.ID)
+	}
+
+	var transactionIDs []uint
+	err := db.Model(&model.Transaction{}).Where("customer_id IN ?", customerIDs).Pluck("id", &transactionIDs).Error
+	return transactionIDs, err
+}
+
+// PreviewMerge menampilkan field yang berbeda, jumlah pesanan per pelanggan dan hasil akhir survivor tanpa mengubah data.
+// Jika nomor HP baru sudah dipakai pelanggan lain, pelanggan tersebut dikembalikan bersama ErrCustomerPhoneExists.
+func (s *CustomerService) PreviewMerge(outletID uint, input model.CustomerMergeInput) (*model.CustomerMergePreview, *model.Customer, error) {
+	survivor, merged, err := s.loadMergeSet(s.db, outletID, input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, existing, err := s.mergeResult(s.db, survivor, merged, input)
+	if err != nil {
+		return nil, existing, err
+	}
+
+	preview := &model.CustomerMergePreview{
+		Survivor:          *survivor,
+		Merged:            merged,
+		Result:            result,
+		Conflicts:         mergeConflicts(survivor, merged),
+		TransactionCounts: make(map[uint]int64, len(merged)+1),
+	}
+
+	customerIDs := []uint{survivor.ID}
+	for _, customer := range merged {
+		customerIDs = append(customerIDs, customer.ID)
+		preview.TransactionCounts[customer.ID] = 0
+	}
+	preview.TransactionCounts[survivor.ID] = 0
+
+	var counts []struct {
+		CustomerID uint
+		Total      int64
+	}
+	if err := s.db.Model(&model.Transaction{}).
+		Select("customer_id, COUNT(*) AS total").
+		Where("customer_id IN ?", customerIDs).
+		Group("customer_id").
+		Scan(&counts).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, count := range counts {
+		preview.TransactionCounts[count.CustomerID] = count.Total
+		if count.CustomerID != survivor.ID {
+			preview.TransactionsMoved += count.Total
+		}
+	}
+
+	transactionIDs, err := mergeTransactionIDs(s.db, merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(transactionIDs) > 0 {
+		if err := s.db.Model(&model.NotaData{}).
+			Where("transaction_ref_id IN ?", transactionIDs).
+			Count(&preview.NotasUpdated).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return preview, nil, nil
+}
+
+// Merge menggabungkan pelanggan ganda ke survivor: pesanan, log WhatsApp dan nota dipindahkan ke survivor,
+// pelanggan yang digabung dihapus dan snapshot sebelum penggabungan disimpan di CustomerMerge.
+// Jika nomor HP baru sudah dipakai pelanggan lain, pelanggan tersebut dikembalikan bersama ErrCustomerPhoneExists.
+func (s *CustomerService) Merge(outletID uint, input model.CustomerMergeInput, mergedBy string) (*model.Customer, *model.CustomerMerge, error) {
+	var (
+		customer model.Customer
+		audit    model.CustomerMerge
+		existing *model.Customer
+	)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		survivor, merged, err := s.loadMergeSet(tx.Clauses(clause.Locking{Strength: "UPDATE"}).Session(&gorm.Session{}), outletID, input)
+		if err != nil {
+			return err
+		}
+
+		var result model.Customer
+		result, existing, err = s.mergeResult(tx, survivor, merged, input)
+		if err != nil {
+			return err
+		}
+
+		mergedIDs := make([]uint, 0, len(merged))
+		for _, c := range merged {
+			mergedIDs = append(mergedIDs, c.ID)
+		}
+
+		transactionIDs, err := mergeTransactionIDs(tx, merged)
+		if err != nil {
+			return err
+		}
+
+		var notasUpdated int64
+		if len(transactionIDs) > 0 {
+			if err := tx.Model(&model.Transaction{}).
+				Where("id IN ?", transactionIDs).
+				UpdateColumn("customer_id", survivor.ID).Error; err != nil {
+				return err
+			}
+
+			// Nota pesanan yang dipindahkan ikut memakai nama dan nomor HP survivor
+			update := tx.Model(&model.NotaData{}).
+				Where("transaction_ref_id IN ?", transactionIDs).
+				Updates(map[string]interface{}{
+					"customer_name":  result.Name,
+					"customer_phone": result.Phone,
+				})
+			if update.Error != nil {
+				return update.Error
+			}
+			notasUpdated = update.RowsAffected
+		}
+
+		if err := tx.Model(&model.WhatsappMessageLog{}).
+			Where("customer_id IN ?", mergedIDs).
+			UpdateColumn("customer_id", survivor.ID).Error; err != nil {
+			return err
+		}
+
+		// Hapus pelanggan lama dulu agar nomor HP mereka bisa dipakai survivor tanpa melanggar unique index
+		if err := tx.Where("id IN ?", mergedIDs).Delete(&model.Customer{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(survivor).
+			Select("name", "phone", "gender", "address").
+			Updates(&result).Error; err != nil {
+			return err
+		}
+
+		idsJSON, _ := json.Marshal(mergedIDs)
+		survivorJSON, _ := json.Marshal(survivor)
+		mergedJSON, _ := json.Marshal(merged)
+		audit = model.CustomerMerge{
+			OutletID:          outletID,
+			SurvivorID:        survivor.ID,
+			MergedIDs:         string(idsJSON),
+			SurvivorBefore:    string(survivorJSON),
+			MergedCustomers:   string(mergedJSON),
+			TransactionsMoved: int64(len(transactionIDs)),
+			NotasUpdated:      notasUpdated,
+			Notes:             strings.TrimSpace(input.Notes),
+			MergedBy:          mergedBy,
+		}
+		if err := tx.Create(&audit).Error; err != nil {
+			return err
+		}
+
+		return tx.First(&customer, survivor.ID).Error
+	})
+	if isDuplicateKey(err) && input.Phone != nil {
+		existing, err = s.phoneConflict(outletID, *input.Phone)
+	}
+	if err != nil {
+		return existing, nil, err
+	}
+
+	// Penggabungan bisa menghapus nomor ganda terakhir yang menahan unique index
+	if err := s.EnsurePhoneIndex(); err != nil {
+		log.Printf("Gagal memasang unique index nomor HP pelanggan setelah penggabungan: %v", err)
+	}
+
+	return &customer, &audit, nil
+}
+
+// EnsurePhoneIndex menormalisasi nomor HP pelanggan lama lalu memasang unique index (outlet_id, phone).
+// Index baru dipasang setelah tidak ada nomor ganda; sampai saat itu keunikan dijaga oleh Create/Update.
+// Dijalankan setiap start dan dicoba lagi setelah Merge menghapus pelanggan ganda.
+func (s *CustomerService) EnsurePhoneIndex() error {
+	if s.db.Migrator().HasIndex(&model.Customer{}, customerPhoneIndex) {
+		return nil
+	}
+
+	var customers []model.Customer
+	err := s.db.Select("id", "phone").FindInBatches(&customers, 500, func(tx *gorm.DB, batch int) error {
+		for _, customer := range customers {
+			if phone := model.NormalizePhone(customer.Phone); phone != customer.Phone {
+				if err := s.db.Model(&model.Customer{}).Where("id = ?", customer.ID).UpdateColumn("phone", phone).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	duplicateGroups := s.db.Model(&model.Customer{}).
+		Select("outlet_id").
+		Group("outlet_id, phone").
+		Having("COUNT(*) > 1")
+
+	var duplicates int64
+	if err := s.db.Table("(?) AS duplicate_phones", duplicateGroups).Count(&duplicates).Error; err != nil {
+		return err
+	}
+	if duplicates > 0 {
+		log.Printf("Unique index %s belum dipasang: %d nomor HP pelanggan masih ganda dalam satu outlet", customerPhoneIndex, duplicates)
+		return nil
+	}
+
+	return s.db.Exec("CREATE UNIQUE INDEX " + customerPhoneIndex + " ON customers (outlet_id, phone)").Error
+}
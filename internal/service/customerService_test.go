@@ -0,0 +1,91 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newTestCustomerService(t *testing.T) (*CustomerService, *gorm.DB) {
+	t.Helper()
+
+	db := newTestDB(t, &model.Customer{}, &model.Transaction{}, &model.NotaData{}, &model.WhatsappMessageLog{}, &model.CustomerMerge{})
+	return NewCustomerService(db), db
+}
+
+func TestCustomerCreatePhone(t *testing.T) {
+	s, _ := newTestCustomerService(t)
+	first, err := s.Create(1, model.CustomerInput{Name: "Budi", Phone: "0812-3456-789"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		outletID     uint
+		phone        string
+		wantErr      error
+		wantExisting bool
+	}{
+		{name: "format lain nomor yang sama", outletID: 1, phone: "+62 812 3456 789", wantErr: ErrCustomerPhoneExists, wantExisting: true},
+		{name: "tanpa 0 di depan", outletID: 1, phone: "8123456789", wantErr: ErrCustomerPhoneExists, wantExisting: true},
+		{name: "nomor sama di outlet lain", outletID: 2, phone: "08123456789"},
+		{name: "nomor kosong", outletID: 1, phone: "-", wantErr: ErrCustomerPhoneInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customer, err := s.Create(tt.outletID, model.CustomerInput{Name: "Budi", Phone: tt.phone})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create(%q) err = %v, want %v", tt.phone, err, tt.wantErr)
+			}
+			if tt.wantExisting && (customer == nil || customer.ID != first.ID) {
+				t.Errorf("Create(%q) tidak mengembalikan pelanggan yang sudah ada: %+v", tt.phone, customer)
+			}
+		})
+	}
+}
+
+func TestEnsurePhoneIndexAfterMerge(t *testing.T) {
+	s, db := newTestCustomerService(t)
+
+	// Data lama sebelum normalisasi: dua format dari nomor yang sama di outlet 1
+	legacy := []model.Customer{
+		{OutletID: 1, Name: "Budi", Phone: "0812-3456-789"},
+		{OutletID: 1, Name: "Budi S", Phone: "628123456789"},
+		{OutletID: 1, Name: "Sari", Phone: "0813 1111 2222"},
+	}
+	if err := db.Create(&legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.EnsurePhoneIndex(); err != nil {
+		t.Fatalf("EnsurePhoneIndex: %v", err)
+	}
+	if db.Migrator().HasIndex(&model.Customer{}, customerPhoneIndex) {
+		t.Fatal("unique index terpasang walaupun nomor masih ganda")
+	}
+	var sari model.Customer
+	db.First(&sari, legacy[2].ID)
+	if sari.Phone != "6281311112222" {
+		t.Errorf("nomor lama tidak dinormalisasi: %q", sari.Phone)
+	}
+
+	if _, _, err := s.Merge(1, model.CustomerMergeInput{SurvivorID: legacy[0].ID, MergedIDs: []uint{legacy[1].ID}}, "Admin"); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if !db.Migrator().HasIndex(&model.Customer{}, customerPhoneIndex) {
+		t.Fatal("unique index tidak dipasang setelah nomor ganda digabung")
+	}
+
+	// Insert yang lolos pengecekan aplikasi (request paralel) ditolak index dan dikenali sebagai nomor ganda
+	err := db.Create(&model.Customer{OutletID: 1, Name: "Budi", Phone: "628123456789"}).Error
+	if !isDuplicateKey(err) {
+		t.Fatalf("insert nomor ganda err = %v, want duplicate key", err)
+	}
+	existing, err := s.phoneConflict(1, "628123456789")
+	if !errors.Is(err, ErrCustomerPhoneExists) || existing == nil || existing.ID != legacy[0].ID {
+		t.Errorf("phoneConflict = %+v, %v, want pelanggan %d", existing, err, legacy[0].ID)
+	}
+}
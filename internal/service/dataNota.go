@@ -1,11 +1,18 @@
 package service
 
 import (
+	"BackendFramework/internal/config"
 	"BackendFramework/internal/database"
 	"BackendFramework/internal/model"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	"gorm.io/gorm"
@@ -17,8 +24,12 @@ type NotaService interface {
     GetNotaByTransactionID(transactionID string) (*model.NotaData, error)
     GetNotasByOutlet(outletID uint, page, limit int) ([]model.NotaData, int64, error)
     PrepareNotaForPrint(notaDataID uint) (*model.NotaPrintFormat, error)
+    PrepareNotaForView(notaDataID uint) (*model.NotaPrintFormat, error)
+    GetNotaByPublicToken(token string) (*model.NotaData, error)
+    GetShareURL(notaDataID uint) (string, error)
     GenerateNotaPreview(input *model.NotaPreviewInput) (*model.NotaPrintFormat, error)
-    VoidNota(notaDataID uint, reason string) error
+    GenerateNotaFromTransaction(transactionID uint, outletID uint, cashierName string) (*model.NotaData, error)
+    VoidNota(notaDataID uint, reason string, adminName string) error
     ReprintNota(notaDataID uint) (*model.NotaPrintFormat, error)
     GetPrintHistory(notaDataID uint) (*model.NotaData, error)
 }
@@ -39,9 +50,11 @@ func (s *notaService) GenerateNota(input *model.NotaGenerateInput) (*model.NotaD
         return nil, errors.New("outlet not found")
     }
 
-    var existing model.NotaData
-    if err := s.db.Where("transaction_id = ?", input.TransactionID).First(&existing).Error; err == nil {
-        return nil, errors.New("transaction ID already exists")
+    if input.TransactionID != "" {
+        var existing model.NotaData
+        if err := s.db.Where("transaction_id = ?", input.TransactionID).First(&existing).Error; err == nil {
+            return nil, errors.New("transaction ID already exists")
+        }
     }
 
     subtotal := 0.0
@@ -62,18 +75,22 @@ func (s *notaService) GenerateNota(input *model.NotaGenerateInput) (*model.NotaD
 
     total := subtotal + tax + input.ServiceCharge - discount
 
-    change := input.PaymentAmount - total
-    if change < 0 {
-        return nil, errors.New("insufficient payment amount")
-    }
+    // Pembayaran kurang dari total dicatat sebagai DP, sisanya menjadi piutang
+    change, outstanding := settleNotaPayment(input.PaymentAmount, total)
 
     itemsJSON, err := json.Marshal(input.Items)
     if err != nil {
         return nil, err
     }
 
+    publicToken, err := generatePublicToken()
+    if err != nil {
+        return nil, err
+    }
+
     notaData := &model.NotaData{
         OutletID:        input.OutletID,
+        PublicToken:     &publicToken,
         TransactionID:   input.TransactionID,
         TransactionDate: input.TransactionDate,
         CustomerName:    input.CustomerName,
@@ -89,6 +106,8 @@ func (s *notaService) GenerateNota(input *model.NotaGenerateInput) (*model.NotaD
         Total:           total,
         PaymentAmount:   input.PaymentAmount,
         Change:          change,
+        Outstanding:     outstanding,
+        PaymentStatus:   model.DerivePaymentStatus(input.PaymentAmount, total),
         PaymentMethod:   input.PaymentMethod,
         Notes:           input.Notes,
         Status:          "completed",
@@ -96,6 +115,13 @@ func (s *notaService) GenerateNota(input *model.NotaGenerateInput) (*model.NotaD
     }
 
     err = s.db.Transaction(func(tx *gorm.DB) error {
+        if notaData.TransactionID == "" {
+            invoice, err := NextInvoiceNumber(tx, input.OutletID)
+            if err != nil {
+                return err
+            }
+            notaData.TransactionID = invoice
+        }
 
         if err := tx.Create(notaData).Error; err != nil {
             return err
@@ -191,6 +217,22 @@ func (s *notaService) GetNotasByOutlet(outletID uint, page, limit int) ([]model.
 
 
 func (s *notaService) PrepareNotaForPrint(notaDataID uint) (*model.NotaPrintFormat, error) {
+    printFormat, err := s.PrepareNotaForView(notaDataID)
+    if err != nil {
+        return nil, err
+    }
+
+    now := time.Now()
+    s.db.Model(&model.NotaData{}).Where("id = ?", notaDataID).Updates(map[string]interface{}{
+        "print_count":     gorm.Expr("print_count + 1"),
+        "last_printed_at": now,
+    })
+
+    return printFormat, nil
+}
+
+// PrepareNotaForView menyusun format nota tanpa menambah print_count (untuk PDF / link publik)
+func (s *notaService) PrepareNotaForView(notaDataID uint) (*model.NotaPrintFormat, error) {
     notaData, err := s.GetNotaByID(notaDataID)
     if err != nil {
         return nil, err
@@ -211,15 +253,60 @@ func (s *notaService) PrepareNotaForPrint(notaDataID uint) (*model.NotaPrintForm
 
     printFormat.FormattedTotal = s.formatTotal(notaData, printFormat.PrintWidth)
 
-    now := time.Now()
-    s.db.Model(&model.NotaData{}).Where("id = ?", notaDataID).Updates(map[string]interface{}{
-        "print_count":     gorm.Expr("print_count + 1"),
-        "last_printed_at": now,
-    })
+    if settings.ShowLogo {
+        printFormat.LogoBase64 = outletLogoDataURL(notaData.Outlet.Photo)
+    }
 
     return printFormat, nil
 }
 
+func (s *notaService) GetNotaByPublicToken(token string) (*model.NotaData, error) {
+    var notaData model.NotaData
+    if token == "" {
+        return nil, gorm.ErrRecordNotFound
+    }
+    if err := s.db.Where("public_token = ?", token).First(&notaData).Error; err != nil {
+        return nil, err
+    }
+
+    return &notaData, nil
+}
+
+// GetShareURL mengembalikan link publik nota, token dibuat jika nota lama belum punya
+func (s *notaService) GetShareURL(notaDataID uint) (string, error) {
+    var notaData model.NotaData
+    if err := s.db.First(&notaData, notaDataID).Error; err != nil {
+        return "", err
+    }
+
+    if notaData.PublicToken == nil || *notaData.PublicToken == "" {
+        token, err := generatePublicToken()
+        if err != nil {
+            return "", err
+        }
+        if err := s.db.Model(&notaData).Update("public_token", token).Error; err != nil {
+            return "", err
+        }
+        notaData.PublicToken = &token
+    }
+
+    return NotaPublicURL(*notaData.PublicToken), nil
+}
+
+// NotaPublicURL menyusun link nota yang bisa dibuka pelanggan tanpa login
+func NotaPublicURL(token string) string {
+    return config.APP_BASE_URL + "/v1/public/nota/" + token
+}
+
+// generatePublicToken membuat token 256-bit agar link nota tidak bisa ditebak
+func generatePublicToken() (string, error) {
+    buf := make([]byte, 32)
+    if _, err := rand.Read(buf); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(buf), nil
+}
+
 func (s *notaService) GenerateNotaPreview(input *model.NotaPreviewInput) (*model.NotaPrintFormat, error) {
     var settings model.NotaSettings
     if err := s.db.Where("outlet_id = ?", input.OutletID).First(&settings).Error; err != nil {
@@ -243,7 +330,7 @@ func (s *notaService) GenerateNotaPreview(input *model.NotaPreviewInput) (*model
     }
 
     total := subtotal + tax + input.Data.ServiceCharge - discount
-    change := input.Data.PaymentAmount - total
+    change, outstanding := settleNotaPayment(input.Data.PaymentAmount, total)
 
     notaData := &model.NotaData{
         TransactionID:   input.Data.TransactionID,
@@ -261,6 +348,8 @@ func (s *notaService) GenerateNotaPreview(input *model.NotaPreviewInput) (*model
         Total:           total,
         PaymentAmount:   input.Data.PaymentAmount,
         Change:          change,
+        Outstanding:     outstanding,
+        PaymentStatus:   model.DerivePaymentStatus(input.Data.PaymentAmount, total),
         PaymentMethod:   input.Data.PaymentMethod,
         Notes:           input.Data.Notes,
     }
@@ -274,10 +363,39 @@ func (s *notaService) GenerateNotaPreview(input *model.NotaPreviewInput) (*model
     printFormat.FormattedItems = s.formatItems(notaData.Items, printFormat.PrintWidth)
     printFormat.FormattedTotal = s.formatTotal(notaData, printFormat.PrintWidth)
 
+    if settings.ShowLogo {
+        var outlet model.Outlet
+        if err := s.db.First(&outlet, input.OutletID).Error; err == nil {
+            printFormat.LogoBase64 = outletLogoDataURL(outlet.Photo)
+        }
+    }
+
     return printFormat, nil
 }
 
-func (s *notaService) VoidNota(notaDataID uint, reason string) error {
+// outletLogoDataURL membaca foto outlet dari folder upload sebagai data URL, kosong jika tidak tersedia
+func outletLogoDataURL(photo string) string {
+    if photo == "" {
+        return ""
+    }
+
+    content, err := os.ReadFile(filepath.Join(OutletUploadDir, filepath.Base(photo)))
+    if err != nil {
+        return ""
+    }
+
+    return "data:" + http.DetectContentType(content) + ";base64," + base64.StdEncoding.EncodeToString(content)
+}
+
+// settleNotaPayment menghitung kembalian atau sisa tagihan dari jumlah yang dibayar
+func settleNotaPayment(paymentAmount, total float64) (change float64, outstanding float64) {
+    if paymentAmount >= total {
+        return paymentAmount - total, 0
+    }
+    return 0, total - paymentAmount
+}
+
+func (s *notaService) VoidNota(notaDataID uint, reason string, adminName string) error {
     var notaData model.NotaData
     if err := s.db.First(&notaData, notaDataID).Error; err != nil {
         return err
@@ -287,10 +405,27 @@ func (s *notaService) VoidNota(notaDataID uint, reason string) error {
         return errors.New("nota already voided")
     }
 
-    return s.db.Model(&notaData).Updates(map[string]interface{}{
-        "status": "void",
-        "notes":  notaData.Notes + " | VOID: " + reason,
-    }).Error
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(&notaData).Updates(map[string]interface{}{
+            "status": "void",
+            "notes":  notaData.Notes + " | VOID: " + reason,
+        }).Error; err != nil {
+            return err
+        }
+
+        // Nota dari pesanan ikut membatalkan pesanannya
+        if notaData.TransactionRefID == nil {
+            return nil
+        }
+        var transaction model.Transaction
+        if err := tx.First(&transaction, *notaData.TransactionRefID).Error; err != nil {
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return nil
+            }
+            return err
+        }
+        return markTransactionVoided(tx, &transaction, reason, adminName)
+    })
 }
 
 
@@ -360,7 +495,11 @@ func (s *notaService) formatTotal(nota *model.NotaData, width int) string {
     total += separator + "\n"
     total += fmt.Sprintf("%-*s %12.2f\n", width-13, "TOTAL", nota.Total)
     total += fmt.Sprintf("%-*s %12.2f\n", width-13, "Payment", nota.PaymentAmount)
-    total += fmt.Sprintf("%-*s %12.2f\n", width-13, "Change", nota.Change)
+    if nota.Outstanding > 0 {
+        total += fmt.Sprintf("%-*s %12.2f\n", width-13, "Outstanding", nota.Outstanding)
+    } else {
+        total += fmt.Sprintf("%-*s %12.2f\n", width-13, "Change", nota.Change)
+    }
     total += separator
 
     return total
@@ -8,19 +8,22 @@ import (
 	"net/http"
 
 	"BackendFramework/internal/config"
+	"BackendFramework/internal/model"
 )
 
 type InfobipService struct {
-	APIKey  string
-	BaseURL string
-	Sender  string
+	APIKey    string
+	BaseURL   string
+	Sender    string
+	SMSSender string
 }
 
 func NewInfobipService() *InfobipService {
 	return &InfobipService{
-		APIKey:  config.INFOBIP_API_KEY,
-		BaseURL: "https://api.infobip.com",
-		Sender:  config.INFOBIP_SENDER,
+		APIKey:    config.INFOBIP_API_KEY,
+		BaseURL:   "https://api.infobip.com",
+		Sender:    config.INFOBIP_SENDER,
+		SMSSender: config.INFOBIP_SMS_SENDER,
 	}
 }
 
@@ -38,6 +41,20 @@ type InfobipMessageContent struct {
 	Text string `json:"text"`
 }
 
+type InfobipSMSRequest struct {
+	Messages []InfobipSMSMessage `json:"messages"`
+}
+
+type InfobipSMSMessage struct {
+	From         string               `json:"from,omitempty"`
+	Destinations []InfobipDestination `json:"destinations"`
+	Text         string               `json:"text"`
+}
+
+type InfobipDestination struct {
+	To string `json:"to"`
+}
+
 type InfobipResponse struct {
 	Messages []struct {
 		MessageID string `json:"messageId"`
@@ -52,20 +69,14 @@ type InfobipResponse struct {
 	} `json:"messages"`
 }
 
-func (s *InfobipService) SendWhatsAppOTP(phoneNumber, otpCode string) error {
-	// Validasi sender dan API key
-	if s.Sender == "" {
-		return fmt.Errorf("INFOBIP_SENDER tidak diset. Cek config/infobip.go dan .env")
-	}
-	if s.APIKey == "" {
-		return fmt.Errorf("INFOBIP_API_KEY tidak diset. Cek config/infobip.go dan .env")
-	}
-
-	// Konversi nomor HP ke format internasional
-	if len(phoneNumber) > 0 && phoneNumber[0] == '0' {
-		phoneNumber = "62" + phoneNumber[1:]
-	}
+// InfobipSendResult adalah hasil kirim satu pesan, status awal dari Infobip (misal PENDING)
+type InfobipSendResult struct {
+	MessageID   string
+	GroupName   string
+	Description string
+}
 
+func (s *InfobipService) SendWhatsAppOTP(phoneNumber, otpCode string) error {
 	message := fmt.Sprintf(
 		"🔐 *Kode Verifikasi Anda*\n\n"+
 			"Kode OTP: *%s*\n\n"+
@@ -75,11 +86,25 @@ func (s *InfobipService) SendWhatsAppOTP(phoneNumber, otpCode string) error {
 		otpCode,
 	)
 
+	_, err := s.SendWhatsAppText(phoneNumber, message)
+	return err
+}
+
+// SendWhatsAppText mengirim pesan teks bebas lewat channel WhatsApp Infobip
+func (s *InfobipService) SendWhatsAppText(phoneNumber, message string) (*InfobipSendResult, error) {
+	// Validasi sender dan API key
+	if s.Sender == "" {
+		return nil, fmt.Errorf("INFOBIP_SENDER tidak diset. Cek config/infobip.go dan .env")
+	}
+	if s.APIKey == "" {
+		return nil, fmt.Errorf("INFOBIP_API_KEY tidak diset. Cek config/infobip.go dan .env")
+	}
+
 	payload := InfobipWhatsAppRequest{
 		Messages: []InfobipMessage{
 			{
 				From: s.Sender,
-				To:   phoneNumber,
+				To:   model.NormalizePhone(phoneNumber),
 				Content: InfobipMessageContent{
 					Text: message,
 				},
@@ -87,18 +112,43 @@ func (s *InfobipService) SendWhatsAppOTP(phoneNumber, otpCode string) error {
 		},
 	}
 
+	return s.send("/whatsapp/1/message/text", payload)
+}
+
+// SendSMSText mengirim SMS lewat Infobip SMS API. Sender SMS memakai INFOBIP_SMS_SENDER,
+// jika kosong Infobip memakai sender default akun.
+func (s *InfobipService) SendSMSText(phoneNumber, message string) (*InfobipSendResult, error) {
+	if s.APIKey == "" {
+		return nil, fmt.Errorf("INFOBIP_API_KEY tidak diset. Cek config/infobip.go dan .env")
+	}
+
+	payload := InfobipSMSRequest{
+		Messages: []InfobipSMSMessage{
+			{
+				From:         s.SMSSender,
+				Destinations: []InfobipDestination{{To: model.NormalizePhone(phoneNumber)}},
+				Text:         message,
+			},
+		},
+	}
+
+	return s.send("/sms/2/text/advanced", payload)
+}
+
+// send mengirim payload ke endpoint Infobip dan membaca status pesan pertama
+func (s *InfobipService) send(path string, payload interface{}) (*InfobipSendResult, error) {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("error marshaling request: %v", err)
+		return nil, fmt.Errorf("error marshaling request: %v", err)
 	}
 
 	req, err := http.NewRequest(
 		"POST",
-		s.BaseURL+"/whatsapp/1/message/text",
+		s.BaseURL+path,
 		bytes.NewBuffer(jsonData),
 	)
 	if err != nil {
-		return fmt.Errorf("error creating request: %v", err)
+		return nil, fmt.Errorf("error creating request: %v", err)
 	}
 
 	req.Header.Set("Authorization", "App "+s.APIKey)
@@ -107,27 +157,32 @@ func (s *InfobipService) SendWhatsAppOTP(phoneNumber, otpCode string) error {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("error sending request: %v", err)
+		return nil, fmt.Errorf("error sending request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("error reading response: %v", err)
+		return nil, fmt.Errorf("error reading response: %v", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("infobip API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("infobip API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	var infobipResp InfobipResponse
 	if err := json.Unmarshal(body, &infobipResp); err != nil {
-		return fmt.Errorf("error parsing response: %v", err)
+		return nil, fmt.Errorf("error parsing response: %v", err)
 	}
 
 	if len(infobipResp.Messages) == 0 {
-		return fmt.Errorf("no messages in response")
+		return nil, fmt.Errorf("no messages in response")
 	}
 
-	return nil
+	sent := infobipResp.Messages[0]
+	return &InfobipSendResult{
+		MessageID:   sent.MessageID,
+		GroupName:   sent.Status.GroupName,
+		Description: sent.Status.Description,
+	}, nil
 }
\ No newline at end of file
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidInvoiceFormat dikembalikan jika template invoice tidak bisa dipakai
+	ErrInvalidInvoiceFormat = errors.New("format invoice wajib memuat {OUTLET} dan {SEQ} atau {SEQ:n}")
+	// ErrInvoiceFormatPeriod dikembalikan jika token tanggal di template lebih kasar dari periode reset,
+	// sehingga nomor urut yang kembali ke 1 menghasilkan nomor invoice yang sudah pernah dipakai
+	ErrInvoiceFormatPeriod = errors.New("format invoice harus memuat tanggal sesuai periode reset: " +
+		"{YYMMDD} atau tahun+{MM}+{DD} untuk harian, {YYMM} atau tahun+{MM} untuk bulanan, {YYYY} atau {YY} untuk tahunan")
+)
+
+var seqTokenPattern = regexp.MustCompile(`\{SEQ(?::(\d+))?\}`)
+
+// ValidateInvoiceFormat memastikan template menghasilkan nomor unik lintas outlet dan lintas periode reset
+func ValidateInvoiceFormat(format, reset string) error {
+	if !strings.Contains(format, "{OUTLET}") || !seqTokenPattern.MatchString(format) {
+		return ErrInvalidInvoiceFormat
+	}
+
+	year, month, day := invoiceDateParts(format)
+	switch reset {
+	case model.InvoiceResetDaily:
+		if !year || !month || !day {
+			return ErrInvoiceFormatPeriod
+		}
+	case model.InvoiceResetYearly:
+		if !year {
+			return ErrInvoiceFormatPeriod
+		}
+	default:
+		if !year || !month {
+			return ErrInvoiceFormatPeriod
+		}
+	}
+	return nil
+}
+
+// invoiceDateParts melaporkan komponen tanggal yang dimuat template invoice
+func invoiceDateParts(format string) (year, month, day bool) {
+	has := func(tokens ...string) bool {
+		for _, token := range tokens {
+			if strings.Contains(format, token) {
+				return true
+			}
+		}
+		return false
+	}
+
+	year = has("{YYYY}", "{YY}", "{YYMM}", "{YYMMDD}")
+	month = has("{MM}", "{YYMM}", "{YYMMDD}")
+	day = has("{DD}", "{YYMMDD}")
+	return year, month, day
+}
+
+// FormatInvoiceNumber mengisi template seperti {OUTLET}/{YYMM}/{SEQ:5}.
+// Token yang didukung: {OUTLET}, {YYYY}, {YY}, {MM}, {DD}, {YYMM}, {YYMMDD}, {SEQ} dan {SEQ:n} (n = panjang zero padding).
+func FormatInvoiceNumber(format string, outletID uint, seq int64, t time.Time) string {
+	replacer := strings.NewReplacer(
+		"{OUTLET}", strconv.FormatUint(uint64(outletID), 10),
+		"{YYMMDD}", t.Format("060102"),
+		"{YYMM}", t.Format("0601"),
+		"{YYYY}", t.Format("2006"),
+		"{YY}", t.Format("06"),
+		"{MM}", t.Format("01"),
+		"{DD}", t.Format("02"),
+	)
+	result := replacer.Replace(format)
+
+	return seqTokenPattern.ReplaceAllStringFunc(result, func(token string) string {
+		match := seqTokenPattern.FindStringSubmatch(token)
+		if match[1] == "" {
+			return strconv.FormatInt(seq, 10)
+		}
+		width, _ := strconv.Atoi(match[1])
+		return fmt.Sprintf("%0*d", width, seq)
+	})
+}
+
+// invoicePeriod menentukan kunci periode counter sesuai jadwal reset
+func invoicePeriod(reset string, t time.Time) string {
+	switch reset {
+	case model.InvoiceResetDaily:
+		return t.Format("20060102")
+	case model.InvoiceResetYearly:
+		return t.Format("2006")
+	default:
+		return t.Format("200601")
+	}
+}
+
+// NextInvoiceNumber mengambil nomor invoice berikutnya untuk outlet.
+// Wajib dipanggil di dalam transaksi database yang sama dengan insert dokumennya:
+// baris counter dikunci sampai commit, dan ikut di-rollback jika insert gagal sehingga urutan tetap tanpa celah.
+func NextInvoiceNumber(tx *gorm.DB, outletID uint) (string, error) {
+	format := model.DefaultInvoiceFormat
+	reset := model.InvoiceResetMonthly
+
+	var settings model.NotaSettings
+	if err := tx.Where("outlet_id = ?", outletID).First(&settings).Error; err == nil {
+		if settings.InvoiceFormat != "" {
+			format = settings.InvoiceFormat
+		}
+		if settings.InvoiceReset != "" {
+			reset = settings.InvoiceReset
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	now := time.Now()
+	period := invoicePeriod(reset, now)
+
+	seed := model.InvoiceSequence{OutletID: outletID, Period: period}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+		return "", err
+	}
+
+	var sequence model.InvoiceSequence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("outlet_id = ? AND period = ?", outletID, period).
+		First(&sequence).Error; err != nil {
+		return "", err
+	}
+
+	sequence.LastNumber++
+	if err := tx.Model(&sequence).Update("last_number", sequence.LastNumber).Error; err != nil {
+		return "", err
+	}
+
+	return FormatInvoiceNumber(format, outletID, sequence.LastNumber, now), nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFormatInvoiceNumber(t *testing.T) {
+	at := time.Date(2026, 10, 7, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{model.DefaultInvoiceFormat, "3/2610/00042"},
+		{"INV-{OUTLET}-{YYMMDD}-{SEQ:3}", "INV-3-261007-042"},
+		{"{OUTLET}/{YYYY}/{MM}/{DD}/{SEQ}", "3/2026/10/07/42"},
+		{"{OUTLET}{YY}{SEQ:6}", "326000042"},
+	}
+	for _, tt := range tests {
+		if got := FormatInvoiceNumber(tt.format, 3, 42, at); got != tt.want {
+			t.Errorf("FormatInvoiceNumber(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestValidateInvoiceFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		reset  string
+		want   error
+	}{
+		{model.DefaultInvoiceFormat, model.InvoiceResetMonthly, nil},
+		{model.DefaultInvoiceFormat, model.InvoiceResetYearly, nil},
+		{model.DefaultInvoiceFormat, model.InvoiceResetDaily, ErrInvoiceFormatPeriod},
+		{"{OUTLET}/{YYMMDD}/{SEQ:4}", model.InvoiceResetDaily, nil},
+		{"{OUTLET}/{YYMM}{DD}/{SEQ:4}", model.InvoiceResetDaily, nil},
+		{"{OUTLET}/{YY}{MM}{DD}/{SEQ}", model.InvoiceResetDaily, nil},
+		{"{OUTLET}/{MM}{DD}/{SEQ}", model.InvoiceResetDaily, ErrInvoiceFormatPeriod},
+		{"{OUTLET}/{YYYY}/{SEQ}", model.InvoiceResetYearly, nil},
+		{"{OUTLET}/{YYYY}/{SEQ}", model.InvoiceResetMonthly, ErrInvoiceFormatPeriod},
+		{"{OUTLET}/{MM}/{SEQ}", model.InvoiceResetMonthly, ErrInvoiceFormatPeriod},
+		{"{OUTLET}/{SEQ:6}", model.InvoiceResetYearly, ErrInvoiceFormatPeriod},
+		{"{YYMM}/{SEQ:5}", model.InvoiceResetMonthly, ErrInvalidInvoiceFormat},
+		{"{OUTLET}/{YYMM}", model.InvoiceResetMonthly, ErrInvalidInvoiceFormat},
+	}
+	for _, tt := range tests {
+		err := ValidateInvoiceFormat(tt.format, tt.reset)
+		if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("ValidateInvoiceFormat(%q, %q) = %v, want %v", tt.format, tt.reset, err, tt.want)
+		}
+	}
+}
+
+func TestInvoicePeriod(t *testing.T) {
+	at := time.Date(2026, 10, 7, 23, 59, 0, 0, time.UTC)
+
+	tests := []struct {
+		reset string
+		want  string
+	}{
+		{model.InvoiceResetDaily, "20261007"},
+		{model.InvoiceResetMonthly, "202610"},
+		{model.InvoiceResetYearly, "2026"},
+		{"", "202610"},
+	}
+	for _, tt := range tests {
+		if got := invoicePeriod(tt.reset, at); got != tt.want {
+			t.Errorf("invoicePeriod(%q) = %q, want %q", tt.reset, got, tt.want)
+		}
+	}
+}
+
+func TestNextInvoiceNumber(t *testing.T) {
+	db := newTestDB(t, &model.NotaSettings{}, &model.InvoiceSequence{})
+	if err := db.Create(&model.NotaSettings{OutletID: 2, InvoiceFormat: "B{OUTLET}-{YYYY}-{SEQ:3}", InvoiceReset: model.InvoiceResetYearly}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		outletID uint
+		want     string
+	}{
+		{1, FormatInvoiceNumber(model.DefaultInvoiceFormat, 1, 1, now)},
+		{1, FormatInvoiceNumber(model.DefaultInvoiceFormat, 1, 2, now)},
+		{2, "B2-" + now.Format("2006") + "-001"},
+		{1, FormatInvoiceNumber(model.DefaultInvoiceFormat, 1, 3, now)},
+		{2, "B2-" + now.Format("2006") + "-002"},
+	}
+	for i, tt := range tests {
+		got, err := NextInvoiceNumber(db, tt.outletID)
+		if err != nil {
+			t.Fatalf("#%d NextInvoiceNumber: %v", i, err)
+		}
+		if got != tt.want {
+			t.Errorf("#%d NextInvoiceNumber(%d) = %q, want %q", i, tt.outletID, got, tt.want)
+		}
+	}
+
+	var sequences []model.InvoiceSequence
+	db.Order("outlet_id").Find(&sequences)
+	if len(sequences) != 2 || sequences[0].Period != invoicePeriod(model.InvoiceResetMonthly, now) || sequences[1].Period != now.Format("2006") {
+		t.Errorf("counter per outlet/periode tidak sesuai: %+v", sequences)
+	}
+}
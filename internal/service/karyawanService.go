@@ -190,6 +190,18 @@ func (s *KaryawanService) Delete(id uint, outletID *uint) error {
 	return nil
 }
 
+// GetByEmailAnyStatus dipakai login agar karyawan "Tidak Aktif" mendapat pesan yang jelas
+func (s *KaryawanService) GetByEmailAnyStatus(email string) (*model.Karyawan, error) {
+	var karyawan model.Karyawan
+	if err := s.DB.Where("kar_email = ?", email).First(&karyawan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("karyawan tidak ditemukan")
+		}
+		return nil, err
+	}
+	return &karyawan, nil
+}
+
 func (s *KaryawanService) GetByEmail(email string) (*model.Karyawan, error) {
 	var karyawan model.Karyawan
 	if err := s.DB.Where("kar_email = ? AND kar_status = ?", email, "Aktif").First(&karyawan).Error; err != nil {
@@ -109,9 +109,9 @@ func (s *LayananService) CreateLayananWithProducts(input *model.CreateLayananWit
 	return layanan, nil
 }
 
-func (s *LayananService) UpdateLayananWithProducts(id uint, input *model.UpdateLayananWithProductsInput, userID uint, files map[string]*multipart.FileHeader) (*model.Layanan, error) {
+func (s *LayananService) UpdateLayananWithProducts(id, outletID uint, input *model.UpdateLayananWithProductsInput, userID uint, files map[string]*multipart.FileHeader) (*model.Layanan, error) {
 	var layanan model.Layanan
-	if err := s.DB.Preload("JenisProduk").First(&layanan, id).Error; err != nil {
+	if err := s.DB.Preload("JenisProduk").Where("outlet_id = ?", outletID).First(&layanan, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("layanan tidak ditemukan")
 		}
@@ -411,11 +411,12 @@ func (s *LayananService) GetAllLayanan(outletID uint, page, limit int) ([]model.
 	return result, total, nil
 }
 
-func (s *LayananService) GetLayananByID(id uint) (*model.LayananDetail, error) {
+func (s *LayananService) GetLayananByID(id, outletID uint) (*model.LayananDetail, error) {
 	var layanan model.Layanan
 	if err := s.DB.Preload("Outlet").
 		Preload("UserUpdate").
 		Preload("JenisProduk").
+		Where("outlet_id = ?", outletID).
 		First(&layanan, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("layanan tidak ditemukan")
@@ -481,9 +482,9 @@ func (s *LayananService) GetLayananByOutlet(outletID uint) ([]model.LayananList,
 	return result, nil
 }
 
-func (s *LayananService) DeleteLayanan(id uint) error {
+func (s *LayananService) DeleteLayanan(id, outletID uint) error {
 	var layanan model.Layanan
-	if err := s.DB.Preload("JenisProduk").First(&layanan, id).Error; err != nil {
+	if err := s.DB.Preload("JenisProduk").Where("outlet_id = ?", outletID).First(&layanan, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("layanan tidak ditemukan")
 		}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MigrateLegacyMasterData memindahkan data Employee, Parfume dan Discount ke model kanonik
+// (Karyawan, Parfum, Diskon). Baris yang sudah tercatat di legacy_id_maps dilewati sehingga aman dijalankan setiap start.
+func MigrateLegacyMasterData(db *gorm.DB) error {
+	cutover, err := legacyCutover(db)
+	if err != nil {
+		return err
+	}
+
+	// Sebelum employee lama dimigrasikan: izin batal mereka sudah dipetakan dari cancel_order
+	if err := grantVoidPermission(db); err != nil {
+		return err
+	}
+	if err := migrateLegacyEmployees(db); err != nil {
+		return err
+	}
+	if err := migrateLegacyParfumes(db, cutover); err != nil {
+		return err
+	}
+	return migrateLegacyDiscounts(db, cutover)
+}
+
+// legacyCutover mengambil waktu migrasi pertama kali berjalan, dicatat di legacy_id_maps saat start pertama.
+// Hanya transaksi sebelum waktu ini yang ditulis dengan ID tabel lama.
+func legacyCutover(db *gorm.DB) (time.Time, error) {
+	marker := model.LegacyIDMap{Entity: model.LegacyEntityCutover}
+	if err := db.Where("entity = ? AND legacy_id = 0", model.LegacyEntityCutover).FirstOrCreate(&marker).Error; err != nil {
+		return time.Time{}, err
+	}
+	return marker.CreatedAt, nil
+}
+
+// unmigratedLegacyRows memuat baris tabel lama yang belum tercatat di legacy_id_maps
+func unmigratedLegacyRows(db *gorm.DB, legacy interface{}, entity string, dest interface{}) (bool, error) {
+	if !db.Migrator().HasTable(legacy) {
+		return false, nil
+	}
+
+	err := db.Model(legacy).
+		Where("id NOT IN (?)", db.Model(&model.LegacyIDMap{}).Select("legacy_id").Where("entity = ?", entity)).
+		Order("id ASC").
+		Find(dest).Error
+	return err == nil, err
+}
+
+// grantVoidPermission memberi PermissionBatalkanOrder ke karyawan yang punya PermissionLayananTransaksi,
+// karena sebelum izin ini ada void pesanan dan nota cukup dengan akses transaksi. Hanya dijalankan sekali
+// agar izin yang kemudian dicabut owner tidak diberikan lagi saat start berikutnya.
+func grantVoidPermission(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var done int64
+		if err := tx.Model(&model.LegacyIDMap{}).Where("entity = ?", model.LegacyEntityVoidPermission).Count(&done).Error; err != nil || done > 0 {
+			return err
+		}
+
+		var karyawans []model.Karyawan
+		if err := tx.Select("kar_id", "kar_permissions").Find(&karyawans).Error; err != nil {
+			return err
+		}
+		for _, karyawan := range karyawans {
+			if !karyawan.HasPermission(model.PermissionLayananTransaksi) || karyawan.HasPermission(model.PermissionBatalkanOrder) {
+				continue
+			}
+			permissionsJSON, _ := json.Marshal(append(karyawan.PermissionList(), model.PermissionBatalkanOrder))
+			if err := tx.Model(&karyawan).Update("kar_permissions", string(permissionsJSON)).Error; err != nil {
+				return err
+			}
+		}
+
+		return recordLegacyID(tx, model.LegacyEntityVoidPermission, 0, 0)
+	})
+}
+
+func recordLegacyID(tx *gorm.DB, entity string, legacyID, canonicalID uint) error {
+	return tx.Create(&model.LegacyIDMap{Entity: entity, LegacyID: legacyID, CanonicalID: canonicalID}).Error
+}
+
+func migrateLegacyEmployees(db *gorm.DB) error {
+	var employees []model.Employee
+	if ok, err := unmigratedLegacyRows(db, &model.Employee{}, model.LegacyEntityEmployee, &employees); !ok {
+		return err
+	}
+
+	for _, employee := range employees {
+		email := strings.ToLower(strings.TrimSpace(employee.Email))
+		if email == "" {
+			// Dicatat dengan CanonicalID 0 agar tidak dicoba (dan di-log) lagi setiap start
+			log.Printf("Migrasi employee %d dilewati: email kosong", employee.ID)
+			if err := recordLegacyID(db, model.LegacyEntityEmployee, employee.ID, 0); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			permissions := model.LegacyPermissionsToKaryawan(employee.LegacyPermissions())
+
+			// Email hanya dicocokkan dengan karyawan di outlet milik owner yang sama
+			ownerOutlets := tx.Unscoped().Model(&model.Outlet{}).Select("id").
+				Where("user_id = (?)", tx.Unscoped().Model(&model.Outlet{}).Select("user_id").Where("id = ?", employee.OutletID))
+
+			var karyawan model.Karyawan
+			err := tx.Where("LOWER(kar_email) = ? AND kar_outlet IN (?)", email, ownerOutlets).First(&karyawan).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// kar_email unik global: email yang dipakai karyawan owner lain tidak boleh digabung atau dibuat ulang
+				var collisions int64
+				if err := tx.Model(&model.Karyawan{}).Where("LOWER(kar_email) = ?", email).Count(&collisions).Error; err != nil {
+					return err
+				}
+				if collisions > 0 {
+					log.Printf("Migrasi employee %d dilewati: email %s sudah dipakai karyawan outlet lain", employee.ID, email)
+					return recordLegacyID(tx, model.LegacyEntityEmployee, employee.ID, 0)
+				}
+			}
+			switch {
+			case err == nil:
+				// Email sudah ada di Karyawan: gabungkan permission, data Karyawan tetap dipakai
+				merged := karyawan.PermissionList()
+				for _, p := range permissions {
+					if !karyawan.HasPermission(p) {
+						merged = append(merged, p)
+					}
+				}
+				permissionsJSON, _ := json.Marshal(merged)
+				if err := tx.Model(&karyawan).Update("kar_permissions", string(permissionsJSON)).Error; err != nil {
+					return err
+				}
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				outletID := employee.OutletID
+				role := "Karyawan"
+				if employee.Role == "Owner" {
+					role = "Ownership"
+				}
+				permissionsJSON, _ := json.Marshal(permissions)
+
+				karyawan = model.Karyawan{
+					OutletID:    &outletID,
+					Nama:        employee.Name,
+					Phone:       employee.Phone,
+					Email:       email,
+					Password:    employee.Password, // Sudah berupa hash bcrypt
+					Role:        role,
+					Permissions: string(permissionsJSON),
+					Status:      "Aktif",
+					JoinDate:    employee.CreatedAt,
+					UserUpdate:  "migrasi",
+				}
+				if err := tx.Create(&karyawan).Error; err != nil {
+					return err
+				}
+			default:
+				return err
+			}
+
+			return recordLegacyID(tx, model.LegacyEntityEmployee, employee.ID, karyawan.ID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func migrateLegacyParfumes(db *gorm.DB, cutover time.Time) error {
+	var parfumes []model.Parfume
+	if ok, err := unmigratedLegacyRows(db, &model.Parfume{}, model.LegacyEntityParfume, &parfumes); !ok || len(parfumes) == 0 {
+		return err
+	}
+
+	// Satu transaksi untuk semua parfum agar parfum_id transaksi dipetakan sekaligus;
+	// pemetaan satu per satu bisa memetakan ulang ID yang baru saja diganti (3 -> 7 lalu 7 -> 2)
+	return db.Transaction(func(tx *gorm.DB) error {
+		cases := make([]string, 0, len(parfumes))
+		args := make([]interface{}, 0, len(parfumes)*3)
+
+		for _, parfume := range parfumes {
+			var parfum model.Parfum
+			err := tx.Where("prf_outlet = ? AND LOWER(prf_nama) = ?", parfume.OutletID, strings.ToLower(strings.TrimSpace(parfume.Name))).
+				First(&parfum).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				outletID := parfume.OutletID
+				parfum = model.Parfum{
+					OutletID:   &outletID,
+					Parfum:     strings.TrimSpace(parfume.Name),
+					Keterangan: parfume.Description,
+					Status:     "Aktif",
+					UserUpdate: "migrasi",
+				}
+				err = tx.Create(&parfum).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := recordLegacyID(tx, model.LegacyEntityParfume, parfume.ID, parfum.ID); err != nil {
+				return err
+			}
+
+			cases = append(cases, "WHEN outlet_id = ? AND parfum_id = ? THEN ?")
+			args = append(args, parfume.OutletID, parfume.ID, parfum.ID)
+		}
+
+		// Transaksi sebelum cutover menyimpan ID parfum dari /master/parfums. Transaksi setelahnya sudah
+		// mengacu ke Parfum dan bisa kebetulan memakai ID yang sama dengan parfum lama, jadi tidak disentuh.
+		return tx.Model(&model.Transaction{}).
+			Where("parfum_id <> 0 AND created_at < ?", cutover).
+			Update("parfum_id", gorm.Expr("CASE "+strings.Join(cases, " ")+" ELSE parfum_id END", args...)).Error
+	})
+}
+
+func migrateLegacyDiscounts(db *gorm.DB, cutover time.Time) error {
+	var discounts []model.Discount
+	if ok, err := unmigratedLegacyRows(db, &model.Discount{}, model.LegacyEntityDiscount, &discounts); !ok || len(discounts) == 0 {
+		return err
+	}
+
+	// Sama seperti parfum: discount_id transaksi dipetakan sekaligus dalam satu transaksi
+	return db.Transaction(func(tx *gorm.DB) error {
+		cases := make([]string, 0, len(discounts))
+		args := make([]interface{}, 0, len(discounts)*3)
+
+		for _, discount := range discounts {
+			var diskon model.Diskon
+			err := tx.Where("dis_outlet = ? AND LOWER(dis_diskon) = ?", discount.OutletID, strings.ToLower(strings.TrimSpace(discount.Name))).
+				First(&diskon).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				outletID := discount.OutletID
+				diskon = model.Diskon{
+					OutletID:    &outletID,
+					Diskon:      strings.TrimSpace(discount.Name),
+					Jenis:       model.NormalizeDiskonJenis(discount.Type),
+					NilaiDiskon: discount.Value,
+					Keterangan:  discount.Description,
+					Status:      model.DiskonStatus(discount.IsActive),
+					UserUpdate:  "migrasi",
+				}
+				err = tx.Create(&diskon).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := recordLegacyID(tx, model.LegacyEntityDiscount, discount.ID, diskon.ID); err != nil {
+				return err
+			}
+
+			cases = append(cases, "WHEN outlet_id = ? AND discount_id = ? THEN ?")
+			args = append(args, discount.OutletID, discount.ID, diskon.ID)
+		}
+
+		// Transaksi sebelum cutover menyimpan ID diskon dari /master/discounts
+		return tx.Model(&model.Transaction{}).
+			Where("discount_id IS NOT NULL AND created_at < ?", cutover).
+			Update("discount_id", gorm.Expr("CASE "+strings.Join(cases, " ")+" ELSE discount_id END", args...)).Error
+	})
+}
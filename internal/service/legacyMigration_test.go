@@ -0,0 +1,111 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"testing"
+	"time"
+)
+
+func TestMigrateLegacyMasterData(t *testing.T) {
+	db := newTestDB(t, &model.LegacyIDMap{}, &model.Employee{}, &model.Karyawan{}, &model.Outlet{},
+		&model.Parfume{}, &model.Parfum{}, &model.Discount{}, &model.Diskon{}, &model.Transaction{})
+
+	cutover := time.Date(2026, 1, 10, 8, 0, 0, 0, time.UTC)
+	outletID := uint(1)
+	seed := []interface{}{
+		&model.LegacyIDMap{Entity: model.LegacyEntityCutover, CreatedAt: cutover},
+		&model.Outlet{ID: 1, UserID: 10},
+		&model.Employee{ID: 1, OutletID: 1, Name: "Tanpa Email"},
+		// Parfum kanonik 1 sudah dipakai transaksi baru, parfum lama 1 dipakai transaksi lama
+		&model.Parfum{ID: 1, OutletID: &outletID, Parfum: "Vanilla"},
+		&model.Parfume{ID: 1, OutletID: 1, Name: "Lavender"},
+		&model.Transaction{ID: 1, InvoiceNumber: "TRX/OLD", OutletID: 1, ParfumID: 1, CreatedAt: cutover.Add(-time.Hour)},
+		&model.Transaction{ID: 2, InvoiceNumber: "TRX/NEW", OutletID: 1, ParfumID: 1, CreatedAt: cutover.Add(time.Hour)},
+	}
+	for _, row := range seed {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("seed %T: %v", row, err)
+		}
+	}
+
+	// Dijalankan dua kali seperti dua kali start: hasil tidak berubah
+	for run := 1; run <= 2; run++ {
+		if err := MigrateLegacyMasterData(db); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+
+	var lavender model.Parfum
+	if err := db.Where("prf_nama = ?", "Lavender").First(&lavender).Error; err != nil {
+		t.Fatalf("parfum lama tidak dimigrasikan: %v", err)
+	}
+
+	tests := []struct {
+		invoice    string
+		wantParfum uint
+	}{
+		{"TRX/OLD", lavender.ID}, // Ditulis dengan ID parfum lama
+		{"TRX/NEW", 1},           // Sudah mengacu ke Parfum kanonik dengan ID yang sama
+	}
+	for _, tt := range tests {
+		var trx model.Transaction
+		db.Where("invoice_number = ?", tt.invoice).First(&trx)
+		if trx.ParfumID != tt.wantParfum {
+			t.Errorf("%s parfum_id = %d, want %d", tt.invoice, trx.ParfumID, tt.wantParfum)
+		}
+	}
+
+	var skipped model.LegacyIDMap
+	if err := db.Where("entity = ? AND legacy_id = ?", model.LegacyEntityEmployee, 1).First(&skipped).Error; err != nil || skipped.CanonicalID != 0 {
+		t.Errorf("employee tanpa email tidak dicatat sebagai dilewati: %+v, %v", skipped, err)
+	}
+	var karyawan int64
+	db.Model(&model.Karyawan{}).Count(&karyawan)
+	if karyawan != 0 {
+		t.Errorf("%d karyawan dibuat dari employee tanpa email", karyawan)
+	}
+}
+
+func TestGrantVoidPermission(t *testing.T) {
+	db := newTestDB(t, &model.LegacyIDMap{}, &model.Karyawan{})
+
+	outletID := uint(1)
+	kasir := model.Karyawan{OutletID: &outletID, Nama: "Kasir", Email: "kasir@example.com", Password: "x",
+		Permissions: `["` + model.PermissionLayananTransaksi + `"]`}
+	gudang := model.Karyawan{OutletID: &outletID, Nama: "Gudang", Email: "gudang@example.com", Password: "x",
+		Permissions: `["` + model.PermissionLayananProduk + `"]`}
+	for _, karyawan := range []*model.Karyawan{&kasir, &gudang} {
+		if err := db.Create(karyawan).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := grantVoidPermission(db); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id   uint
+		want bool
+	}{
+		{kasir.ID, true},   // Sebelumnya bisa void lewat akses transaksi
+		{gudang.ID, false}, // Tidak punya akses transaksi
+	}
+	for _, tt := range tests {
+		var karyawan model.Karyawan
+		db.First(&karyawan, tt.id)
+		if got := karyawan.HasPermission(model.PermissionBatalkanOrder); got != tt.want {
+			t.Errorf("karyawan %s punya izin batal = %v, want %v", karyawan.Nama, got, tt.want)
+		}
+	}
+
+	// Izin yang dicabut owner tidak diberikan lagi saat start berikutnya
+	db.Model(&kasir).Update("kar_permissions", `["`+model.PermissionLayananTransaksi+`"]`)
+	if err := grantVoidPermission(db); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&kasir, kasir.ID)
+	if kasir.HasPermission(model.PermissionBatalkanOrder) {
+		t.Error("izin batal diberikan ulang setelah dicabut")
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"BackendFramework/internal/thirdparty"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"sync"
+)
+
+const (
+	OTPChannelWhatsApp = "whatsapp"
+	OTPChannelSMS      = "sms"
+	OTPChannelEmail    = "email"
+	OTPChannelFake     = "fake"
+)
+
+var (
+	ErrOTPChannelUnknown = errors.New("channel OTP tidak dikenali")
+	// ErrOTPChannelUnavailable berarti channel tidak bisa dipakai untuk user ini (misal belum punya email)
+	ErrOTPChannelUnavailable = errors.New("channel OTP tidak tersedia untuk akun ini")
+)
+
+// OTPChannel mengirim kode OTP ke user lewat satu media
+type OTPChannel interface {
+	Name() string
+	Send(user *model.User, code string) error
+}
+
+// OTPChannelLabel adalah nama channel untuk pesan ke user
+func OTPChannelLabel(channel string) string {
+	switch channel {
+	case OTPChannelWhatsApp:
+		return "WhatsApp"
+	case OTPChannelSMS:
+		return "SMS"
+	case OTPChannelEmail:
+		return "email"
+	}
+	return channel
+}
+
+func otpPlainMessage(code string) string {
+	return fmt.Sprintf("Kode OTP Anda: %s. Berlaku %d menit. Jangan bagikan kode ini kepada siapa pun.", code, int(OTPExpiry.Minutes()))
+}
+
+// ==================== WHATSAPP ====================
+
+type whatsappOTPChannel struct {
+	infobip *InfobipService
+}
+
+func (ch *whatsappOTPChannel) Name() string { return OTPChannelWhatsApp }
+
+func (ch *whatsappOTPChannel) Send(user *model.User, code string) error {
+	if user.NomorHP == "" {
+		return ErrOTPChannelUnavailable
+	}
+	return ch.infobip.SendWhatsAppOTP(user.NomorHP, code)
+}
+
+// ==================== SMS ====================
+
+type smsOTPChannel struct {
+	infobip *InfobipService
+}
+
+func (ch *smsOTPChannel) Name() string { return OTPChannelSMS }
+
+func (ch *smsOTPChannel) Send(user *model.User, code string) error {
+	if user.NomorHP == "" {
+		return ErrOTPChannelUnavailable
+	}
+	_, err := ch.infobip.SendSMSText(user.NomorHP, otpPlainMessage(code))
+	return err
+}
+
+// ==================== EMAIL ====================
+
+type emailOTPChannel struct{}
+
+func (ch *emailOTPChannel) Name() string { return OTPChannelEmail }
+
+func (ch *emailOTPChannel) Send(user *model.User, code string) error {
+	if strings.TrimSpace(user.Email) == "" {
+		return ErrOTPChannelUnavailable
+	}
+
+	body := fmt.Sprintf(
+		"<p>Halo %s,</p>"+
+			"<p>Kode OTP Anda: <strong style=\"font-size:20px;letter-spacing:4px\">%s</strong></p>"+
+			"<p>Kode ini berlaku selama %d menit. Jangan bagikan kode ini kepada siapa pun.</p>"+
+			"<p>Jika Anda tidak meminta kode ini, abaikan email ini.</p>",
+		html.EscapeString(user.NamaLengkap), code, int(OTPExpiry.Minutes()),
+	)
+	recipients := []thirdparty.RecipientStruct{{Name: user.NamaLengkap, Email: user.Email}}
+	if !thirdparty.SendEmail(body, "Kode Verifikasi", recipients) {
+		return errors.New("gagal mengirim email")
+	}
+	return nil
+}
+
+// ==================== FAKE ====================
+
+// FakeOTPMessage adalah pesan yang "dikirim" FakeOTPChannel
+type FakeOTPMessage struct {
+	NomorHP string
+	Email   string
+	Code    string
+}
+
+// FakeOTPChannel tidak mengirim apa pun: kode disimpan di memori dan dicetak ke log.
+// Dipakai untuk test dan development lokal (OTP_CHANNEL_ORDER=fake).
+type FakeOTPChannel struct {
+	ChannelName string // Kosong = "fake"; isi "whatsapp" dsb untuk menggantikan channel asli di test
+	Err         error  // Jika diisi, Send selalu gagal dengan error ini
+
+	mu   sync.Mutex
+	sent []FakeOTPMessage
+}
+
+func (ch *FakeOTPChannel) Name() string {
+	if ch.ChannelName == "" {
+		return OTPChannelFake
+	}
+	return ch.ChannelName
+}
+
+func (ch *FakeOTPChannel) Send(user *model.User, code string) error {
+	if ch.Err != nil {
+		return ch.Err
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.sent = append(ch.sent, FakeOTPMessage{NomorHP: user.NomorHP, Email: user.Email, Code: code})
+	log.Printf("[OTP %s] %s / %s: %s", ch.Name(), user.NomorHP, user.Email, code)
+	return nil
+}
+
+// Sent mengembalikan salinan pesan yang sudah dikirim
+func (ch *FakeOTPChannel) Sent() []FakeOTPMessage {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return append([]FakeOTPMessage(nil), ch.sent...)
+}
+
+// LastCode mengembalikan kode terakhir yang dikirim, kosong jika belum ada
+func (ch *FakeOTPChannel) LastCode() string {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if len(ch.sent) == 0 {
+		return ""
+	}
+	return ch.sent[len(ch.sent)-1].Code
+}
@@ -1,103 +1,370 @@
-
-package service
-
-import (
-	"crypto/rand"
-	"errors"
-	"fmt"
-	"math/big"
-	"time"
-
-	"BackendFramework/internal/model"
-	"gorm.io/gorm"
-)
-
-type OTPService struct {
-	db             *gorm.DB
-	infobipService *InfobipService
-}
-
-func NewOTPService(db *gorm.DB) *OTPService {
-	return &OTPService{
-		db:             db,
-		infobipService: NewInfobipService(),
-	}
-}
-
-// membuat kode OTP 6 digit random
-func (s *OTPService) GenerateOTPCode() (string, error) {
-	max := big.NewInt(1000000)
-	n, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		return "", err
-	}
-	return fmt.Sprintf("%06d", n.Int64()), nil
-}
-
-
-func (s *OTPService) SendOTP(nomorHP, purpose string) error {
-	
-	var user model.User
-	if err := s.db.Where("nomor_hp = ?", nomorHP).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("nomor HP tidak terdaftar")
-		}
-		return err
-	}
-
-	s.db.Where("nomor_hp = ? AND purpose = ? AND is_used = false", nomorHP, purpose).Delete(&model.OTP{})
-
-	
-	code, err := s.GenerateOTPCode()
-	if err != nil {
-		return errors.New("gagal generate OTP code")
-	}
-
-	// OTP ke database
-	otp := model.OTP{
-		NomorHP:   nomorHP,
-		Code:      code,
-		ExpiresAt: time.Now().Add(5 * time.Minute), 
-		IsUsed:    false,
-		Purpose:   purpose,
-	}
-
-	if err := s.db.Create(&otp).Error; err != nil {
-		return errors.New("gagal menyimpan OTP")
-	}
-
-	// Kirim OTP via WhatsApp menggunakan Infobip
-	if err := s.infobipService.SendWhatsAppOTP(nomorHP, code); err != nil {
-		return fmt.Errorf("gagal mengirim OTP: %v", err)
-	}
-
-	return nil
-}
-
-//  memverifikasi kode OTP
-func (s *OTPService) VerifyOTP(nomorHP, code, purpose string) (bool, error) {
-	var otp model.OTP
-	err := s.db.Where(
-		"nomor_hp = ? AND code = ? AND purpose = ? AND is_used = false AND expires_at > ?",
-		nomorHP, code, purpose, time.Now(),
-	).First(&otp).Error
-
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, errors.New("kode OTP tidak valid atau sudah kadaluarsa")
-		}
-		return false, err
-	}
-
-	otp.IsUsed = true
-	if err := s.db.Save(&otp).Error; err != nil {
-		return false, err
-	}
-
-	return true, nil
-}
-
-
-func (s *OTPService) CleanupExpiredOTP() error {
-	return s.db.Where("expires_at < ? OR is_used = true", time.Now().Add(-24*time.Hour)).Delete(&model.OTP{}).Error
+
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"BackendFramework/internal/config"
+	"BackendFramework/internal/model"
+	"gorm.io/gorm"
+)
+
+const (
+	OTPExpiry            = 5 * time.Minute
+	OTPSendCooldown      = 60 * time.Second // Jeda minimal antar pengiriman ke nomor yang sama
+	OTPSendWindow        = time.Hour
+	MaxOTPSendPerPhone   = 5  // Pengiriman per nomor HP dalam OTPSendWindow
+	MaxOTPRequestPerIP   = 10 // Permintaan per IP dalam OTPSendWindow, termasuk nomor yang tidak terdaftar
+	MaxOTPVerifyAttempts = 5  // Setelah ini kode hangus dan harus minta kode baru
+	ResetTokenExpiry     = 10 * time.Minute
+
+	OTPPurposeForgotPassword = "forgot_password"
+	OTPPurposeResetPassword  = "reset_password" // Baris otps berisi hash reset token dari VerifyOTP, bukan kode OTP
+)
+
+var (
+	ErrOTPPhoneNotRegistered = errors.New("nomor HP tidak terdaftar")
+	ErrOTPInvalid            = errors.New("kode OTP tidak valid atau sudah kadaluarsa")
+	ErrResetTokenInvalid     = errors.New("token reset password tidak valid atau sudah kadaluarsa")
+)
+
+// OTPRateLimitError dikembalikan jika kuota atau cooldown OTP terlampaui
+type OTPRateLimitError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *OTPRateLimitError) Error() string {
+	return e.Message
+}
+
+// RetryAfterSeconds dibulatkan ke atas agar client tidak mencoba sedetik terlalu cepat
+func (e *OTPRateLimitError) RetryAfterSeconds() int {
+	seconds := int((e.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+type OTPService struct {
+	db             *gorm.DB
+	infobipService *InfobipService
+	channels       map[string]OTPChannel
+	channelOrder   []string // Urutan fallback dari config.OTP_CHANNEL_ORDER
+}
+
+func NewOTPService(db *gorm.DB) *OTPService {
+	infobip := NewInfobipService()
+	s := &OTPService{
+		db:             db,
+		infobipService: infobip,
+	}
+	channels := []OTPChannel{
+		&whatsappOTPChannel{infobip: infobip},
+		&smsOTPChannel{infobip: infobip},
+		&emailOTPChannel{},
+	}
+	// Channel fake hanya terdaftar jika disebut di config, agar tidak bisa dipilih user di production
+	for _, name := range config.OTP_CHANNEL_ORDER {
+		if name == OTPChannelFake {
+			channels = append(channels, &FakeOTPChannel{})
+			break
+		}
+	}
+	s.UseChannels(config.OTP_CHANNEL_ORDER, channels...)
+	return s
+}
+
+// UseChannels mengganti channel yang terdaftar dan urutan fallback-nya, misal FakeOTPChannel untuk test.
+// Nama di order yang tidak terdaftar diabaikan.
+func (s *OTPService) UseChannels(order []string, channels ...OTPChannel) *OTPService {
+	s.channels = make(map[string]OTPChannel, len(channels))
+	for _, ch := range channels {
+		s.channels[ch.Name()] = ch
+	}
+
+	s.channelOrder = nil
+	for _, name := range order {
+		if _, ok := s.channels[name]; ok {
+			s.channelOrder = append(s.channelOrder, name)
+		}
+	}
+	return s
+}
+
+// deliveryOrder menaruh channel pilihan user di depan lalu sisa urutan fallback
+func (s *OTPService) deliveryOrder(preferred string) ([]string, error) {
+	preferred = strings.ToLower(strings.TrimSpace(preferred))
+	if preferred == "" {
+		return s.channelOrder, nil
+	}
+	if _, ok := s.channels[preferred]; !ok {
+		return nil, ErrOTPChannelUnknown
+	}
+
+	order := []string{preferred}
+	for _, name := range s.channelOrder {
+		if name != preferred {
+			order = append(order, name)
+		}
+	}
+	return order, nil
+}
+
+// membuat kode OTP 6 digit random
+func (s *OTPService) GenerateOTPCode() (string, error) {
+	max := big.NewInt(1000000)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+
+// reserveSend mencatat permintaan ke otp_send_logs lebih dulu, baru menghitung kuota termasuk baris itu.
+// Dua request paralel masing-masing melihat baris yang lain sehingga tidak bisa sama-sama lolos melewati batas.
+// Baris dibuat dengan Sent = true agar pengiriman yang sedang berjalan ikut terhitung kuota nomor HP;
+// permintaan yang ditolak menghapus barisnya sehingga tidak memakan kuota.
+func (s *OTPService) reserveSend(nomorHP, purpose, ipAddress string) (*model.OTPSendLog, error) {
+	entry := &model.OTPSendLog{
+		NomorHP:   nomorHP,
+		IPAddress: ipAddress,
+		Purpose:   purpose,
+		Sent:      true,
+	}
+	if err := s.db.Create(entry).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.checkSendAllowed(entry); err != nil {
+		s.db.Delete(entry)
+		return nil, err
+	}
+	return entry, nil
+}
+
+// checkSendAllowed memeriksa kuota per IP, cooldown dan kuota per nomor HP untuk entry yang sudah dicatat
+func (s *OTPService) checkSendAllowed(entry *model.OTPSendLog) error {
+	now := time.Now()
+	windowStart := now.Add(-OTPSendWindow)
+
+	if entry.IPAddress != "" {
+		var ipLogs []model.OTPSendLog
+		if err := s.db.Where("ip_address = ? AND created_at > ?", entry.IPAddress, windowStart).
+			Order("created_at ASC").Limit(MaxOTPRequestPerIP + 1).Find(&ipLogs).Error; err != nil {
+			return err
+		}
+		if len(ipLogs) > MaxOTPRequestPerIP {
+			return &OTPRateLimitError{
+				Message:    "Terlalu banyak permintaan OTP dari jaringan ini, coba lagi nanti",
+				RetryAfter: ipLogs[0].CreatedAt.Add(OTPSendWindow).Sub(now),
+			}
+		}
+	}
+
+	var phoneLogs []model.OTPSendLog
+	if err := s.db.Where("nomor_hp = ? AND sent = ? AND created_at > ? AND id <> ?", entry.NomorHP, true, windowStart, entry.ID).
+		Order("created_at DESC").Find(&phoneLogs).Error; err != nil {
+		return err
+	}
+	if len(phoneLogs) == 0 {
+		return nil
+	}
+
+	if wait := phoneLogs[0].CreatedAt.Add(OTPSendCooldown).Sub(now); wait > 0 {
+		return &OTPRateLimitError{
+			Message:    "Kode OTP baru saja dikirim, tunggu sebelum meminta kode lagi",
+			RetryAfter: wait,
+		}
+	}
+
+	if len(phoneLogs) >= MaxOTPSendPerPhone {
+		// Kuota kembali saat pengiriman tertua di dalam window keluar dari window
+		oldest := phoneLogs[MaxOTPSendPerPhone-1]
+		return &OTPRateLimitError{
+			Message:    "Batas pengiriman OTP ke nomor ini tercapai, coba lagi nanti",
+			RetryAfter: oldest.CreatedAt.Add(OTPSendWindow).Sub(now),
+		}
+	}
+
+	return nil
+}
+
+// SendOTP mengirim kode baru setelah lolos reserveSend lewat channel pilihan user (boleh kosong),
+// lalu channel berikutnya sesuai urutan fallback jika gagal. Mengembalikan channel yang berhasil.
+// Setiap permintaan dicatat satu baris, termasuk nomor yang tidak terdaftar agar kuota per IP juga membatasi
+// pencarian nomor; channel yang gagal saat fallback tidak menambah baris.
+func (s *OTPService) SendOTP(nomorHP, purpose, ipAddress, channel string) (string, error) {
+	order, err := s.deliveryOrder(channel)
+	if err != nil {
+		return "", err
+	}
+	if len(order) == 0 {
+		return "", errors.New("tidak ada channel OTP yang aktif")
+	}
+
+	entry, err := s.reserveSend(nomorHP, purpose, ipAddress)
+	if err != nil {
+		return "", err
+	}
+	// Permintaan yang tidak berakhir dengan kode terkirim tidak dihitung kuota nomor HP
+	delivered := false
+	defer func() {
+		if !delivered {
+			s.db.Model(entry).Update("sent", false)
+		}
+	}()
+
+	var user model.User
+	if err := s.db.Where("nomor_hp = ?", nomorHP).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrOTPPhoneNotRegistered
+		}
+		return "", err
+	}
+
+	s.db.Where("nomor_hp = ? AND purpose = ? AND is_used = false", nomorHP, purpose).Delete(&model.OTP{})
+
+	
+	code, err := s.GenerateOTPCode()
+	if err != nil {
+		return "", errors.New("gagal generate OTP code")
+	}
+
+	// OTP ke database
+	otp := model.OTP{
+		NomorHP:   nomorHP,
+		Code:      code,
+		ExpiresAt: time.Now().Add(OTPExpiry),
+		IsUsed:    false,
+		Purpose:   purpose,
+	}
+
+	if err := s.db.Create(&otp).Error; err != nil {
+		return "", errors.New("gagal menyimpan OTP")
+	}
+
+	var failures []string
+	for _, name := range order {
+		err := s.channels[name].Send(&user, code)
+		if err == nil {
+			delivered = true
+			s.db.Model(entry).Update("channel", name)
+			return name, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+	}
+
+	return "", fmt.Errorf("gagal mengirim OTP: %s", strings.Join(failures, "; "))
+}
+
+//  memverifikasi kode OTP. Kode yang salah MaxOTPVerifyAttempts kali hangus walaupun belum kadaluarsa.
+func (s *OTPService) VerifyOTP(nomorHP, code, purpose string) (bool, error) {
+	var otp model.OTP
+	err := s.db.Where(
+		"nomor_hp = ? AND purpose = ? AND is_used = false AND expires_at > ?",
+		nomorHP, purpose, time.Now(),
+	).Order("created_at DESC").First(&otp).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, ErrOTPInvalid
+		}
+		return false, err
+	}
+
+	// Percobaan dihitung sebelum kode dibandingkan; update bersyarat memastikan request paralel
+	// tidak bisa membandingkan kode lebih dari MaxOTPVerifyAttempts kali
+	attempt := s.db.Model(&model.OTP{}).
+		Where("id = ? AND attempts < ?", otp.ID, MaxOTPVerifyAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if attempt.Error != nil {
+		return false, attempt.Error
+	}
+	if attempt.RowsAffected == 0 {
+		return false, &OTPRateLimitError{
+			Message:    "Terlalu banyak percobaan kode OTP, silakan minta kode baru",
+			RetryAfter: OTPSendCooldown,
+		}
+	}
+
+	if subtle.ConstantTimeCompare([]byte(otp.Code), []byte(code)) != 1 {
+		return false, ErrOTPInvalid
+	}
+
+	// Update bersyarat agar kode yang sama tidak bisa dipakai dua kali oleh request paralel
+	result := s.db.Model(&model.OTP{}).Where("id = ? AND is_used = false", otp.ID).Update("is_used", true)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, ErrOTPInvalid
+	}
+
+	return true, nil
+}
+
+// IssueResetToken dipanggil setelah OTP forgot_password terverifikasi. Hanya hash token yang disimpan
+// dan token lama yang belum dipakai untuk nomor yang sama dihapus.
+func (s *OTPService) IssueResetToken(nomorHP string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	s.db.Where("nomor_hp = ? AND purpose = ? AND is_used = false", nomorHP, OTPPurposeResetPassword).Delete(&model.OTP{})
+
+	entry := model.OTP{
+		NomorHP:   nomorHP,
+		Code:      HashRefreshToken(token),
+		ExpiresAt: time.Now().Add(ResetTokenExpiry),
+		Purpose:   OTPPurposeResetPassword,
+	}
+	if err := s.db.Create(&entry).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ResetPassword memakai reset token dan mengganti password dalam satu transaksi;
+// token ditandai terpakai dengan update bersyarat sehingga hanya berlaku sekali
+func (s *OTPService) ResetPassword(nomorHP, resetToken, hashedPassword string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.OTP{}).
+			Where("nomor_hp = ? AND purpose = ? AND code = ? AND is_used = false AND expires_at > ?",
+				nomorHP, OTPPurposeResetPassword, HashRefreshToken(resetToken), time.Now()).
+			Update("is_used", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrResetTokenInvalid
+		}
+
+		update := tx.Model(&model.User{}).Where("nomor_hp = ?", nomorHP).Update("password", hashedPassword)
+		if update.Error != nil {
+			return update.Error
+		}
+		if update.RowsAffected == 0 {
+			return ErrOTPPhoneNotRegistered
+		}
+		return nil
+	})
+}
+
+func (s *OTPService) CleanupExpiredOTP() error {
+	if err := s.db.Where("created_at < ?", time.Now().Add(-24*time.Hour)).Delete(&model.OTPSendLog{}).Error; err != nil {
+		return err
+	}
+	return s.db.Where("expires_at < ? OR is_used = true", time.Now().Add(-24*time.Hour)).Delete(&model.OTP{}).Error
 }
\ No newline at end of file
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const testOTPPhone = "6281200000001"
+
+// newTestOTPService memakai FakeOTPChannel sebagai whatsapp dan sms dengan urutan fallback whatsapp -> sms
+func newTestOTPService(t *testing.T) (*OTPService, *gorm.DB, *FakeOTPChannel, *FakeOTPChannel) {
+	t.Helper()
+
+	db := newTestDB(t, &model.User{}, &model.OTP{}, &model.OTPSendLog{})
+	user := model.User{NamaLengkap: "Pemilik", Email: "owner@example.com", Password: "x", NomorHP: testOTPPhone}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	whatsapp := &FakeOTPChannel{ChannelName: OTPChannelWhatsApp}
+	sms := &FakeOTPChannel{ChannelName: OTPChannelSMS}
+	s := (&OTPService{db: db}).UseChannels([]string{OTPChannelWhatsApp, OTPChannelSMS}, whatsapp, sms)
+	return s, db, whatsapp, sms
+}
+
+// seedSendLogs mencatat n pengiriman lama (lewat cooldown, masih di dalam window)
+func seedSendLogs(t *testing.T, db *gorm.DB, n int, nomorHP, ipAddress string, sent bool) {
+	t.Helper()
+
+	at := time.Now().Add(-10 * time.Minute)
+	for i := 0; i < n; i++ {
+		entry := model.OTPSendLog{NomorHP: nomorHP, IPAddress: ipAddress, Purpose: "forgot_password", Sent: sent, CreatedAt: at}
+		if err := db.Create(&entry).Error; err != nil {
+			t.Fatalf("seed send log: %v", err)
+		}
+	}
+}
+
+func TestSendOTPLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(t *testing.T, db *gorm.DB)
+		nomorHP   string
+		wantErr   error
+		wantLimit bool
+	}{
+		{
+			name:    "pengiriman pertama",
+			nomorHP: testOTPPhone,
+		},
+		{
+			name: "masih dalam cooldown",
+			setup: func(t *testing.T, db *gorm.DB) {
+				db.Create(&model.OTPSendLog{NomorHP: testOTPPhone, IPAddress: "10.0.0.2", Sent: true})
+			},
+			nomorHP:   testOTPPhone,
+			wantLimit: true,
+		},
+		{
+			name: "pengiriman gagal tidak memicu cooldown",
+			setup: func(t *testing.T, db *gorm.DB) {
+				db.Create(&model.OTPSendLog{NomorHP: testOTPPhone, IPAddress: "10.0.0.2", Sent: false})
+			},
+			nomorHP: testOTPPhone,
+		},
+		{
+			name: "kuota nomor HP habis",
+			setup: func(t *testing.T, db *gorm.DB) {
+				seedSendLogs(t, db, MaxOTPSendPerPhone, testOTPPhone, "10.0.0.2", true)
+			},
+			nomorHP:   testOTPPhone,
+			wantLimit: true,
+		},
+		{
+			name: "kuota nomor HP tersisa satu",
+			setup: func(t *testing.T, db *gorm.DB) {
+				seedSendLogs(t, db, MaxOTPSendPerPhone-1, testOTPPhone, "10.0.0.2", true)
+			},
+			nomorHP: testOTPPhone,
+		},
+		{
+			name: "kuota IP habis walaupun nomor tidak terdaftar",
+			setup: func(t *testing.T, db *gorm.DB) {
+				seedSendLogs(t, db, MaxOTPRequestPerIP, "6289999999999", "10.0.0.1", false)
+			},
+			nomorHP:   testOTPPhone,
+			wantLimit: true,
+		},
+		{
+			name:    "nomor tidak terdaftar",
+			nomorHP: "6289999999999",
+			wantErr: ErrOTPPhoneNotRegistered,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, db, whatsapp, _ := newTestOTPService(t)
+			if tt.setup != nil {
+				tt.setup(t, db)
+			}
+			var before int64
+			db.Model(&model.OTPSendLog{}).Count(&before)
+
+			channel, err := s.SendOTP(tt.nomorHP, "forgot_password", "10.0.0.1", "")
+
+			var limitErr *OTPRateLimitError
+			switch {
+			case tt.wantLimit:
+				if !errors.As(err, &limitErr) || limitErr.RetryAfterSeconds() < 1 {
+					t.Fatalf("err = %v, want OTPRateLimitError", err)
+				}
+				// Permintaan yang ditolak tidak memakan kuota
+				var after int64
+				db.Model(&model.OTPSendLog{}).Count(&after)
+				if after != before {
+					t.Errorf("send log bertambah %d untuk permintaan yang ditolak", after-before)
+				}
+				if len(whatsapp.Sent()) != 0 {
+					t.Errorf("kode tetap terkirim saat dibatasi")
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				var entry model.OTPSendLog
+				db.Order("id DESC").First(&entry)
+				if entry.NomorHP != tt.nomorHP || entry.Sent {
+					t.Errorf("permintaan gagal harus tercatat dengan sent = false: %+v", entry)
+				}
+			default:
+				if err != nil || channel != OTPChannelWhatsApp {
+					t.Fatalf("SendOTP = %q, %v, want whatsapp", channel, err)
+				}
+				var entry model.OTPSendLog
+				db.Order("id DESC").First(&entry)
+				if !entry.Sent || entry.Channel != OTPChannelWhatsApp {
+					t.Errorf("pengiriman tidak tercatat sebagai terkirim: %+v", entry)
+				}
+			}
+		})
+	}
+}
+
+func TestSendOTPFallback(t *testing.T) {
+	tests := []struct {
+		name        string
+		whatsappErr error
+		smsErr      error
+		preferred   string
+		wantChannel string
+		wantSent    bool
+	}{
+		{name: "channel utama", wantChannel: OTPChannelWhatsApp, wantSent: true},
+		{name: "pilihan user didahulukan", preferred: OTPChannelSMS, wantChannel: OTPChannelSMS, wantSent: true},
+		{name: "fallback ke sms", whatsappErr: errors.New("provider down"), wantChannel: OTPChannelSMS, wantSent: true},
+		{name: "channel tidak tersedia dilewati", whatsappErr: ErrOTPChannelUnavailable, wantChannel: OTPChannelSMS, wantSent: true},
+		{name: "semua channel gagal", whatsappErr: errors.New("provider down"), smsErr: errors.New("provider down")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, db, whatsapp, sms := newTestOTPService(t)
+			whatsapp.Err, sms.Err = tt.whatsappErr, tt.smsErr
+
+			channel, err := s.SendOTP(testOTPPhone, "forgot_password", "10.0.0.1", tt.preferred)
+			if tt.wantChannel == "" {
+				if err == nil {
+					t.Fatalf("SendOTP = %q, want error", channel)
+				}
+			} else if err != nil || channel != tt.wantChannel {
+				t.Fatalf("SendOTP = %q, %v, want %q", channel, err, tt.wantChannel)
+			}
+
+			// Satu permintaan satu baris log, channel yang gagal saat fallback tidak dicatat terpisah
+			var logs []model.OTPSendLog
+			db.Find(&logs)
+			if len(logs) != 1 {
+				t.Fatalf("%d send log untuk satu permintaan, want 1", len(logs))
+			}
+			if logs[0].Sent != tt.wantSent || logs[0].Channel != tt.wantChannel {
+				t.Errorf("send log = %+v, want sent = %v channel = %q", logs[0], tt.wantSent, tt.wantChannel)
+			}
+
+			// Pengiriman yang gagal semua tidak memicu cooldown
+			if !tt.wantSent {
+				whatsapp.Err, sms.Err = nil, nil
+				if _, err := s.SendOTP(testOTPPhone, "forgot_password", "10.0.0.1", ""); err != nil {
+					t.Errorf("kirim ulang setelah gagal: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyOTPAttempts(t *testing.T) {
+	tests := []struct {
+		name      string
+		wrong     int  // Jumlah kode salah sebelum kode benar
+		wantValid bool // Kode benar diterima setelah percobaan salah
+	}{
+		{name: "langsung benar", wrong: 0, wantValid: true},
+		{name: "benar di percobaan terakhir", wrong: MaxOTPVerifyAttempts - 1, wantValid: true},
+		{name: "percobaan habis", wrong: MaxOTPVerifyAttempts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, whatsapp, _ := newTestOTPService(t)
+			if _, err := s.SendOTP(testOTPPhone, "forgot_password", "10.0.0.1", ""); err != nil {
+				t.Fatalf("SendOTP: %v", err)
+			}
+			code := whatsapp.LastCode()
+			wrongCode := fmt.Sprintf("%06d", (mustAtoi(t, code)+1)%1000000)
+
+			for i := 0; i < tt.wrong; i++ {
+				if valid, err := s.VerifyOTP(testOTPPhone, wrongCode, "forgot_password"); valid || !errors.Is(err, ErrOTPInvalid) {
+					t.Fatalf("percobaan salah %d = %v, %v, want ErrOTPInvalid", i+1, valid, err)
+				}
+			}
+
+			valid, err := s.VerifyOTP(testOTPPhone, code, "forgot_password")
+			if tt.wantValid {
+				if !valid || err != nil {
+					t.Fatalf("VerifyOTP = %v, %v, want valid", valid, err)
+				}
+				// Kode yang sudah dipakai tidak bisa dipakai lagi
+				if valid, err := s.VerifyOTP(testOTPPhone, code, "forgot_password"); valid || !errors.Is(err, ErrOTPInvalid) {
+					t.Errorf("pemakaian ulang = %v, %v, want ErrOTPInvalid", valid, err)
+				}
+				return
+			}
+
+			var limitErr *OTPRateLimitError
+			if valid || !errors.As(err, &limitErr) {
+				t.Fatalf("VerifyOTP = %v, %v, want OTPRateLimitError", valid, err)
+			}
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   func(t *testing.T, s *OTPService, db *gorm.DB) string
+		nomorHP string
+		wantErr error
+	}{
+		{
+			name: "token dari verifikasi OTP",
+			token: func(t *testing.T, s *OTPService, db *gorm.DB) string {
+				token, err := s.IssueResetToken(testOTPPhone)
+				if err != nil {
+					t.Fatalf("IssueResetToken: %v", err)
+				}
+				return token
+			},
+			nomorHP: testOTPPhone,
+		},
+		{
+			name:    "tanpa verifikasi OTP",
+			token:   func(t *testing.T, s *OTPService, db *gorm.DB) string { return "" },
+			nomorHP: testOTPPhone,
+			wantErr: ErrResetTokenInvalid,
+		},
+		{
+			name: "token nomor lain",
+			token: func(t *testing.T, s *OTPService, db *gorm.DB) string {
+				token, _ := s.IssueResetToken("6289999999999")
+				return token
+			},
+			nomorHP: testOTPPhone,
+			wantErr: ErrResetTokenInvalid,
+		},
+		{
+			name: "token kadaluarsa",
+			token: func(t *testing.T, s *OTPService, db *gorm.DB) string {
+				token, _ := s.IssueResetToken(testOTPPhone)
+				db.Model(&model.OTP{}).Where("purpose = ?", OTPPurposeResetPassword).Update("expires_at", time.Now().Add(-time.Minute))
+				return token
+			},
+			nomorHP: testOTPPhone,
+			wantErr: ErrResetTokenInvalid,
+		},
+		{
+			name: "token lama diganti token baru",
+			token: func(t *testing.T, s *OTPService, db *gorm.DB) string {
+				token, _ := s.IssueResetToken(testOTPPhone)
+				s.IssueResetToken(testOTPPhone)
+				return token
+			},
+			nomorHP: testOTPPhone,
+			wantErr: ErrResetTokenInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, db, _, _ := newTestOTPService(t)
+			token := tt.token(t, s, db)
+
+			err := s.ResetPassword(tt.nomorHP, token, "hashed-baru")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResetPassword = %v, want %v", err, tt.wantErr)
+			}
+
+			var user model.User
+			db.Where("nomor_hp = ?", testOTPPhone).First(&user)
+			if tt.wantErr != nil {
+				if user.Password != "x" {
+					t.Errorf("password berubah tanpa reset token yang sah")
+				}
+				return
+			}
+			if user.Password != "hashed-baru" {
+				t.Errorf("password = %q, want hashed-baru", user.Password)
+			}
+			// Reset token hanya berlaku sekali
+			if err := s.ResetPassword(tt.nomorHP, token, "hashed-lagi"); !errors.Is(err, ErrResetTokenInvalid) {
+				t.Errorf("pemakaian ulang = %v, want ErrResetTokenInvalid", err)
+			}
+		})
+	}
+}
+
+func mustAtoi(t *testing.T, s string) int {
+	t.Helper()
+
+	var n int
+	if _, err := fmt.Sscanf(s, "%d", &n); err != nil {
+		t.Fatalf("kode %q bukan angka", s)
+	}
+	return n
+}
@@ -14,6 +14,8 @@ import (
 	"time"
 )
 
+const OutletUploadDir = `C:\xampp\htdocs\Mobile-PipoSmart\uploads\outlets`
+
 func normalizePhotoURL(photo string) string {
 	if photo == "" {
 		return ""
@@ -57,7 +59,7 @@ func CreateOutlet(userID uint, input model.OutletInput, file *multipart.FileHead
 	var photoPath string
 	var fullPath string
 	if file != nil {
-		uploadDir := `C:\xampp\htdocs\Mobile-PipoSmart\uploads\outlets`
+		uploadDir := OutletUploadDir
 
 		if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
 			if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
@@ -131,13 +133,13 @@ func GetOutletsByUserID(userID uint) ([]model.Outlet, error) {
 	return outlets, nil
 }
 
-func GetAllOutlets(page, limit int) ([]model.OutletList, int64, error) {
+func GetAllOutlets(userID uint, page, limit int) ([]model.OutletList, int64, error) {
 	var outlets []model.OutletList
 	var total int64
 
 	offset := (page - 1) * limit
 
-	if err := database.DbCore.Model(&model.Outlet{}).Count(&total).Error; err != nil {
+	if err := database.DbCore.Model(&model.Outlet{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
 		return nil, 0, fmt.Errorf("gagal menghitung total outlet: %v", err)
 	}
 
@@ -145,12 +147,12 @@ func GetAllOutlets(page, limit int) ([]model.OutletList, int64, error) {
 		SELECT id, user_id, nama_outlet, alamat, nomor_hp, is_aktif, photo,
 		       DATE_FORMAT(created_at, '%Y-%m-%d %H:%i:%s') as created_at
 		FROM outlets 
-		WHERE deleted_at IS NULL
+		WHERE deleted_at IS NULL AND user_id = ?
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
 	`
 
-	if err := database.DbCore.Raw(query, limit, offset).Scan(&outlets).Error; err != nil {
+	if err := database.DbCore.Raw(query, userID, limit, offset).Scan(&outlets).Error; err != nil {
 		return nil, 0, fmt.Errorf("gagal mengambil outlet: %v", err)
 	}
 
@@ -199,11 +201,11 @@ func UpdateOutlet(outletID uint, userID uint, input model.UpdateOutletInput, fil
 	if file != nil {
 		if outlet.Photo != "" {
 			oldFileName := filepath.Base(outlet.Photo)
-			oldFilePath := filepath.Join(`C:\xampp\htdocs\Mobile-PipoSmart\uploads\outlets`, oldFileName)
+			oldFilePath := filepath.Join(OutletUploadDir, oldFileName)
 			os.Remove(oldFilePath)
 		}
 
-		uploadDir := `C:\xampp\htdocs\Mobile-PipoSmart\uploads\outlets`
+		uploadDir := OutletUploadDir
 		fileExt := filepath.Ext(file.Filename)
 		fileName := fmt.Sprintf("l_%d_outlet%s", time.Now().Unix(), fileExt)
 		fullPath := filepath.Join(uploadDir, fileName)
@@ -0,0 +1,238 @@
+package service
+
+import (
+    "errors"
+    "time"
+    "BackendFramework/internal/database"
+    "BackendFramework/internal/model"
+    "gorm.io/gorm"
+)
+
+type PengeluaranService struct {
+    db *gorm.DB
+}
+
+func NewPengeluaranService() *PengeluaranService {
+    return &PengeluaranService{
+        db: database.DbCore,
+    }
+}
+
+// parseTanggal membaca tanggal format YYYY-MM-DD
+func parseTanggal(value string) (time.Time, error) {
+    tanggal, err := time.ParseInLocation("2006-01-02", value, time.Local)
+    if err != nil {
+        return time.Time{}, errors.New("format tanggal harus YYYY-MM-DD")
+    }
+    return tanggal, nil
+}
+
+// validateKategori memastikan kategori ada dan milik outlet yang sama
+func (s *PengeluaranService) validateKategori(kategoriID uint, outletID *uint) error {
+    query := s.db.Model(&model.KategoriPengeluaran{}).Where("ktg_id = ?", kategoriID)
+    if outletID != nil {
+        query = query.Where("ktg_outlet = ?", *outletID)
+    }
+
+    var count int64
+    if err := query.Count(&count).Error; err != nil {
+        return err
+    }
+    if count == 0 {
+        return errors.New("kategori pengeluaran tidak ditemukan")
+    }
+
+    return nil
+}
+
+// applyFilter menerapkan filter outlet, rentang tanggal, kategori dan status
+func (s *PengeluaranService) applyFilter(query *gorm.DB, outletID *uint, filter model.PengeluaranFilter) (*gorm.DB, error) {
+    if outletID != nil {
+        query = query.Where("ac_pengeluaran.pengeluaran_outlet = ?", *outletID)
+    }
+
+    if filter.StartDate != "" {
+        start, err := parseTanggal(filter.StartDate)
+        if err != nil {
+            return nil, err
+        }
+        query = query.Where("ac_pengeluaran.pengeluaran_tanggal >= ?", start)
+    }
+
+    if filter.EndDate != "" {
+        end, err := parseTanggal(filter.EndDate)
+        if err != nil {
+            return nil, err
+        }
+        query = query.Where("ac_pengeluaran.pengeluaran_tanggal <= ?", end)
+    }
+
+    if filter.KategoriID != nil {
+        query = query.Where("ac_pengeluaran.pengeluaran_kategori = ?", *filter.KategoriID)
+    }
+
+    if filter.Status != "" {
+        query = query.Where("ac_pengeluaran.pengeluaran_status = ?", filter.Status)
+    }
+
+    return query, nil
+}
+
+func (s *PengeluaranService) Create(input model.PengeluaranInput, outletID *uint, username string) (*model.Pengeluaran, error) {
+    tanggal, err := parseTanggal(input.Tanggal)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := s.validateKategori(input.KategoriPengeluaranID, outletID); err != nil {
+        return nil, err
+    }
+
+    kategoriID := input.KategoriPengeluaranID
+    pengeluaran := &model.Pengeluaran{
+        OutletID:              outletID,
+        KategoriPengeluaranID: &kategoriID,
+        Tanggal:               tanggal,
+        Nominal:               input.Nominal,
+        Keterangan:            input.Keterangan,
+        Status:                "Aktif",
+        UserUpdate:            username,
+    }
+
+    if err := s.db.Create(pengeluaran).Error; err != nil {
+        return nil, err
+    }
+
+    return s.GetByID(pengeluaran.ID, outletID)
+}
+
+func (s *PengeluaranService) GetAll(outletID *uint, filter model.PengeluaranFilter) ([]model.Pengeluaran, error) {
+    var pengeluarans []model.Pengeluaran
+
+    query, err := s.applyFilter(s.db.Preload("KategoriPengeluaran"), outletID, filter)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := query.Order("pengeluaran_tanggal DESC, pengeluaran_id DESC").Find(&pengeluarans).Error; err != nil {
+        return nil, err
+    }
+
+    return pengeluarans, nil
+}
+
+func (s *PengeluaranService) GetByID(id uint, outletID *uint) (*model.Pengeluaran, error) {
+    var pengeluaran model.Pengeluaran
+
+    query := s.db.Preload("KategoriPengeluaran")
+
+    if outletID != nil {
+        query = query.Where("pengeluaran_outlet = ?", *outletID)
+    }
+
+    if err := query.First(&pengeluaran, id).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return nil, errors.New("pengeluaran tidak ditemukan")
+        }
+        return nil, err
+    }
+
+    return &pengeluaran, nil
+}
+
+func (s *PengeluaranService) Update(id uint, input model.UpdatePengeluaranInput, outletID *uint, username string) (*model.Pengeluaran, error) {
+    pengeluaran, err := s.GetByID(id, outletID)
+    if err != nil {
+        return nil, err
+    }
+
+    updates := make(map[string]interface{})
+
+    if input.KategoriPengeluaranID != nil {
+        if err := s.validateKategori(*input.KategoriPengeluaranID, outletID); err != nil {
+            return nil, err
+        }
+        updates["pengeluaran_kategori"] = *input.KategoriPengeluaranID
+    }
+    if input.Tanggal != "" {
+        tanggal, err := parseTanggal(input.Tanggal)
+        if err != nil {
+            return nil, err
+        }
+        updates["pengeluaran_tanggal"] = tanggal
+    }
+    if input.Nominal != nil {
+        updates["pengeluaran_nominal"] = *input.Nominal
+    }
+    if input.Keterangan != "" {
+        updates["pengeluaran_keterangan"] = input.Keterangan
+    }
+    if input.Status != "" {
+        updates["pengeluaran_status"] = input.Status
+    }
+
+    updates["pengeluaran_userupdate"] = username
+
+    if err := s.db.Model(pengeluaran).Updates(updates).Error; err != nil {
+        return nil, err
+    }
+
+    return s.GetByID(id, outletID)
+}
+
+func (s *PengeluaranService) Delete(id uint, outletID *uint) error {
+    pengeluaran, err := s.GetByID(id, outletID)
+    if err != nil {
+        return err
+    }
+
+    if err := s.db.Delete(pengeluaran).Error; err != nil {
+        return err
+    }
+
+    return nil
+}
+
+// GetPerKategori mengelompokkan total pengeluaran aktif per kategori dari satu atau beberapa outlet
+func (s *PengeluaranService) GetPerKategori(outletIDs []uint, filter model.PengeluaranFilter) ([]model.PengeluaranPerKategori, error) {
+    if filter.Status == "" {
+        filter.Status = "Aktif"
+    }
+
+    query := s.db.Model(&model.Pengeluaran{}).
+        Select("COALESCE(ac_kategori_pengeluaran.ktg_id, 0) AS kategori_id, COALESCE(ac_kategori_pengeluaran.ktg_nama, 'Tanpa Kategori') AS kategori_nama, " +
+            "COALESCE(SUM(ac_pengeluaran.pengeluaran_nominal), 0) AS total, COUNT(ac_pengeluaran.pengeluaran_id) AS jumlah").
+        Joins("LEFT JOIN ac_kategori_pengeluaran ON ac_kategori_pengeluaran.ktg_id = ac_pengeluaran.pengeluaran_kategori")
+
+    query, err := s.applyFilter(query.Where("ac_pengeluaran.pengeluaran_outlet IN ?", outletIDs), nil, filter)
+    if err != nil {
+        return nil, err
+    }
+
+    perKategori := make([]model.PengeluaranPerKategori, 0)
+    if err := query.Group("ac_kategori_pengeluaran.ktg_id, ac_kategori_pengeluaran.ktg_nama").
+        Order("total DESC").
+        Scan(&perKategori).Error; err != nil {
+        return nil, err
+    }
+
+    return perKategori, nil
+}
+
+// GetSummary mengisi PengeluaranSummary: total, jumlah transaksi dan rincian per kategori
+func (s *PengeluaranService) GetSummary(outletIDs []uint, filter model.PengeluaranFilter) (*model.PengeluaranSummary, error) {
+    perKategori, err := s.GetPerKategori(outletIDs, filter)
+    if err != nil {
+        return nil, err
+    }
+
+    summary := &model.PengeluaranSummary{
+        PerKategori: perKategori,
+    }
+    for _, kategori := range perKategori {
+        summary.TotalPengeluaran += kategori.Total
+        summary.JumlahTransaksi += kategori.Jumlah
+    }
+
+    return summary, nil
+}
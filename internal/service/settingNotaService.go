@@ -50,6 +50,8 @@ func (s *notaSettingsService) GetByOutletID(outletID uint) (*model.NotaSettings,
         ShowWhatsappFooter: true,
         PrinterSize:        58,
         PrinterType:        "A",
+        InvoiceFormat:      model.DefaultInvoiceFormat,
+        InvoiceReset:       model.InvoiceResetMonthly,
     }
 
     if err := s.db.Create(&defaultSettings).Error; err != nil {
@@ -67,13 +69,31 @@ func (s *notaSettingsService) CreateOrUpdate(outletID uint, input *model.NotaSet
         }
         return nil, err
     }
-    
+
     var settings model.NotaSettings
     err := s.db.Where("outlet_id = ?", outletID).First(&settings).Error
     
     if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
         return nil, err
     }
+
+    // Format dan periode reset divalidasi bersama: yang tidak dikirim memakai nilai tersimpan atau default
+    invoiceFormat, invoiceReset := input.InvoiceFormat, input.InvoiceReset
+    if invoiceFormat == "" {
+        invoiceFormat = settings.InvoiceFormat
+    }
+    if invoiceFormat == "" {
+        invoiceFormat = model.DefaultInvoiceFormat
+    }
+    if invoiceReset == "" {
+        invoiceReset = settings.InvoiceReset
+    }
+    if invoiceReset == "" {
+        invoiceReset = model.InvoiceResetMonthly
+    }
+    if err := ValidateInvoiceFormat(invoiceFormat, invoiceReset); err != nil {
+        return nil, err
+    }
     
     if errors.Is(err, gorm.ErrRecordNotFound) {
         settings = model.NotaSettings{
@@ -91,6 +111,14 @@ func (s *notaSettingsService) CreateOrUpdate(outletID uint, input *model.NotaSet
             ShowWhatsappFooter: input.ShowWhatsappFooter,
             PrinterSize:        input.PrinterSize,
             PrinterType:        input.PrinterType,
+            InvoiceFormat:      input.InvoiceFormat,
+            InvoiceReset:       input.InvoiceReset,
+        }
+        if settings.InvoiceFormat == "" {
+            settings.InvoiceFormat = model.DefaultInvoiceFormat
+        }
+        if settings.InvoiceReset == "" {
+            settings.InvoiceReset = model.InvoiceResetMonthly
         }
         
         if err := s.db.Create(&settings).Error; err != nil {
@@ -113,6 +141,12 @@ func (s *notaSettingsService) CreateOrUpdate(outletID uint, input *model.NotaSet
             "printer_size":         input.PrinterSize,
             "printer_type":         input.PrinterType,
         }
+        if input.InvoiceFormat != "" {
+            updates["invoice_format"] = input.InvoiceFormat
+        }
+        if input.InvoiceReset != "" {
+            updates["invoice_reset"] = input.InvoiceReset
+        }
         
         if err := s.db.Model(&settings).Updates(updates).Error; err != nil {
             return nil, err
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB membuka SQLite in-memory dan memigrasikan model yang dibutuhkan test.
+// Penguncian baris (FOR UPDATE) diabaikan SQLite, jadi test konkurensi tidak memakai helper ini.
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true, // Pelanggaran unique index menjadi gorm.ErrDuplicatedKey, dikenali isDuplicateKey
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	// Satu koneksi agar seluruh test memakai database in-memory yang sama
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
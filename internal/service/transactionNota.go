@@ -0,0 +1,240 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotaVoided dikembalikan saat membuat ulang nota pesanan yang notanya sudah di-void
+var ErrNotaVoided = errors.New("nota transaksi ini sudah dibatalkan")
+
+// paymentMethodCodes memetakan PaymentMethod.Category ke kode metode bayar di NotaData
+var paymentMethodCodes = map[string]string{
+	"Cash":     "cash",
+	"Transfer": "transfer",
+	"E-Wallet": "ewallet",
+}
+
+// GenerateNotaFromTransaction membangun nota dari pesanan: detail, pelanggan, diskon dan ledger pembayaran.
+// Jika nota pesanan sudah ada, isinya diperbarui (misal setelah pelunasan) alih-alih membuat nota baru.
+func (s *notaService) GenerateNotaFromTransaction(transactionID uint, outletID uint, cashierName string) (*model.NotaData, error) {
+	var notaData model.NotaData
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var transaction model.Transaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND outlet_id = ?", transactionID, outletID).
+			Preload("Customer").
+			Preload("Items").
+			Preload("Payments", func(db *gorm.DB) *gorm.DB {
+				return db.Order("created_at ASC")
+			}).
+			Preload("Payments.PaymentMethod").
+			First(&transaction).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransactionNotFound
+			}
+			return err
+		}
+
+		if transaction.OrderStatus == model.OrderStatusBatal {
+			return ErrTransactionCancelled
+		}
+
+		items := make([]model.NotaItem, 0, len(transaction.Items))
+		for _, detail := range transaction.Items {
+			items = append(items, model.NotaItem{
+				ProductName: detail.ServiceName,
+				Quantity:    detail.Qty,
+				Unit:        detail.Unit,
+				Price:       detail.Price,
+				Subtotal:    detail.Subtotal,
+			})
+		}
+		itemsJSON, err := json.Marshal(items)
+		if err != nil {
+			return err
+		}
+
+		change, outstanding := settleNotaPayment(transaction.PaidAmount, transaction.TotalPrice)
+
+		err = tx.Where("transaction_ref_id = ?", transaction.ID).First(&notaData).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		exists := err == nil
+		if exists && notaData.Status == "void" {
+			return ErrNotaVoided
+		}
+
+		if notaData.PublicToken == nil {
+			token, err := generatePublicToken()
+			if err != nil {
+				return err
+			}
+			notaData.PublicToken = &token
+		}
+
+		refID := transaction.ID
+		notaData.OutletID = transaction.OutletID
+		notaData.TransactionID = transaction.InvoiceNumber
+		notaData.TransactionRefID = &refID
+		notaData.TransactionDate = transaction.CreatedAt
+		if transaction.Customer != nil {
+			notaData.CustomerName = transaction.Customer.Name
+			notaData.CustomerPhone = transaction.Customer.Phone
+		}
+		notaData.CashierName = cashierName
+		notaData.ItemsJSON = string(itemsJSON)
+		notaData.Subtotal = transaction.Subtotal
+		notaData.Discount = transaction.DiscountTotal
+		notaData.DiscountType = "fixed"
+		notaData.Total = transaction.TotalPrice
+		notaData.PaymentAmount = transaction.PaidAmount
+		notaData.Change = change
+		notaData.Outstanding = outstanding
+		notaData.PaymentStatus = transaction.PaymentStatus
+		notaData.PaymentMethod = notaPaymentMethod(transaction.Payments)
+		notaData.Notes = transaction.Notes
+		// QR nota berisi link tracking pesanan (dibaca notaQRContent di controller). Isi lama seperti gambar QR
+		// atau link dengan base URL sebelumnya diganti agar nota cetak ulang selalu menunjuk ke tracking terbaru.
+		if err := ensureTrackingToken(tx, &transaction); err != nil {
+			return err
+		}
+		notaData.QRCodeData = TransactionTrackingURL(*transaction.TrackingToken)
+		notaData.Status = "completed"
+
+		if exists {
+			if err := tx.Omit(clause.Associations).Save(&notaData).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("nota_data_id = ?", notaData.ID).Delete(&model.NotaItemDetail{}).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Omit(clause.Associations).Create(&notaData).Error; err != nil {
+			return err
+		}
+
+		for i, item := range items {
+			itemDetail := model.NotaItemDetail{
+				NotaDataID:  notaData.ID,
+				ProductName: item.ProductName,
+				Quantity:    item.Quantity,
+				Unit:        item.Unit,
+				Price:       item.Price,
+				Subtotal:    item.Subtotal,
+			}
+			if detailProduk := transaction.Items[i].JenisProdukID; detailProduk != nil {
+				itemDetail.ProductID = *detailProduk
+			}
+			if err := tx.Create(&itemDetail).Error; err != nil {
+				return err
+			}
+		}
+
+		notaData.Items = items
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &notaData, nil
+}
+
+// notaPaymentMethod merangkum metode pembayaran yang dipakai, misal "cash" atau "cash+transfer"
+func notaPaymentMethod(payments []model.TransactionPayment) string {
+	var codes []string
+	seen := map[string]bool{}
+	for _, payment := range payments {
+		code, ok := paymentMethodCodes[payment.PaymentMethod.Category]
+		if !ok {
+			code = strings.ToLower(payment.PaymentMethod.Category)
+		}
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	return strings.Join(codes, "+")
+}
+
+// markTransactionVoided membatalkan pesanan dan mencatatnya di OrderLog.
+// Tidak melakukan apa-apa jika pesanan sudah Batal, agar void dari dua sisi tidak saling mengulang.
+func markTransactionVoided(tx *gorm.DB, transaction *model.Transaction, reason string, adminName string) error {
+	if transaction.OrderStatus == model.OrderStatusBatal {
+		return nil
+	}
+
+	now := time.Now()
+	if err := tx.Model(transaction).Updates(map[string]interface{}{
+		"order_status": model.OrderStatusBatal,
+		"void_reason":  reason,
+		"voided_at":    now,
+	}).Error; err != nil {
+		return err
+	}
+
+	log := model.OrderLog{
+		TransactionID: transaction.ID,
+		Status:        model.OrderStatusBatal,
+		AdminName:     adminName,
+		Reason:        reason,
+	}
+	return tx.Create(&log).Error
+}
+
+// markNotaVoided membatalkan nota yang terhubung ke pesanan, jika ada dan belum void
+func markNotaVoided(tx *gorm.DB, transactionID uint, reason string) error {
+	var notaData model.NotaData
+	if err := tx.Where("transaction_ref_id = ?", transactionID).First(&notaData).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if notaData.Status == "void" {
+		return nil
+	}
+
+	return tx.Model(&notaData).Updates(map[string]interface{}{
+		"status": "void",
+		"notes":  notaData.Notes + " | VOID: " + reason,
+	}).Error
+}
+
+// VoidTransaction membatalkan pesanan beserta notanya
+func (s *TransactionService) VoidTransaction(id uint, outletID uint, reason string, adminName string) (*model.Transaction, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var transaction model.Transaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND outlet_id = ?", id, outletID).
+			First(&transaction).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransactionNotFound
+			}
+			return err
+		}
+
+		if transaction.OrderStatus == model.OrderStatusBatal {
+			return ErrTransactionCancelled
+		}
+
+		if err := markTransactionVoided(tx, &transaction, reason, adminName); err != nil {
+			return err
+		}
+		return markNotaVoided(tx, transaction.ID, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(id, outletID)
+}
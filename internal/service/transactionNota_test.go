@@ -0,0 +1,53 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"testing"
+)
+
+func TestGenerateNotaQRCodeData(t *testing.T) {
+	tests := []struct {
+		name   string
+		qrCode string // QRCodeData nota yang sudah ada, kosong berarti nota belum pernah dibuat
+	}{
+		{name: "nota baru"},
+		{name: "gambar QR lama", qrCode: "data:image/png;base64,iVBORw0KGgo="},
+		{name: "link dengan base URL lama", qrCode: "http://old-host/v1/public/track/trk-1"},
+		{name: "link tracking terbaru", qrCode: TransactionTrackingURL("trk-1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &model.Customer{}, &model.Transaction{}, &model.TransactionDetail{},
+				&model.PaymentMethod{}, &model.TransactionPayment{}, &model.NotaData{}, &model.NotaItemDetail{})
+			token := "trk-1"
+			transaction := model.Transaction{InvoiceNumber: "TRX/1", OutletID: 1, TotalPrice: 10000, TrackingToken: &token}
+			if err := db.Create(&transaction).Error; err != nil {
+				t.Fatalf("create transaction: %v", err)
+			}
+			if tt.qrCode != "" {
+				publicToken := "nota-1"
+				nota := model.NotaData{OutletID: 1, TransactionID: "TRX/1", TransactionRefID: &transaction.ID,
+					PublicToken: &publicToken, QRCodeData: tt.qrCode, Status: "completed"}
+				if err := db.Create(&nota).Error; err != nil {
+					t.Fatalf("create nota: %v", err)
+				}
+			}
+
+			s := &notaService{db: db}
+			nota, err := s.GenerateNotaFromTransaction(transaction.ID, 1, "Kasir")
+			if err != nil {
+				t.Fatalf("GenerateNotaFromTransaction: %v", err)
+			}
+			if want := TransactionTrackingURL(token); nota.QRCodeData != want {
+				t.Errorf("QRCodeData = %q, want %q", nota.QRCodeData, want)
+			}
+
+			var saved model.NotaData
+			db.Where("transaction_ref_id = ?", transaction.ID).First(&saved)
+			if saved.QRCodeData != TransactionTrackingURL(token) {
+				t.Errorf("QRCodeData tersimpan = %q", saved.QRCodeData)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPaymentMethodNotFound = errors.New("metode pembayaran tidak ditemukan")
+	ErrPaymentMethodInactive = errors.New("metode pembayaran tidak aktif")
+	ErrTransactionPaid       = errors.New("transaksi sudah lunas")
+	// ErrPaymentExceedsOutstanding dibungkus bersama sisa tagihan saat pembayaran melebihinya
+	ErrPaymentExceedsOutstanding = errors.New("pembayaran melebihi sisa tagihan")
+)
+
+// recordPayment menambah satu baris ledger lalu menghitung ulang PaidAmount dan PaymentStatus.
+// Harus dipanggil di dalam transaksi database dengan baris transaksi yang sudah dikunci.
+func recordPayment(tx *gorm.DB, transaction *model.Transaction, input model.AddPaymentInput, adminName string) error {
+	var method model.PaymentMethod
+	if err := tx.Where("id = ? AND outlet_id = ?", input.PaymentMethodID, transaction.OutletID).First(&method).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPaymentMethodNotFound
+		}
+		return err
+	}
+	if !method.IsActive {
+		return ErrPaymentMethodInactive
+	}
+
+	amount := roundPrice(input.Amount)
+	outstanding := roundPrice(transaction.Outstanding())
+	if outstanding <= 0 {
+		return ErrTransactionPaid
+	}
+	if amount > outstanding {
+		return fmt.Errorf("%w (sisa %.2f)", ErrPaymentExceedsOutstanding, outstanding)
+	}
+
+	payment := model.TransactionPayment{
+		TransactionID:   transaction.ID,
+		OutletID:        transaction.OutletID,
+		PaymentMethodID: method.ID,
+		Amount:          amount,
+		Notes:           input.Notes,
+		ReceivedBy:      adminName,
+	}
+	if err := tx.Create(&payment).Error; err != nil {
+		return err
+	}
+
+	transaction.PaidAmount = roundPrice(transaction.PaidAmount + amount)
+	transaction.PaymentStatus = model.DerivePaymentStatus(transaction.PaidAmount, transaction.TotalPrice)
+
+	return tx.Model(transaction).Updates(map[string]interface{}{
+		"paid_amount":    transaction.PaidAmount,
+		"payment_status": transaction.PaymentStatus,
+	}).Error
+}
+
+// AddPayment mencatat DP, cicilan atau pelunasan untuk transaksi milik outlet
+func (s *TransactionService) AddPayment(id uint, outletID uint, input model.AddPaymentInput, adminName string) (*model.Transaction, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var transaction model.Transaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND outlet_id = ?", id, outletID).
+			First(&transaction).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransactionNotFound
+			}
+			return err
+		}
+
+		if transaction.OrderStatus == model.OrderStatusBatal {
+			return ErrTransactionCancelled
+		}
+
+		return recordPayment(tx, &transaction, input, adminName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(id, outletID)
+}
+
+// GetPayments mengembalikan ledger pembayaran satu transaksi, urut dari yang paling awal
+func (s *TransactionService) GetPayments(id uint, outletID uint) ([]model.TransactionPayment, error) {
+	var count int64
+	if err := s.db.Model(&model.Transaction{}).Where("id = ? AND outlet_id = ?", id, outletID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrTransactionNotFound
+	}
+
+	var payments []model.TransactionPayment
+	if err := s.db.Where("transaction_id = ?", id).
+		Preload("PaymentMethod").
+		Order("created_at ASC").
+		Find(&payments).Error; err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// GetReceivables mengembalikan daftar piutang outlet (transaksi Belum Bayar / DP), yang paling lama di atas.
+// Lebih dari satu outlet dipakai untuk laporan gabungan owner (middleware.OutletIDsInScope).
+func (s *TransactionService) GetReceivables(outletIDs []uint) (*model.ReceivableSummary, error) {
+	var transactions []model.Transaction
+	if err := s.db.Where("outlet_id IN ? AND payment_status <> ? AND order_status <> ?", outletIDs, model.PaymentStatusLunas, model.OrderStatusBatal).
+		Preload("Customer").
+		Order("created_at ASC").
+		Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &model.ReceivableSummary{Items: make([]model.Receivable, 0, len(transactions))}
+	for _, trx := range transactions {
+		outstanding := roundPrice(trx.Outstanding())
+		if outstanding <= 0 {
+			continue
+		}
+		var customer model.Customer
+		if trx.Customer != nil {
+			customer = *trx.Customer
+		}
+
+		summary.Items = append(summary.Items, model.Receivable{
+			TransactionID: trx.ID,
+			OutletID:      trx.OutletID,
+			InvoiceNumber: trx.InvoiceNumber,
+			CustomerID:    trx.CustomerID,
+			CustomerName:  customer.Name,
+			CustomerPhone: customer.Phone,
+			TotalPrice:    trx.TotalPrice,
+			PaidAmount:    trx.PaidAmount,
+			Outstanding:   outstanding,
+			PaymentStatus: trx.PaymentStatus,
+			OrderStatus:   trx.OrderStatus,
+			CreatedAt:     trx.CreatedAt,
+		})
+		summary.TotalOutstanding += outstanding
+	}
+	summary.TotalOutstanding = roundPrice(summary.TotalOutstanding)
+	summary.Count = len(summary.Items)
+
+	return summary, nil
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"BackendFramework/internal/model"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TransactionService struct {
+	db *gorm.DB
+}
+
+func NewTransactionService(db *gorm.DB) *TransactionService {
+	return &TransactionService{db: db}
+}
+
+var (
+	// ErrTotalMismatch dikembalikan jika total dari client berbeda dengan hitungan server
+	ErrTotalMismatch       = errors.New("total harga tidak sesuai dengan perhitungan server")
+	ErrTransactionNotFound = errors.New("transaksi tidak ditemukan")
+	// ErrTransactionCancelled dikembalikan untuk aksi pada pesanan yang sudah Batal
+	ErrTransactionCancelled = errors.New("transaksi sudah dibatalkan")
+	ErrTransactionFinished  = errors.New("pesanan sudah selesai, status tidak dapat diubah")
+	// ErrInvalidStatusTransition dikembalikan jika status baru melompati atau mundur dari alur pesanan
+	ErrInvalidStatusTransition = errors.New("status tidak dapat diubah")
+)
+
+// pricedItem adalah hasil hitung harga satu baris item dari master JenisProduk
+type pricedItem struct {
+	produk  model.JenisProduk
+	layanan string
+	qty     float64
+}
+
+func roundPrice(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// allowsFractionalQty menentukan satuan yang boleh desimal (berat/panjang), selain itu harus bilangan bulat
+func allowsFractionalQty(satuan string) bool {
+	switch strings.ToLower(strings.TrimSpace(satuan)) {
+	case "kg", "kilo", "kilogram", "m", "meter", "m2", "meter persegi":
+		return true
+	}
+	return false
+}
+
+// CreateTransaction menghitung harga dari JenisProduk dan Diskon di server lalu menyimpan transaksi
+func (s *TransactionService) CreateTransaction(input model.CreateTransactionInput, outletID uint, adminName string) (*model.Transaction, error) {
+	var customer model.Customer
+	if err := s.db.Where("id = ? AND outlet_id = ?", input.CustomerID, outletID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("pelanggan tidak ditemukan")
+		}
+		return nil, err
+	}
+
+	// parfum_id mengacu ke Parfum (ac_parfum), 0 berarti tanpa parfum
+	if input.ParfumID != 0 {
+		var parfum model.Parfum
+		if err := s.db.Where("prf_id = ? AND prf_outlet = ?", input.ParfumID, outletID).First(&parfum).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("parfum tidak ditemukan")
+			}
+			return nil, err
+		}
+	}
+
+	items, err := s.priceItems(input.Items, outletID)
+	if err != nil {
+		return nil, err
+	}
+
+	subtotal := 0.0
+	for _, item := range items {
+		subtotal += roundPrice(float64(*item.produk.HargaPer) * item.qty)
+	}
+	subtotal = roundPrice(subtotal)
+
+	discountTotal := 0.0
+	if input.DiscountID != nil {
+		discountTotal, err = s.calculateDiscount(*input.DiscountID, outletID, subtotal)
+		if err != nil {
+			return nil, err
+		}
+	}
+	total := roundPrice(subtotal - discountTotal)
+
+	if input.TotalPrice != nil && roundPrice(*input.TotalPrice) != total {
+		return nil, fmt.Errorf("%w: client %.2f, server %.2f", ErrTotalMismatch, *input.TotalPrice, total)
+	}
+
+	trackingToken, err := generatePublicToken()
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := model.Transaction{
+		OutletID:         outletID,
+		CustomerID:       input.CustomerID,
+		ParfumID:         input.ParfumID,
+		DiscountID:       input.DiscountID,
+		Subtotal:         subtotal,
+		DiscountTotal:    discountTotal,
+		TotalPrice:       total,
+		PaymentStatus:    model.DerivePaymentStatus(0, total),
+		Notes:            input.Notes,
+		TrackingToken:    &trackingToken,
+		EstimatedReadyAt: estimatedReadyAt(time.Now(), items),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		invoice, err := NextInvoiceNumber(tx, outletID)
+		if err != nil {
+			return err
+		}
+		transaction.InvoiceNumber = invoice
+
+		if err := tx.Omit("Items", "Logs", "Payments", "Customer").Create(&transaction).Error; err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			produkID := item.produk.ID
+			price := float64(*item.produk.HargaPer)
+			unit := ""
+			if item.produk.Satuan != nil {
+				unit = *item.produk.Satuan
+			}
+
+			detail := model.TransactionDetail{
+				TransactionID: transaction.ID,
+				JenisProdukID: &produkID,
+				ServiceName:   item.layanan + " - " + item.produk.Nama,
+				Unit:          unit,
+				Price:         price,
+				Qty:           item.qty,
+				Subtotal:      roundPrice(price * item.qty),
+			}
+			if err := tx.Create(&detail).Error; err != nil {
+				return err
+			}
+		}
+
+		log := model.OrderLog{
+			TransactionID: transaction.ID,
+			Status:        model.OrderStatusAntrian,
+			AdminName:     adminName,
+		}
+		if err := tx.Create(&log).Error; err != nil {
+			return err
+		}
+
+		// DP atau pembayaran penuh saat drop-off langsung masuk ledger
+		if input.Payment != nil {
+			return recordPayment(tx, &transaction, *input.Payment, adminName)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(transaction.ID, outletID)
+}
+
+// priceItems memuat JenisProduk milik outlet untuk setiap item dan memvalidasi qty terhadap satuannya
+func (s *TransactionService) priceItems(inputs []model.CreateTransactionItemInput, outletID uint) ([]pricedItem, error) {
+	items := make([]pricedItem, 0, len(inputs))
+
+	for i, input := range inputs {
+		var produk model.JenisProduk
+		if err := s.db.First(&produk, input.JenisProdukID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("item %d: jenis produk %d tidak ditemukan", i+1, input.JenisProdukID)
+			}
+			return nil, err
+		}
+
+		var layanan model.Layanan
+		if err := s.db.Where("id = ? AND outlet_id = ?", produk.LayananID, outletID).First(&layanan).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("item %d: jenis produk %d bukan milik outlet ini", i+1, input.JenisProdukID)
+			}
+			return nil, err
+		}
+
+		if produk.HargaPer == nil {
+			return nil, fmt.Errorf("item %d: harga %s belum diatur", i+1, produk.Nama)
+		}
+
+		satuan := ""
+		if produk.Satuan != nil {
+			satuan = *produk.Satuan
+		}
+		if !allowsFractionalQty(satuan) && input.Qty != math.Trunc(input.Qty) {
+			return nil, fmt.Errorf("item %d: qty %s harus bilangan bulat untuk satuan %s", i+1, produk.Nama, satuan)
+		}
+
+		items = append(items, pricedItem{
+			produk:  produk,
+			layanan: layanan.NamaLayanan,
+			qty:     input.Qty,
+		})
+	}
+
+	return items, nil
+}
+
+// calculateDiscount menghitung potongan dari Diskon aktif milik outlet atau Diskon global
+// (dis_outlet NULL), Nominal atau Persen
+func (s *TransactionService) calculateDiscount(diskonID uint, outletID uint, subtotal float64) (float64, error) {
+	var diskon model.Diskon
+	if err := s.db.Where("dis_id = ? AND (dis_outlet = ? OR dis_outlet IS NULL)", diskonID, outletID).First(&diskon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.New("diskon tidak ditemukan")
+		}
+		return 0, err
+	}
+
+	if diskon.Status != "Aktif" {
+		return 0, errors.New("diskon tidak aktif")
+	}
+
+	var amount float64
+	switch diskon.Jenis {
+	case "Persen":
+		amount = subtotal * diskon.NilaiDiskon / 100
+	case "Nominal":
+		amount = diskon.NilaiDiskon
+	default:
+		return 0, fmt.Errorf("jenis diskon %s tidak dikenali", diskon.Jenis)
+	}
+
+	// Potongan tidak boleh membuat total negatif
+	if amount > subtotal {
+		amount = subtotal
+	}
+
+	return roundPrice(amount), nil
+}
+
+func (s *TransactionService) GetByID(id uint, outletID uint) (*model.Transaction, error) {
+	var transaction model.Transaction
+
+	if err := s.db.Where("id = ? AND outlet_id = ?", id, outletID).
+		Preload("Customer").
+		Preload("Items").
+		Preload("Logs", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Payments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Payments.PaymentMethod").
+		First(&transaction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// UpdateOrderStatus memindahkan status pesanan satu tahap dan mencatatnya di OrderLog
+func (s *TransactionService) UpdateOrderStatus(id uint, outletID uint, input model.UpdateOrderStatusInput, adminName string) (*model.Transaction, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var transaction model.Transaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND outlet_id = ?", id, outletID).
+			First(&transaction).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransactionNotFound
+			}
+			return err
+		}
+
+		if transaction.OrderStatus == model.OrderStatusSelesai {
+			return ErrTransactionFinished
+		}
+		if transaction.OrderStatus == model.OrderStatusBatal {
+			return fmt.Errorf("%w, status tidak dapat diubah", ErrTransactionCancelled)
+		}
+
+		if !transaction.CanTransitionTo(input.Status) {
+			return fmt.Errorf("%w dari %s ke %s, status berikutnya adalah %s",
+				ErrInvalidStatusTransition, transaction.OrderStatus, input.Status, transaction.NextOrderStatus())
+		}
+
+		if err := tx.Model(&transaction).Update("order_status", input.Status).Error; err != nil {
+			return err
+		}
+
+		log := model.OrderLog{
+			TransactionID: transaction.ID,
+			Status:        input.Status,
+			AdminName:     adminName,
+			Reason:        input.Reason,
+		}
+		return tx.Create(&log).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(id, outletID)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"BackendFramework/internal/config"
+	"BackendFramework/internal/model"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrTrackingNotFound dikembalikan untuk token tracking yang tidak dikenal
+var ErrTrackingNotFound = errors.New("pesanan tidak ditemukan")
+
+// TransactionTrackingURL menyusun link tracking pesanan yang bisa dibuka pelanggan tanpa login
+func TransactionTrackingURL(token string) string {
+	return config.APP_BASE_URL + "/v1/public/track/" + token
+}
+
+// ensureTrackingToken memberi token tracking ke pesanan lama yang dibuat sebelum fitur tracking ada
+func ensureTrackingToken(tx *gorm.DB, transaction *model.Transaction) error {
+	if transaction.TrackingToken != nil && *transaction.TrackingToken != "" {
+		return nil
+	}
+
+	token, err := generatePublicToken()
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(transaction).UpdateColumn("tracking_token", token).Error; err != nil {
+		return err
+	}
+	transaction.TrackingToken = &token
+	return nil
+}
+
+// estimatedReadyAt adalah waktu mulai ditambah LamaPengerjaan terlama di antara item, nil jika tidak ada item
+// yang punya lama pengerjaan. SatuanWaktu kosong dianggap jam.
+func estimatedReadyAt(start time.Time, items []pricedItem) *time.Time {
+	var longest time.Duration
+	for _, item := range items {
+		if item.produk.LamaPengerjaan == nil || *item.produk.LamaPengerjaan <= 0 {
+			continue
+		}
+
+		unit := time.Hour
+		if item.produk.SatuanWaktu != nil {
+			switch strings.ToLower(strings.TrimSpace(*item.produk.SatuanWaktu)) {
+			case "menit", "minute", "minutes":
+				unit = time.Minute
+			case "hari", "day", "days":
+				unit = 24 * time.Hour
+			case "minggu", "week", "weeks":
+				unit = 7 * 24 * time.Hour
+			}
+		}
+
+		if duration := time.Duration(*item.produk.LamaPengerjaan) * unit; duration > longest {
+			longest = duration
+		}
+	}
+
+	if longest == 0 {
+		return nil
+	}
+	ready := start.Add(longest)
+	return &ready
+}
+
+// GetTracking mengambil status pesanan untuk link tracking publik
+func (s *TransactionService) GetTracking(token string) (*model.TransactionTracking, error) {
+	if token == "" {
+		return nil, ErrTrackingNotFound
+	}
+
+	var transaction model.Transaction
+	err := s.db.Where("tracking_token = ?", token).
+		Preload("Items").
+		Preload("Logs", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		First(&transaction).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTrackingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var outlet model.Outlet
+	if err := s.db.Select("id", "nama_outlet").First(&outlet, transaction.OutletID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	tracking := &model.TransactionTracking{
+		InvoiceNumber:    transaction.InvoiceNumber,
+		OutletName:       outlet.NamaOutlet,
+		Items:            make([]model.TransactionTrackingItem, 0, len(transaction.Items)),
+		OrderStatus:      transaction.OrderStatus,
+		Timeline:         make([]model.TransactionTrackingEvent, 0, len(transaction.Logs)),
+		EstimatedReadyAt: transaction.EstimatedReadyAt,
+		PaymentStatus:    transaction.PaymentStatus,
+		TotalPrice:       transaction.TotalPrice,
+		Outstanding:      roundPrice(transaction.Outstanding()),
+		CreatedAt:        transaction.CreatedAt,
+	}
+	for _, item := range transaction.Items {
+		tracking.Items = append(tracking.Items, model.TransactionTrackingItem{
+			ServiceName: item.ServiceName,
+			Qty:         item.Qty,
+			Unit:        item.Unit,
+			Subtotal:    item.Subtotal,
+		})
+	}
+	for _, log := range transaction.Logs {
+		tracking.Timeline = append(tracking.Timeline, model.TransactionTrackingEvent{
+			Status:    log.Status,
+			CreatedAt: log.CreatedAt,
+		})
+	}
+
+	return tracking, nil
+}
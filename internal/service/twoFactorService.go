@@ -0,0 +1,431 @@
+package service
+
+import (
+	"BackendFramework/internal/config"
+	"BackendFramework/internal/model"
+	"BackendFramework/internal/thirdparty"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Parameter TOTP mengikuti default RFC 6238 yang didukung semua aplikasi authenticator
+const (
+	totpPeriod             = 30
+	totpDigits             = 6
+	totpSkew               = 1 // Toleransi selisih jam HP, satu langkah sebelum dan sesudah
+	twoFactorRecoveryCount = 10
+)
+
+var (
+	ErrTwoFactorInvalidCode   = errors.New("kode 2FA salah")
+	ErrTwoFactorNotEnabled    = errors.New("2FA belum aktif")
+	ErrTwoFactorAlreadyActive = errors.New("2FA sudah aktif")
+	ErrTwoFactorNotSetup      = errors.New("2FA belum di-setup, panggil setup terlebih dahulu")
+)
+
+type TwoFactorService struct {
+	db  *gorm.DB
+	now func() time.Time // Jam untuk menghitung time step TOTP, diganti di test
+}
+
+func NewTwoFactorService(db *gorm.DB) *TwoFactorService {
+	return &TwoFactorService{db: db, now: time.Now}
+}
+
+// IsEnabled mengecek apakah owner wajib memasukkan kode 2FA saat login
+func (s *TwoFactorService) IsEnabled(userID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&model.UserTwoFactor{}).Where("user_id = ? AND enabled = ?", userID, true).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *TwoFactorService) GetStatus(userID uint) (*model.TwoFactorStatusResponse, error) {
+	status := &model.TwoFactorStatusResponse{}
+
+	var twoFactor model.UserTwoFactor
+	err := s.db.Where("user_id = ?", userID).First(&twoFactor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status.Enabled = twoFactor.Enabled
+	status.EnabledAt = twoFactor.EnabledAt
+	if twoFactor.Enabled {
+		if err := s.db.Model(&model.TwoFactorRecoveryCode{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Count(&status.RemainingRecoveryCodes).Error; err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Setup membuat secret baru (belum aktif) beserta QR untuk dipindai aplikasi authenticator.
+// Setup ulang sebelum Enable mengganti secret sebelumnya.
+func (s *TwoFactorService) Setup(user *model.User) (*model.TwoFactorSetupResponse, error) {
+	var existing model.UserTwoFactor
+	err := s.db.Where("user_id = ?", user.ID).First(&existing).Error
+	if err == nil && existing.Enabled {
+		return nil, ErrTwoFactorAlreadyActive
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+
+	encrypted, err := encryptTwoFactorSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.UserID = user.ID
+	existing.Secret = encrypted
+	existing.Enabled = false
+	existing.EnabledAt = nil
+	existing.LastUsedStep = 0
+	if err := s.db.Save(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	otpauthURL := totpURL(secret, user.Email)
+	qrCode, err := twoFactorQRCode(otpauthURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorSetupResponse{
+		Secret:     secret,
+		OtpauthURL: otpauthURL,
+		QRCode:     qrCode,
+	}, nil
+}
+
+// Enable mengaktifkan 2FA setelah kode pertama dari aplikasi authenticator benar,
+// lalu mengembalikan recovery code yang hanya ditampilkan sekali
+func (s *TwoFactorService) Enable(userID uint, code string) ([]string, error) {
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var twoFactor model.UserTwoFactor
+		if err := tx.Where("user_id = ?", userID).First(&twoFactor).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTwoFactorNotSetup
+			}
+			return err
+		}
+		if twoFactor.Enabled {
+			return ErrTwoFactorAlreadyActive
+		}
+
+		if err := s.verifyTOTP(tx, &twoFactor, code); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&twoFactor).Updates(map[string]interface{}{
+			"enabled":    true,
+			"enabled_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable mematikan 2FA setelah diverifikasi dengan kode TOTP atau recovery code
+func (s *TwoFactorService) Disable(userID uint, code string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.verify(tx, userID, code); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.TwoFactorRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.UserTwoFactor{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes mengganti semua recovery code lama dengan yang baru
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var twoFactor model.UserTwoFactor
+		if err := tx.Where("user_id = ? AND enabled = ?", userID, true).First(&twoFactor).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTwoFactorNotEnabled
+			}
+			return err
+		}
+		if err := s.verifyTOTP(tx, &twoFactor, code); err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify memeriksa kode TOTP, atau recovery code jika formatnya bukan 6 digit angka
+func (s *TwoFactorService) Verify(userID uint, code string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.verify(tx, userID, code)
+	})
+}
+
+func (s *TwoFactorService) verify(tx *gorm.DB, userID uint, code string) error {
+	var twoFactor model.UserTwoFactor
+	if err := tx.Where("user_id = ? AND enabled = ?", userID, true).First(&twoFactor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTwoFactorNotEnabled
+		}
+		return err
+	}
+
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		return s.verifyTOTP(tx, &twoFactor, code)
+	}
+	return useRecoveryCode(tx, userID, code)
+}
+
+// verifyTOTP mencocokkan kode dengan time step sekarang ±totpSkew. Time step yang sudah dipakai
+// disimpan di LastUsedStep sehingga kode yang sama tidak bisa dipakai dua kali.
+func (s *TwoFactorService) verifyTOTP(tx *gorm.DB, twoFactor *model.UserTwoFactor, code string) error {
+	code = strings.TrimSpace(code)
+	if !isTOTPCode(code) {
+		return ErrTwoFactorInvalidCode
+	}
+
+	secret, err := decryptTwoFactorSecret(twoFactor.Secret)
+	if err != nil {
+		return err
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return err
+	}
+
+	current := s.now().Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= twoFactor.LastUsedStep {
+			continue
+		}
+		if !hmac.Equal([]byte(totpCode(key, step)), []byte(code)) {
+			continue
+		}
+
+		// Update bersyarat agar dua request bersamaan dengan kode yang sama tidak sama-sama lolos
+		result := tx.Model(&model.UserTwoFactor{}).
+			Where("id = ? AND last_used_step < ?", twoFactor.ID, step).
+			Update("last_used_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTwoFactorInvalidCode
+		}
+		twoFactor.LastUsedStep = step
+		return nil
+	}
+
+	return ErrTwoFactorInvalidCode
+}
+
+// totpCode menghitung kode HOTP (RFC 4226) untuk satu time step dengan HMAC-SHA1
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func totpURL(secret, email string) string {
+	label := url.PathEscape(config.APP_NAME + ":" + email)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", config.APP_NAME)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", totpDigits))
+	params.Set("period", fmt.Sprintf("%d", totpPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// twoFactorQRCode membuat QR enrollment lewat thirdparty.GenerateQrFile lalu mengembalikannya sebagai data URI
+func twoFactorQRCode(content string) (string, error) {
+	file, err := os.CreateTemp("", "2fa-*.png")
+	if err != nil {
+		return "", err
+	}
+	path := file.Name()
+	file.Close()
+	defer os.Remove(path)
+
+	if !thirdparty.GenerateQrFile(content, path) {
+		return "", errors.New("gagal membuat QR code 2FA")
+	}
+
+	png, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
+
+// ==================== RECOVERY CODE ====================
+
+// normalizeRecoveryCode mengabaikan huruf besar/kecil, spasi dan tanda hubung saat kode diketik ulang
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.TwoFactorRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, twoFactorRecoveryCount)
+	records := make([]model.TwoFactorRecoveryCode, 0, twoFactorRecoveryCount)
+	for i := 0; i < twoFactorRecoveryCount; i++ {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		encoded := strings.ToLower(encoding.EncodeToString(raw))[:10]
+		code := encoded[:5] + "-" + encoded[5:]
+
+		codes = append(codes, code)
+		records = append(records, model.TwoFactorRecoveryCode{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// useRecoveryCode menandai recovery code sebagai terpakai; setiap kode hanya berlaku sekali
+func useRecoveryCode(tx *gorm.DB, userID uint, code string) error {
+	if normalizeRecoveryCode(code) == "" {
+		return ErrTwoFactorInvalidCode
+	}
+
+	result := tx.Model(&model.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorInvalidCode
+	}
+	return nil
+}
+
+// ==================== ENKRIPSI SECRET ====================
+
+// Secret TOTP harus bisa dibaca kembali untuk menghitung kode, jadi dienkripsi (AES-GCM) bukan di-hash
+func encryptTwoFactorSecret(secret string) (string, error) {
+	aesGCM, err := twoFactorCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aesGCM.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func decryptTwoFactorSecret(encrypted string) (string, error) {
+	aesGCM, err := twoFactorCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aesGCM.NonceSize() {
+		return "", errors.New("secret 2FA tidak valid")
+	}
+	nonce, cipherText := data[:aesGCM.NonceSize()], data[aesGCM.NonceSize():]
+	plain, err := aesGCM.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func twoFactorCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher([]byte(config.ENCRYPTION_KEY))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
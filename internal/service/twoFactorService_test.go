@@ -0,0 +1,158 @@
+package service
+
+import (
+	"BackendFramework/internal/config"
+	"BackendFramework/internal/model"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// rfc6238Key adalah secret SHA-1 dari lampiran B RFC 6238, base32-nya dipakai sebagai secret owner di test
+var rfc6238Key = []byte("12345678901234567890")
+
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// Vektor RFC 6238 memakai 8 digit; kode 6 digit adalah 6 digit terakhirnya
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+	for _, tt := range tests {
+		if got := totpCode(rfc6238Key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+// newTestTwoFactorService membuat owner 1 dengan 2FA aktif memakai secret RFC 6238, jam diset ke now
+func newTestTwoFactorService(t *testing.T, now time.Time) (*TwoFactorService, *gorm.DB, *model.UserTwoFactor) {
+	t.Helper()
+
+	previousKey := config.ENCRYPTION_KEY
+	config.ENCRYPTION_KEY = "0123456789abcdef0123456789abcdef"
+	t.Cleanup(func() { config.ENCRYPTION_KEY = previousKey })
+
+	db := newTestDB(t, &model.UserTwoFactor{}, &model.TwoFactorRecoveryCode{})
+	encrypted, err := encryptTwoFactorSecret(rfc6238Secret)
+	if err != nil {
+		t.Fatalf("encrypt secret: %v", err)
+	}
+	twoFactor := model.UserTwoFactor{UserID: 1, Secret: encrypted, Enabled: true, EnabledAt: &now}
+	if err := db.Create(&twoFactor).Error; err != nil {
+		t.Fatalf("create 2fa: %v", err)
+	}
+
+	s := &TwoFactorService{db: db, now: func() time.Time { return now }}
+	return s, db, &twoFactor
+}
+
+func TestVerifyTOTPWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name    string
+		step    int64
+		wantErr error
+	}{
+		{name: "time step sekarang", step: current},
+		{name: "satu langkah sebelum (jam HP terlambat)", step: current - 1},
+		{name: "satu langkah sesudah (jam HP terlalu cepat)", step: current + 1},
+		{name: "dua langkah sebelum", step: current - 2, wantErr: ErrTwoFactorInvalidCode},
+		{name: "dua langkah sesudah", step: current + 2, wantErr: ErrTwoFactorInvalidCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, _ := newTestTwoFactorService(t, now)
+			if err := s.Verify(1, totpCode(rfc6238Key, tt.step)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyTOTPReplay(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	s, db, twoFactor := newTestTwoFactorService(t, now)
+
+	steps := []struct {
+		name    string
+		step    int64
+		wantErr error
+	}{
+		{name: "kode pertama", step: current},
+		{name: "kode yang sama dipakai lagi", step: current, wantErr: ErrTwoFactorInvalidCode},
+		{name: "kode lebih lama dari yang sudah dipakai", step: current - 1, wantErr: ErrTwoFactorInvalidCode},
+		{name: "kode langkah berikutnya", step: current + 1},
+		{name: "kode langkah berikutnya dipakai lagi", step: current + 1, wantErr: ErrTwoFactorInvalidCode},
+	}
+	for _, step := range steps {
+		if err := s.Verify(1, totpCode(rfc6238Key, step.step)); !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: Verify = %v, want %v", step.name, err, step.wantErr)
+		}
+	}
+
+	db.First(twoFactor, twoFactor.ID)
+	if twoFactor.LastUsedStep != current+1 {
+		t.Errorf("LastUsedStep = %d, want %d", twoFactor.LastUsedStep, current+1)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	s, _, _ := newTestTwoFactorService(t, now)
+
+	codes, err := s.RegenerateRecoveryCodes(1, totpCode(rfc6238Key, now.Unix()/totpPeriod))
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %v", err)
+	}
+	if len(codes) != twoFactorRecoveryCount {
+		t.Fatalf("%d recovery code, want %d", len(codes), twoFactorRecoveryCount)
+	}
+
+	steps := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{name: "kode pertama", code: codes[0]},
+		{name: "kode pertama dipakai lagi", code: codes[0], wantErr: ErrTwoFactorInvalidCode},
+		{name: "diketik huruf besar tanpa tanda hubung", code: strings.ToUpper(strings.ReplaceAll(codes[1], "-", ""))},
+		{name: "kode yang tidak pernah dibuat", code: "aaaaa-bbbbb", wantErr: ErrTwoFactorInvalidCode},
+		{name: "kosong", code: " - ", wantErr: ErrTwoFactorInvalidCode},
+	}
+	for _, step := range steps {
+		if err := s.Verify(1, step.code); !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: Verify = %v, want %v", step.name, err, step.wantErr)
+		}
+	}
+
+	status, err := s.GetStatus(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.RemainingRecoveryCodes != int64(twoFactorRecoveryCount-2) {
+		t.Errorf("sisa recovery code = %d, want %d", status.RemainingRecoveryCodes, twoFactorRecoveryCount-2)
+	}
+
+	// Kode lama tidak berlaku lagi setelah diganti
+	if _, err := s.RegenerateRecoveryCodes(1, totpCode(rfc6238Key, now.Unix()/totpPeriod+1)); err != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %v", err)
+	}
+	if err := s.Verify(1, codes[2]); !errors.Is(err, ErrTwoFactorInvalidCode) {
+		t.Errorf("recovery code lama = %v, want ErrTwoFactorInvalidCode", err)
+	}
+}
@@ -0,0 +1,427 @@
+package service
+
+import (
+	"BackendFramework/internal/middleware"
+	"BackendFramework/internal/model"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxPickupReminders membatasi jumlah pengingat agar pelanggan tidak di-spam
+const maxPickupReminders = 3
+
+// defaultWhatsappTemplates adalah isi awal template saat outlet belum punya template
+var defaultWhatsappTemplates = map[string]model.WhatsappTemplate{
+	model.WhatsappEventOrderCreated: {
+		Body: "Halo {nama}, pesanan laundry Anda di {outlet} sudah kami terima.\n\n" +
+			"No. Nota: {invoice}\nTotal: Rp {total}\nSisa tagihan: Rp {sisa}\n\nLihat nota: {link}",
+	},
+	model.WhatsappEventOrderReady: {
+		Body: "Halo {nama}, cucian Anda dengan nota {invoice} sudah *Siap Ambil* di {outlet}.\n\n" +
+			"Sisa tagihan: Rp {sisa}\nLihat nota: {link}",
+	},
+	model.WhatsappEventPickupReminder: {
+		Body: "Halo {nama}, pengingat: cucian Anda dengan nota {invoice} masih menunggu diambil di {outlet}.\n\n" +
+			"Sisa tagihan: Rp {sisa}\nLihat nota: {link}",
+		DelayHours: 24,
+	},
+}
+
+type WhatsappNotificationService struct {
+	db      *gorm.DB
+	infobip *InfobipService
+	nota    NotaService
+}
+
+func NewWhatsappNotificationService(db *gorm.DB) *WhatsappNotificationService {
+	return &WhatsappNotificationService{
+		db:      db,
+		infobip: NewInfobipService(),
+		nota:    NewNotaService(db),
+	}
+}
+
+// formatRupiah memformat nominal tanpa desimal dengan pemisah ribuan titik, misal 25.000
+func formatRupiah(amount float64) string {
+	digits := fmt.Sprintf("%.0f", amount)
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+
+	var result strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			result.WriteByte('.')
+		}
+		result.WriteRune(c)
+	}
+
+	if negative {
+		return "-" + result.String()
+	}
+	return result.String()
+}
+
+// ensureTemplates membuat template default untuk outlet, catatan WhatsApp dari NotaSettings ikut ditambahkan di akhir pesan
+func (s *WhatsappNotificationService) ensureTemplates(outletID uint) error {
+	var count int64
+	if err := s.db.Model(&model.WhatsappTemplate{}).Where("outlet_id = ?", outletID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= int64(len(defaultWhatsappTemplates)) {
+		return nil
+	}
+
+	var settings model.NotaSettings
+	note := ""
+	if err := s.db.Where("outlet_id = ?", outletID).First(&settings).Error; err == nil {
+		note = strings.TrimSpace(settings.WhatsappNote)
+	}
+
+	for event, tmpl := range defaultWhatsappTemplates {
+		body := tmpl.Body
+		if note != "" {
+			body += "\n\n" + note
+		}
+
+		seed := model.WhatsappTemplate{
+			OutletID:   outletID,
+			Event:      event,
+			Body:       body,
+			IsActive:   true,
+			DelayHours: tmpl.DelayHours,
+		}
+		if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *WhatsappNotificationService) GetTemplates(outletID uint) ([]model.WhatsappTemplate, error) {
+	if err := s.ensureTemplates(outletID); err != nil {
+		return nil, err
+	}
+
+	var templates []model.WhatsappTemplate
+	if err := s.db.Where("outlet_id = ?", outletID).Order("id ASC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+func (s *WhatsappNotificationService) getTemplate(outletID uint, event string) (*model.WhatsappTemplate, error) {
+	if _, ok := defaultWhatsappTemplates[event]; !ok {
+		return nil, errors.New("jenis notifikasi tidak dikenali")
+	}
+
+	if err := s.ensureTemplates(outletID); err != nil {
+		return nil, err
+	}
+
+	var tmpl model.WhatsappTemplate
+	if err := s.db.Where("outlet_id = ? AND event = ?", outletID, event).First(&tmpl).Error; err != nil {
+		return nil, err
+	}
+
+	return &tmpl, nil
+}
+
+func (s *WhatsappNotificationService) UpdateTemplate(outletID uint, event string, input model.WhatsappTemplateInput) (*model.WhatsappTemplate, error) {
+	tmpl, err := s.getTemplate(outletID, event)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"body": input.Body,
+	}
+	if input.IsActive != nil {
+		updates["is_active"] = *input.IsActive
+	}
+	if input.DelayHours != nil {
+		updates["delay_hours"] = *input.DelayHours
+	}
+
+	if err := s.db.Model(tmpl).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getTemplate(outletID, event)
+}
+
+// renderTemplate mengisi placeholder template dengan data pesanan
+func renderTemplate(body string, transaction *model.Transaction, outletName, link string) string {
+	var customerName string
+	if transaction.Customer != nil {
+		customerName = transaction.Customer.Name
+	}
+	replacer := strings.NewReplacer(
+		"{nama}", customerName,
+		"{invoice}", transaction.InvoiceNumber,
+		"{total}", formatRupiah(transaction.TotalPrice),
+		"{sisa}", formatRupiah(transaction.Outstanding()),
+		"{status}", transaction.OrderStatus,
+		"{link}", link,
+		"{outlet}", outletName,
+	)
+	return replacer.Replace(body)
+}
+
+// notaLink mengambil link publik nota pesanan, nota dibuat dulu jika belum ada
+func (s *WhatsappNotificationService) notaLink(transaction *model.Transaction, cashierName string) (string, error) {
+	var notaData model.NotaData
+	err := s.db.Where("transaction_ref_id = ?", transaction.ID).First(&notaData).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		created, err := s.nota.GenerateNotaFromTransaction(transaction.ID, transaction.OutletID, cashierName)
+		if err != nil {
+			return "", err
+		}
+		notaData = *created
+	} else if err != nil {
+		return "", err
+	}
+
+	return s.nota.GetShareURL(notaData.ID)
+}
+
+func (s *WhatsappNotificationService) outletName(outletID uint) string {
+	var settings model.NotaSettings
+	if err := s.db.Where("outlet_id = ?", outletID).First(&settings).Error; err == nil && settings.BusinessName != "" {
+		return settings.BusinessName
+	}
+
+	var outlet model.Outlet
+	if err := s.db.First(&outlet, outletID).Error; err == nil {
+		return outlet.NamaOutlet
+	}
+	return ""
+}
+
+// NotifyTransaction mengirim notifikasi WhatsApp untuk pesanan dan mencatatnya di whatsapp_message_logs.
+// Mengembalikan nil tanpa error jika template untuk event tersebut dinonaktifkan outlet.
+func (s *WhatsappNotificationService) NotifyTransaction(transactionID uint, event string, cashierName string) (*model.WhatsappMessageLog, error) {
+	var transaction model.Transaction
+	if err := s.db.Preload("Customer").First(&transaction, transactionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("transaksi tidak ditemukan")
+		}
+		return nil, err
+	}
+
+	tmpl, err := s.getTemplate(transaction.OutletID, event)
+	if err != nil {
+		return nil, err
+	}
+	if !tmpl.IsActive {
+		return nil, nil
+	}
+
+	if transaction.Customer == nil || strings.TrimSpace(transaction.Customer.Phone) == "" {
+		return nil, errors.New("nomor WhatsApp pelanggan kosong")
+	}
+
+	link, err := s.notaLink(&transaction, cashierName)
+	if err != nil {
+		return nil, err
+	}
+
+	customerID := transaction.CustomerID
+	messageLog := model.WhatsappMessageLog{
+		OutletID:      transaction.OutletID,
+		TransactionID: &transaction.ID,
+		CustomerID:    &customerID,
+		Phone:         transaction.Customer.Phone,
+		Event:         event,
+		Message:       renderTemplate(tmpl.Body, &transaction, s.outletName(transaction.OutletID), link),
+		Status:        model.WhatsappStatusPending,
+	}
+	if err := s.db.Create(&messageLog).Error; err != nil {
+		return nil, err
+	}
+
+	result, sendErr := s.infobip.SendWhatsAppText(messageLog.Phone, messageLog.Message)
+	now := time.Now()
+	updates := map[string]interface{}{}
+	if sendErr != nil {
+		updates["status"] = model.WhatsappStatusFailed
+		updates["status_description"] = sendErr.Error()
+	} else {
+		updates["status"] = model.WhatsappStatusSent
+		updates["provider_message_id"] = result.MessageID
+		updates["status_description"] = result.Description
+		updates["sent_at"] = now
+	}
+	if err := s.db.Model(&messageLog).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	if sendErr != nil {
+		return &messageLog, sendErr
+	}
+	return &messageLog, nil
+}
+
+// NotifyTransactionForOutlet sama dengan NotifyTransaction, tetapi memastikan pesanan milik outlet pemanggil
+func (s *WhatsappNotificationService) NotifyTransactionForOutlet(transactionID uint, outletID uint, event string, cashierName string) (*model.WhatsappMessageLog, error) {
+	var count int64
+	if err := s.db.Model(&model.Transaction{}).Where("id = ? AND outlet_id = ?", transactionID, outletID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("transaksi tidak ditemukan")
+	}
+
+	return s.NotifyTransaction(transactionID, event, cashierName)
+}
+
+// SendPickupReminders mengirim pengingat untuk pesanan Siap Ambil yang belum diambil melewati DelayHours outlet
+func (s *WhatsappNotificationService) SendPickupReminders() (int, error) {
+	var transactions []model.Transaction
+	if err := s.db.Where("order_status = ?", model.OrderStatusSiapAmbil).Find(&transactions).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, transaction := range transactions {
+		// Error pada satu pesanan tidak menghentikan pengingat outlet lain di putaran ini
+		tmpl, err := s.getTemplate(transaction.OutletID, model.WhatsappEventPickupReminder)
+		if err != nil {
+			middleware.LogError(err, fmt.Sprintf("Gagal memuat template pengingat outlet %d", transaction.OutletID))
+			continue
+		}
+		if !tmpl.IsActive || tmpl.DelayHours <= 0 {
+			continue
+		}
+		delay := time.Duration(tmpl.DelayHours) * time.Hour
+
+		// Hitung dari pengingat terakhir, atau dari saat pesanan masuk Siap Ambil
+		var lastReminder model.WhatsappMessageLog
+		var reminders int64
+		if err := s.db.Model(&model.WhatsappMessageLog{}).
+			Where("transaction_id = ? AND event = ? AND status <> ?", transaction.ID, model.WhatsappEventPickupReminder, model.WhatsappStatusFailed).
+			Count(&reminders).Error; err != nil {
+			middleware.LogError(err, fmt.Sprintf("Gagal menghitung pengingat pesanan %d", transaction.ID))
+			continue
+		}
+		if reminders >= maxPickupReminders {
+			continue
+		}
+
+		since := transaction.UpdatedAt
+		var readyLog model.OrderLog
+		if err := s.db.Where("transaction_id = ? AND status = ?", transaction.ID, model.OrderStatusSiapAmbil).
+			Order("created_at DESC").First(&readyLog).Error; err == nil {
+			since = readyLog.CreatedAt
+		}
+		err = s.db.Where("transaction_id = ? AND event = ? AND status <> ?", transaction.ID, model.WhatsappEventPickupReminder, model.WhatsappStatusFailed).
+			Order("created_at DESC").First(&lastReminder).Error
+		if err == nil {
+			since = lastReminder.CreatedAt
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.LogError(err, fmt.Sprintf("Gagal memuat pengingat terakhir pesanan %d", transaction.ID))
+			continue
+		}
+
+		if time.Since(since) < delay {
+			continue
+		}
+
+		if _, err := s.NotifyTransaction(transaction.ID, model.WhatsappEventPickupReminder, "Sistem"); err == nil {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// StartPickupReminderJob menjalankan SendPickupReminders secara berkala di background
+func (s *WhatsappNotificationService) StartPickupReminderJob(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := s.SendPickupReminders(); err != nil {
+				middleware.LogError(err, "Pickup reminder job error")
+			}
+		}
+	}()
+}
+
+// GetLogs mengembalikan riwayat pesan WhatsApp outlet, bisa difilter per transaksi / status
+func (s *WhatsappNotificationService) GetLogs(outletID uint, transactionID uint, status string, page, limit int) ([]model.WhatsappMessageLog, int64, error) {
+	query := s.db.Model(&model.WhatsappMessageLog{}).Where("outlet_id = ?", outletID)
+	if transactionID > 0 {
+		query = query.Where("transaction_id = ?", transactionID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []model.WhatsappMessageLog
+	if err := query.Order("created_at DESC").Limit(limit).Offset((page - 1) * limit).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// deliveryStatusFromInfobip memetakan groupName Infobip ke status log
+func deliveryStatusFromInfobip(groupName, name string) string {
+	switch strings.ToUpper(groupName) {
+	case "DELIVERED":
+		return model.WhatsappStatusDelivered
+	case "UNDELIVERABLE", "EXPIRED", "REJECTED":
+		return model.WhatsappStatusFailed
+	case "SEEN":
+		return model.WhatsappStatusRead
+	}
+	if strings.EqualFold(name, "SEEN") {
+		return model.WhatsappStatusRead
+	}
+	return model.WhatsappStatusSent
+}
+
+// ApplyDeliveryReport memperbarui status log dari webhook delivery report Infobip
+func (s *WhatsappNotificationService) ApplyDeliveryReport(report model.InfobipDeliveryReport) (int, error) {
+	updated := 0
+	for _, result := range report.Results {
+		if result.MessageID == "" {
+			continue
+		}
+
+		status := deliveryStatusFromInfobip(result.Status.GroupName, result.Status.Name)
+		description := result.Status.Description
+		if result.Error.Description != "" && result.Error.Name != "NO_ERROR" {
+			description = result.Error.Description
+		}
+
+		updates := map[string]interface{}{
+			"status":             status,
+			"status_description": description,
+		}
+		if status == model.WhatsappStatusDelivered || status == model.WhatsappStatusRead {
+			updates["delivered_at"] = time.Now()
+		}
+
+		res := s.db.Model(&model.WhatsappMessageLog{}).Where("provider_message_id = ?", result.MessageID).Updates(updates)
+		if res.Error != nil {
+			return updated, res.Error
+		}
+		updated += int(res.RowsAffected)
+	}
+
+	return updated, nil
+}
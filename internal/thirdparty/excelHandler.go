@@ -1,41 +1,88 @@
 package thirdparty
 
 import(
+	"errors"
+	"io"
 	"strconv"
 
 	"github.com/xuri/excelize/v2"
-	
+
 	"BackendFramework/internal/middleware"
 
 )
 
 type Header struct {
-	Text  string  // Header text (e.g., "ID", "Name").
-	Width float64 // Column width for the header.
+	Text   string  // Header text (e.g., "ID", "Name").
+	Width  float64 // Column width for the header.
+	AsText bool    // Format the column as text so values like phone numbers keep their leading zero.
 }
 
+// ErrExcelEmpty is returned when the sheet has no header row.
+var ErrExcelEmpty = errors.New("excel sheet is empty")
+
 func GenerateExcelFile(excelHeader []Header, excelData []map[string]interface{}, sheetName,savePath string) bool {
-	f := excelize.NewFile()
+	f, err := newExcelFile(excelHeader, excelData, sheetName)
+	if err != nil {
+		middleware.LogError(err,"Failed To Generate Excel File")
+		return false
+	}
 	defer func() {
 		if err := f.Close(); err != nil {
 			middleware.LogError(err,"Failed To Close Excel File")
 			// log.Printf("Error closing Excel file: %v", err)
 		}
 	}()
+
+	// Save the file.
+	if err := f.SaveAs(savePath); err != nil {
+		middleware.LogError(err,"Failed To Save File")
+		return false
+	}
+
+	return true
+}
+
+// GenerateExcelBytes builds the same workbook as GenerateExcelFile in memory, for download responses.
+func GenerateExcelBytes(excelHeader []Header, excelData []map[string]interface{}, sheetName string) ([]byte, error) {
+	f, err := newExcelFile(excelHeader, excelData, sheetName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			middleware.LogError(err,"Failed To Close Excel File")
+		}
+	}()
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newExcelFile(excelHeader []Header, excelData []map[string]interface{}, sheetName string) (*excelize.File, error) {
+	f := excelize.NewFile()
+
 	// Customize header cell style (e.g., bold font).
 	BoldStyle, err := f.NewStyle(&excelize.Style{
 		Font: &excelize.Font{Bold: true},
 	})
 	if err != nil {
-		middleware.LogError(err,"Failed To Close Excel File")
-		// return err
-		return false
+		f.Close()
+		return nil, err
+	}
+	// Built-in number format 49 is "@" (text).
+	TextStyle, err := f.NewStyle(&excelize.Style{NumFmt: 49})
+	if err != nil {
+		f.Close()
+		return nil, err
 	}
 	// Create a new sheet.
 	index, err := f.NewSheet(sheetName)
 	if err != nil {
-		middleware.LogError(err,"Failed To Create Sheet")
-		return false
+		f.Close()
+		return nil, err
 	}
 
 	// Write excelHeader to the first row and set column widths.
@@ -43,6 +90,11 @@ func GenerateExcelFile(excelHeader []Header, excelData []map[string]interface{},
 		colName, _ := excelize.ColumnNumberToName(colIndex + 1) // e.g., "A", "B", etc.
 		cell := colName + "1"                                  // e.g., "A1", "B1", etc.
 
+		// Text columns also apply to rows the user types in later (e.g., import templates).
+		if header.AsText {
+			f.SetColStyle(sheetName, colName, TextStyle)
+		}
+
 		// Write header text.
 		f.SetCellValue(sheetName, cell, header.Text)
 
@@ -69,19 +121,12 @@ func GenerateExcelFile(excelHeader []Header, excelData []map[string]interface{},
     // Delete the default "Sheet1".
     if sheetName != "Sheet1" {
 	    if err := f.DeleteSheet("Sheet1"); err != nil {
-	        // return err
-			middleware.LogError(err,"Failed To Delete Default Sheet")
-			return false
+			f.Close()
+			return nil, err
 	    }
 	}
 
-	// Save the file.
-	if err := f.SaveAs(savePath); err != nil {
-		middleware.LogError(err,"Failed To Save File")
-		return false
-	}
-
-	return true
+	return f, nil
 }
 
 // ReadExcelFile reads data from an Excel file and returns headers and data.
@@ -98,13 +143,45 @@ func ReadExcelFile(sheetName,filePath string) ([]Header, []map[string]interface{
         }
     }()
 
-    // Get all the rows in the first sheet.
-    rows, err := f.GetRows(sheetName)
+    // Get all the rows in the sheet, formatted as displayed in Excel.
+    headers, data, err := readExcelSheet(f, sheetName)
     if err != nil {
 		middleware.LogError(err,"Failed to get rows from excel file")
         return nil, nil, false
     }
 
+    return headers, data, true
+}
+
+// ReadExcel reads an uploaded workbook without saving it to disk. An empty sheetName reads the first sheet.
+// data[i] is spreadsheet row i+2 (row 1 is the header), blank rows included, so callers can report row numbers.
+// Cells are read as raw values so long numbers (e.g., phone numbers typed as numbers) stay out of scientific notation.
+func ReadExcel(r io.Reader, sheetName string) ([]Header, []map[string]interface{}, error) {
+    f, err := excelize.OpenReader(r)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer func() {
+        if err := f.Close(); err != nil {
+			middleware.LogError(err,"Failed To Close Excel File")
+        }
+    }()
+
+    if sheetName == "" {
+        sheetName = f.GetSheetName(0)
+    }
+    return readExcelSheet(f, sheetName, excelize.Options{RawCellValue: true})
+}
+
+func readExcelSheet(f *excelize.File, sheetName string, opts ...excelize.Options) ([]Header, []map[string]interface{}, error) {
+    rows, err := f.GetRows(sheetName, opts...)
+    if err != nil {
+        return nil, nil, err
+    }
+    if len(rows) == 0 {
+        return nil, nil, ErrExcelEmpty
+    }
+
     // Extract headers and their widths.
     var headers []Header
     for colIndex, headerText := range rows[0] {
@@ -116,15 +193,19 @@ func ReadExcelFile(sheetName,filePath string) ([]Header, []map[string]interface{
         })
     }
 
-    // Extract data starting from the second row.
+    // Extract data starting from the second row. GetRows drops trailing empty cells, so short rows are padded.
     var data []map[string]interface{}
     for rowIndex := 1; rowIndex < len(rows); rowIndex++ {
         rowData := make(map[string]interface{})
         for colIndex, header := range headers {
-            rowData[header.Text] = rows[rowIndex][colIndex]
+            value := ""
+            if colIndex < len(rows[rowIndex]) {
+                value = rows[rowIndex][colIndex]
+            }
+            rowData[header.Text] = value
         }
         data = append(data, rowData)
     }
 
-    return headers, data, true
-}
\ No newline at end of file
+    return headers, data, nil
+}
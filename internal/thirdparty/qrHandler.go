@@ -22,4 +22,15 @@ func GenerateQrFile(qrContent, outputPath string) bool {
 	}
 
 	return true
+}
+
+// GenerateQrPng menghasilkan QR dalam bentuk PNG tanpa menulis file
+func GenerateQrPng(qrContent string, size int) ([]byte, error) {
+	png, err := qrcode.Encode(qrContent, qrcode.Medium, size)
+	if err != nil {
+		middleware.LogError(err,"Failed To Create Qr Code")
+		return nil, err
+	}
+
+	return png, nil
 }
\ No newline at end of file